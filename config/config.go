@@ -1,11 +1,20 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/netautomate/netorca-go/pkg/client"
 )
 
 // Config holds the configuration for the API client.
@@ -18,6 +27,27 @@ type Config struct {
 	APIVersion string
 	// RequestTimeout is the timeout for API requests (in seconds).
 	RequestTimeout int
+
+	// CAFile is the path to a PEM bundle of certificate authorities to trust in addition to
+	// the system pool - the usual need of an on-prem instance behind an internal CA.
+	CAFile string
+	// ClientCertFile is the path to a PEM client certificate for mutual TLS. It must be set
+	// together with ClientKeyFile.
+	ClientCertFile string
+	// ClientKeyFile is the path to the PEM private key matching ClientCertFile.
+	ClientKeyFile string
+	// TLSMinVersion is the lowest TLS version to negotiate: "1.0", "1.1", "1.2" or "1.3".
+	// Empty means "1.2".
+	TLSMinVersion string
+	// ProxyURL routes every request through this proxy, e.g. "http://proxy.corp:3128".
+	// Empty falls back to the standard HTTPS_PROXY/HTTP_PROXY environment variables.
+	ProxyURL string
+	// NoProxy is a comma-separated list of hosts, domains and CIDR ranges that bypass the
+	// proxy, in the same form as the standard NO_PROXY variable.
+	NoProxy string
+	// InsecureSkipVerify disables server certificate verification entirely. It exists for
+	// throwaway lab instances only; building a transport with it set logs a warning.
+	InsecureSkipVerify bool
 }
 
 // LoadConfig loads the configuration from the .env file and returns a Config struct.
@@ -42,11 +72,232 @@ func LoadConfig(file string) *Config {
 	if err != nil {
 		log.Fatal("Error: REQUEST_TIMEOUT should be valid INT", err)
 	}
+	// the insecure flag only counts when spelled out as a boolean - a typo must not
+	// silently turn certificate verification off
+	insecure := false
+	if value := os.Getenv("TLS_INSECURE_SKIP_VERIFY"); value != "" {
+		insecure, err = strconv.ParseBool(value)
+		if err != nil {
+			log.Fatal("Error: TLS_INSECURE_SKIP_VERIFY should be true or false", err)
+		}
+	}
 
 	return &Config{
-		APIKey:         os.Getenv("API_KEY"),
-		BaseURL:        os.Getenv("API_URL"),
-		APIVersion:     apiVersion,
-		RequestTimeout: intTimeout,
+		APIKey:             os.Getenv("API_KEY"),
+		BaseURL:            os.Getenv("API_URL"),
+		APIVersion:         apiVersion,
+		RequestTimeout:     intTimeout,
+		CAFile:             os.Getenv("TLS_CA_FILE"),
+		ClientCertFile:     os.Getenv("TLS_CLIENT_CERT_FILE"),
+		ClientKeyFile:      os.Getenv("TLS_CLIENT_KEY_FILE"),
+		TLSMinVersion:      os.Getenv("TLS_MIN_VERSION"),
+		ProxyURL:           os.Getenv("PROXY_URL"),
+		NoProxy:            os.Getenv("NO_PROXY"),
+		InsecureSkipVerify: insecure,
+	}
+}
+
+// tlsVersions maps the accepted TLS_MIN_VERSION spellings onto crypto/tls constants.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig builds the TLS settings described by the config: the extra CA bundle, the client
+// certificate for mutual TLS and the minimum version. Files are read on every call, so a
+// rotated certificate is picked up by building a fresh transport.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	var minVersion uint16 = tls.VersionTLS12
+	if c.TLSMinVersion != "" {
+		version, ok := tlsVersions[c.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf(
+				"invalid TLS minimum version %q (expected 1.0, 1.1, 1.2 or 1.3)", c.TLSMinVersion,
+			)
+		}
+		minVersion = version
+	}
+
+	//nolint:gosec // the minimum is the caller's explicit choice, and defaults to 1.2
+	tlsConfig := &tls.Config{MinVersion: minVersion}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		// Extend the system pool rather than replace it, so public endpoints keep working
+		// through the same client. Some platforms have no system pool; start empty there.
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case c.ClientCertFile != "" && c.ClientKeyFile != "":
+		certificate, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	case c.ClientCertFile != "" || c.ClientKeyFile != "":
+		return nil, fmt.Errorf("mutual TLS needs both a client certificate and a client key")
+	}
+
+	if c.InsecureSkipVerify {
+		log.Print("WARNING: TLS certificate verification is DISABLED (TLS_INSECURE_SKIP_VERIFY). " +
+			"Any server can impersonate the NetOrca API; never use this outside a lab.")
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return tlsConfig, nil
+}
+
+// Transport builds an HTTP transport carrying the config's TLS and proxy settings. It starts
+// from a clone of http.DefaultTransport so pooling and timeouts keep their usual defaults.
+func (c *Config) Transport() (*http.Transport, error) {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if c.ProxyURL != "" {
+		proxyURL, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("proxy URL must use http, https or socks5, got %q", c.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	if c.NoProxy != "" {
+		proxy = bypassProxy(proxy, parseNoProxy(c.NoProxy))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+	return transport, nil
+}
+
+// HTTPClient wraps Transport in an http.Client, ready to assign to client.Client.HTTPClient.
+// It sets no client-wide timeout: the API client already bounds each request through its
+// context, and a second, competing deadline would only obscure which one fired.
+func (c *Config) HTTPClient() (*http.Client, error) {
+	transport, err := c.Transport()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// NewClient builds an API client from the config, with the transport described by its TLS and
+// proxy settings already installed.
+func (c *Config) NewClient() (*client.Client, error) {
+	nc, err := client.NewClient(
+		c.BaseURL, c.APIKey, c.APIVersion, time.Duration(c.RequestTimeout)*time.Second,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := c.HTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the HTTP transport: %w", err)
+	}
+	nc.HTTPClient = httpClient
+	return nc, nil
+}
+
+// noProxyRule is one parsed NO_PROXY entry: a CIDR range, or a host or domain with an
+// optional port.
+type noProxyRule struct {
+	network *net.IPNet
+	host    string
+	port    string
+	// subdomainsOnly is set for a leading-dot entry such as ".corp.example", which matches
+	// hosts below the domain but not the domain itself.
+	subdomainsOnly bool
+}
+
+// parseNoProxy reads a NO_PROXY list. A bare "*" anywhere in it bypasses the proxy for every
+// host, and collapses the list to that single wildcard rule.
+func parseNoProxy(list string) []noProxyRule {
+	var rules []noProxyRule
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return []noProxyRule{{host: "*"}}
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			rules = append(rules, noProxyRule{network: network})
+			continue
+		}
+
+		rule := noProxyRule{host: entry}
+		if host, port, err := net.SplitHostPort(entry); err == nil {
+			rule.host, rule.port = host, port
+		}
+		if strings.HasPrefix(rule.host, ".") {
+			rule.host = strings.TrimPrefix(rule.host, ".")
+			rule.subdomainsOnly = true
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// matches reports whether the rule covers the given host and port.
+func (r noProxyRule) matches(host, port string) bool {
+	if r.host == "*" {
+		return true
+	}
+	if r.network != nil {
+		ip := net.ParseIP(host)
+		return ip != nil && r.network.Contains(ip)
+	}
+	if r.port != "" && r.port != port {
+		return false
+	}
+	if host == r.host {
+		return !r.subdomainsOnly
+	}
+	return strings.HasSuffix(host, "."+r.host)
+}
+
+// proxyFunc is the shape of http.Transport.Proxy.
+type proxyFunc = func(*http.Request) (*url.URL, error)
+
+// bypassProxy wraps a proxy function so requests to hosts on the NO_PROXY list go direct.
+func bypassProxy(proxy proxyFunc, rules []noProxyRule) proxyFunc {
+	return func(req *http.Request) (*url.URL, error) {
+		host := strings.ToLower(req.URL.Hostname())
+		port := req.URL.Port()
+		if port == "" {
+			port = "443"
+			if req.URL.Scheme == "http" {
+				port = "80"
+			}
+		}
+		for _, rule := range rules {
+			if rule.matches(host, port) {
+				return nil, nil
+			}
+		}
+		return proxy(req)
 	}
 }
@@ -1,13 +1,44 @@
 package config_test
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/joho/godotenv"
 	"github.com/netautomate/netorca-go/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// isolateEnv unsets every variable the env file sets, for the rest of the test. godotenv never
+// overrides a variable that is already set, and LoadConfig exports what it reads into the
+// process, so without this a test would both see the runner's environment and leak its own
+// file into the tests after it. t.Setenv restores each variable when the test ends.
+func isolateEnv(t *testing.T, file string) {
+	t.Helper()
+	values, err := godotenv.Read(file)
+	require.NoError(t, err)
+	for name := range values {
+		t.Setenv(name, "")
+		require.NoError(t, os.Unsetenv(name))
+	}
+}
+
 func TestLoadConfig(t *testing.T) {
+	isolateEnv(t, "testdata/.env_example")
+
 	// Load the configuration from the .env file
 	cfg := config.LoadConfig("testdata/.env_example")
 
@@ -16,3 +47,180 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, "v1", cfg.APIVersion)
 	assert.Equal(t, 15, cfg.RequestTimeout)
 }
+
+func TestLoadConfigTLSAndProxy(t *testing.T) {
+	isolateEnv(t, "testdata/.env_tls_example")
+
+	cfg := config.LoadConfig("testdata/.env_tls_example")
+
+	assert.Equal(t, "testdata/ca.pem", cfg.CAFile)
+	assert.Equal(t, "testdata/client.pem", cfg.ClientCertFile)
+	assert.Equal(t, "testdata/client-key.pem", cfg.ClientKeyFile)
+	assert.Equal(t, "1.3", cfg.TLSMinVersion)
+	assert.Equal(t, "http://proxy.corp.example:3128", cfg.ProxyURL)
+	assert.Equal(t, "localhost,.corp.example,10.0.0.0/8", cfg.NoProxy)
+	assert.True(t, cfg.InsecureSkipVerify)
+}
+
+// writeTestPKI generates a throwaway CA and a client certificate signed by it, so the TLS
+// tests exercise real PEM parsing without checking key material into the repository.
+func writeTestPKI(t *testing.T) (caFile, certFile, keyFile string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "netorca test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "netorca test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, caTemplate, &clientKey.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	require.NoError(t, err)
+
+	write := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+		return path
+	}
+	return write("ca.pem", "CERTIFICATE", caDER),
+		write("client.pem", "CERTIFICATE", clientDER),
+		write("client-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+//nolint:funlen // one subtest per setting; they share the generated PKI
+func TestConfigTransport(t *testing.T) {
+	caFile, certFile, keyFile := writeTestPKI(t)
+
+	t.Run("defaults to TLS 1.2 and the environment proxy", func(t *testing.T) {
+		cfg := &config.Config{}
+
+		transport, err := cfg.Transport()
+
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+		assert.Nil(t, transport.TLSClientConfig.RootCAs)
+		assert.False(t, transport.TLSClientConfig.InsecureSkipVerify)
+	})
+
+	t.Run("trusts the CA bundle and presents the client certificate", func(t *testing.T) {
+		cfg := &config.Config{CAFile: caFile, ClientCertFile: certFile, ClientKeyFile: keyFile, TLSMinVersion: "1.3"}
+
+		tlsConfig, err := cfg.TLSConfig()
+
+		require.NoError(t, err)
+		assert.NotNil(t, tlsConfig.RootCAs)
+		assert.Len(t, tlsConfig.Certificates, 1)
+		assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+	})
+
+	t.Run("rejects half a client certificate pair", func(t *testing.T) {
+		cfg := &config.Config{ClientCertFile: certFile}
+
+		_, err := cfg.TLSConfig()
+
+		require.ErrorContains(t, err, "both a client certificate and a client key")
+	})
+
+	t.Run("rejects a CA file with no certificates in it", func(t *testing.T) {
+		empty := filepath.Join(t.TempDir(), "empty.pem")
+		require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0o600))
+		cfg := &config.Config{CAFile: empty}
+
+		_, err := cfg.TLSConfig()
+
+		require.ErrorContains(t, err, "contains no PEM certificates")
+	})
+
+	t.Run("rejects an unknown minimum version", func(t *testing.T) {
+		cfg := &config.Config{TLSMinVersion: "1.4"}
+
+		_, err := cfg.TLSConfig()
+
+		require.ErrorContains(t, err, "invalid TLS minimum version")
+	})
+
+	t.Run("skips verification only when asked to", func(t *testing.T) {
+		cfg := &config.Config{InsecureSkipVerify: true}
+
+		tlsConfig, err := cfg.TLSConfig()
+
+		require.NoError(t, err)
+		assert.True(t, tlsConfig.InsecureSkipVerify)
+	})
+
+	t.Run("routes through the proxy except for NO_PROXY hosts", func(t *testing.T) {
+		cfg := &config.Config{
+			ProxyURL: "http://proxy.corp.example:3128",
+			NoProxy:  "localhost, .internal.example, netorca.example:8443, 10.0.0.0/8",
+		}
+		transport, err := cfg.Transport()
+		require.NoError(t, err)
+
+		for target, proxied := range map[string]bool{
+			"https://api.netorca.io/v1/":           true,
+			"https://localhost/v1/":                false,
+			"https://api.internal.example/v1/":     false,
+			"https://internal.example/v1/":         true, // a leading dot means subdomains only
+			"https://netorca.example:8443/v1/":     false,
+			"https://netorca.example/v1/":          true, // the entry is pinned to port 8443
+			"https://10.20.30.40/v1/":              false,
+			"https://192.168.1.1/v1/":              true,
+			"https://sub.netorca.example:8443/v1/": false,
+		} {
+			req, err := http.NewRequestWithContext(context.Background(), "GET", target, nil)
+			require.NoError(t, err)
+
+			proxyURL, err := transport.Proxy(req)
+
+			require.NoError(t, err)
+			if proxied {
+				require.NotNil(t, proxyURL, target)
+				assert.Equal(t, "proxy.corp.example:3128", proxyURL.Host, target)
+			} else {
+				assert.Nil(t, proxyURL, target)
+			}
+		}
+	})
+
+	t.Run("rejects a proxy URL with an unsupported scheme", func(t *testing.T) {
+		cfg := &config.Config{ProxyURL: "ftp://proxy.corp.example"}
+
+		_, err := cfg.Transport()
+
+		require.ErrorContains(t, err, "proxy URL must use http, https or socks5")
+	})
+
+	t.Run("installs the transport on a new API client", func(t *testing.T) {
+		cfg := &config.Config{
+			BaseURL: "https://api.example.com", APIKey: "key", APIVersion: "v1", RequestTimeout: 5, CAFile: caFile,
+		}
+
+		nc, err := cfg.NewClient()
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://api.example.com/v1/", nc.BaseURL)
+		require.NotNil(t, nc.HTTPClient)
+		transport, ok := nc.HTTPClient.Transport.(*http.Transport)
+		require.True(t, ok)
+		assert.NotNil(t, transport.TLSClientConfig.RootCAs)
+	})
+}
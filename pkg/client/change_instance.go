@@ -4,30 +4,109 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
-	"strconv"
+	"strings"
 	"time"
 )
 
 // ChangeInstanceState represents the state of a change instance.
+//
+// Decoding never fails on an unfamiliar value: a state the platform added after this package
+// was released is kept verbatim, reports false from IsKnown, and encodes back unchanged.
 type ChangeInstanceState string
 
 const (
-	ChangeInstanceERROR     ChangeInstanceState = "ERROR"
-	ChangeInstancePENDING   ChangeInstanceState = "PENDING"
-	ChangeInstanceAPPROVED  ChangeInstanceState = "APPROVED"
+	// ChangeInstanceERROR means automation tried to fulfil the change and failed.
+	ChangeInstanceERROR ChangeInstanceState = "ERROR"
+	// ChangeInstancePENDING means the change is waiting for the service owner's decision.
+	ChangeInstancePENDING ChangeInstanceState = "PENDING"
+	// ChangeInstanceAPPROVED means the change was accepted and awaits fulfilment.
+	ChangeInstanceAPPROVED ChangeInstanceState = "APPROVED"
+	// ChangeInstanceCOMPLETED means the change was fulfilled.
 	ChangeInstanceCOMPLETED ChangeInstanceState = "COMPLETED"
-	ChangeInstanceCLOSED    ChangeInstanceState = "CLOSED"
-	ChangeInstanceREJECTED  ChangeInstanceState = "REJECTED"
+	// ChangeInstanceCLOSED means the change was withdrawn or superseded without fulfilment.
+	ChangeInstanceCLOSED ChangeInstanceState = "CLOSED"
+	// ChangeInstanceREJECTED means the service owner declined the change.
+	ChangeInstanceREJECTED ChangeInstanceState = "REJECTED"
 )
 
+// IsKnown reports whether the state is one this package models.
+func (s ChangeInstanceState) IsKnown() bool {
+	switch s {
+	case ChangeInstanceERROR, ChangeInstancePENDING, ChangeInstanceAPPROVED,
+		ChangeInstanceCOMPLETED, ChangeInstanceCLOSED, ChangeInstanceREJECTED:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether the change has stopped moving on its own: it was completed,
+// rejected, closed or failed. ERROR is included because nothing further happens to an errored
+// change unless somebody acts on it, which is what a caller waiting on an outcome needs to know.
+// An unknown state is not terminal.
+func (s ChangeInstanceState) IsTerminal() bool {
+	switch s {
+	case ChangeInstanceCOMPLETED, ChangeInstanceREJECTED, ChangeInstanceCLOSED, ChangeInstanceERROR:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON decodes the state, keeping values this package does not know about.
+func (s *ChangeInstanceState) UnmarshalJSON(data []byte) error {
+	value, err := decodeEnum(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode change instance state: %w", err)
+	}
+	*s = ChangeInstanceState(value)
+	return nil
+}
+
+// ChangeType is the kind of change a change instance asks for. Like ChangeInstanceState, it
+// decodes unfamiliar values verbatim rather than failing.
+type ChangeType string
+
+const (
+	// ChangeTypeCreate asks for a new service item to be built.
+	ChangeTypeCreate ChangeType = "CREATE"
+	// ChangeTypeModify asks for an existing service item to be changed - including the
+	// changes the platform raises on an item when something it references is modified.
+	ChangeTypeModify ChangeType = "MODIFY"
+	// ChangeTypeDelete asks for a service item to be removed.
+	ChangeTypeDelete ChangeType = "DELETE"
+)
+
+// IsKnown reports whether the change type is one this package models.
+func (t ChangeType) IsKnown() bool {
+	switch t {
+	case ChangeTypeCreate, ChangeTypeModify, ChangeTypeDelete:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON decodes the change type, keeping values this package does not know about.
+func (t *ChangeType) UnmarshalJSON(data []byte) error {
+	value, err := decodeEnum(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode change type: %w", err)
+	}
+	*t = ChangeType(value)
+	return nil
+}
+
 // GetChangeInstancesRequest represents the filters for change instances.
 // It includes fields for filtering by change instance state, type, and the consumer team,
 // as well as pagination and ordering options.
 // The POV (point of view) field is used to determine the API path.
+//
+// Most filters exist in two forms: the original string field, which takes a comma-joined list
+// as written on the wire, and a typed field (ServiceIDs beside ServiceID, States beside State)
+// that takes real values and is encoded the way the platform expects. Use either form for a
+// given filter; setting both is reported as an error by ToQueryParams.
 type GetChangeInstancesRequest struct {
 	// The POV (point of view) is used to determine the API path(serviceowner or consumer).
-	POV string `json:"pov"`
+	// Defaults to serviceowner.
+	POV POV `json:"pov"`
 	// ApplicationID is the ID of the application owning the change instance's service item.
 	// Comma-join several to get an "in" lookup (an OR), as with the other id filters here.
 	//
@@ -35,22 +114,42 @@ type GetChangeInstancesRequest struct {
 	// link to an application, so without it a caller has to list every change instance and
 	// sift them client-side.
 	ApplicationID string `json:"application_id"`
-	// ChangeType is the type of change instance (e.g., "CREATE", "UPDATE", "DELETE").
+	// ApplicationIDs is the typed form of ApplicationID.
+	ApplicationIDs []int `json:"-"`
+	// ChangeType is the type of change instance (e.g., "CREATE", "MODIFY", "DELETE").
 	ChangeType string `json:"change_type"`
+	// ChangeTypes is the typed form of ChangeType; several types are an "in" lookup.
+	ChangeTypes []ChangeType `json:"-"`
 	// CommitID is the ID of the commit associated with the submission.
 	CommitID string `json:"commit_id"`
 	// ConsumerTeamID is the ID of the consumer team associated with the change instance.
 	ConsumerTeamID string `json:"consumer_team_id"`
+	// ConsumerTeamIDs is the typed form of ConsumerTeamID.
+	ConsumerTeamIDs []int `json:"-"`
 	// Declaration is the declaration associated with the change instance.
 	Declaration string `json:"declaration"`
+	// DeclarationFilter is the typed form of Declaration: a map or struct matched against
+	// declaration fields exactly, encoded as compact JSON.
+	DeclarationFilter any `json:"-"`
 	// DeclarationContains is a substring to search for in the declaration.
 	DeclarationContains string `json:"declaration_contains"`
+	// DeclarationContainsFilter is the typed form of DeclarationContains.
+	DeclarationContainsFilter any `json:"-"`
 	// DeclarationRegex is a regex pattern to match against the declaration.
 	DeclarationRegex string `json:"declaration_regex"`
+	// DeclarationRegexFilter is the typed form of DeclarationRegex.
+	DeclarationRegexFilter any `json:"-"`
+	// DeclarationQuery is a typed search across all three declaration filters, built with
+	// DeclarationQuery(). A filter it renders must not also be set through the fields above.
+	DeclarationQuery *DeclarationSearch `json:"-"`
 	// EndDate restricts results to change instances modified at or before this time.
 	EndDate time.Time `json:"end_date"`
-	// ExcludeReferenced indicates whether to exclude referenced change instances.
+	// ExcludeReferenced indicates whether to exclude referenced change instances. Only true is
+	// ever sent; use ExcludeReferencedFilter to send an explicit false.
 	ExcludeReferenced bool `json:"exclude_referenced"`
+	// ExcludeReferencedFilter is the tri-state form of ExcludeReferenced. Nil leaves the
+	// platform default in force, and a pointer to false sends exclude_referenced=false.
+	ExcludeReferencedFilter *bool `json:"-"`
 	// Limit is the maximum number of results to return per page.
 	Limit int `json:"limit"`
 	// Modified is the timestamp of the last modification.
@@ -61,12 +160,18 @@ type GetChangeInstancesRequest struct {
 	Ordering string `json:"ordering"`
 	// ServiceID is the ID of the service associated with the change instance.
 	ServiceID string `json:"service_id"`
+	// ServiceIDs is the typed form of ServiceID.
+	ServiceIDs []int `json:"-"`
 	// ServiceItemID is the ID of the service item associated with the change instance.
 	ServiceItemID string `json:"service_item_id"`
+	// ServiceItemIDs is the typed form of ServiceItemID.
+	ServiceItemIDs []int `json:"-"`
 	// ServiceName is the name of the service associated with the change instance.
 	ServiceName string `json:"service_name"`
 	// ServiceOwnerTeamID is the ID of the service owner team associated with the change instance.
 	ServiceOwnerTeamID string `json:"service_owner_team_id"`
+	// ServiceOwnerTeamIDs is the typed form of ServiceOwnerTeamID.
+	ServiceOwnerTeamIDs []int `json:"-"`
 	// StartDate restricts results to change instances modified at or after this time. It is
 	// the same lower bound Modified already applies - the backend declares both against
 	// modified with a gte lookup - and exists so a window can be expressed as one pair of
@@ -74,78 +179,73 @@ type GetChangeInstancesRequest struct {
 	StartDate time.Time `json:"start_date"`
 	// State is the state of the change instance (e.g., "PENDING", "APPROVED", "REJECTED").
 	State string `json:"state"`
+	// States is the typed form of State; several states are an "in" lookup (an OR).
+	States []ChangeInstanceState `json:"-"`
 	// SubmissionID is the ID of the submission associated with the change instance.
 	SubmissionID string `json:"submission_id"`
+	// SubmissionIDs is the typed form of SubmissionID.
+	SubmissionIDs []int `json:"-"`
 }
 
 // ToQueryParams converts the GetChangeInstancesRequest fields into a URL-encoded query string.
-//
-//nolint:funlen // one flat branch per filter; splitting it would only hide which filters exist
+// It fails when a filter is set in both its string and its typed form, or when a declaration
+// filter cannot be encoded as JSON.
 func (r *GetChangeInstancesRequest) ToQueryParams() (string, error) {
-	params := url.Values{}
+	params := newQueryParams()
 
-	if r.ApplicationID != "" {
-		params.Add("application_id", r.ApplicationID)
-	}
-	if r.ChangeType != "" {
-		params.Add("change_type", r.ChangeType)
+	// ChangeInstanceState and ChangeType are named types, so the values need widening before
+	// they can be comma-joined into the "in" lookup the API expects.
+	states := make([]string, 0, len(r.States))
+	for _, state := range r.States {
+		states = append(states, string(state))
 	}
-	if r.CommitID != "" {
-		params.Add("commit_id", r.CommitID)
+	changeTypes := make([]string, 0, len(r.ChangeTypes))
+	for _, changeType := range r.ChangeTypes {
+		changeTypes = append(changeTypes, string(changeType))
 	}
-	if r.ConsumerTeamID != "" {
-		params.Add("consumer_team_id", r.ConsumerTeamID)
-	}
-	if r.Declaration != "" {
-		params.Add("declaration", r.Declaration)
-	}
-	if r.DeclarationContains != "" {
-		params.Add("declaration_contains", r.DeclarationContains)
-	}
-	if r.DeclarationRegex != "" {
-		params.Add("declaration_regex", r.DeclarationRegex)
-	}
-	if !r.EndDate.IsZero() {
-		params.Add("end_date", r.EndDate.Format(time.RFC3339))
+
+	for _, err := range []error{
+		params.SetIntsOrString("application_id", r.ApplicationIDs, r.ApplicationID),
+		params.SetIntsOrString("consumer_team_id", r.ConsumerTeamIDs, r.ConsumerTeamID),
+		params.SetIntsOrString("service_id", r.ServiceIDs, r.ServiceID),
+		params.SetIntsOrString("service_item_id", r.ServiceItemIDs, r.ServiceItemID),
+		params.SetIntsOrString("service_owner_team_id", r.ServiceOwnerTeamIDs, r.ServiceOwnerTeamID),
+		params.SetIntsOrString("submission_id", r.SubmissionIDs, r.SubmissionID),
+		params.SetStringsOrString("state", states, r.State),
+		params.SetStringsOrString("change_type", changeTypes, r.ChangeType),
+		params.SetJSONOrString("declaration", r.DeclarationFilter, r.Declaration),
+		params.SetJSONOrString("declaration_contains", r.DeclarationContainsFilter, r.DeclarationContains),
+		params.SetJSONOrString("declaration_regex", r.DeclarationRegexFilter, r.DeclarationRegex),
+		params.SetDeclarationSearch(r.DeclarationQuery),
+	} {
+		if err != nil {
+			return "", err
+		}
 	}
+
+	// The plain bool can only say "true"; the pointer can also say "false". When both are set
+	// they have to agree, since there is no way to tell which one the caller meant.
+	excludeReferenced := r.ExcludeReferencedFilter
 	if r.ExcludeReferenced {
-		params.Add("exclude_referenced", strconv.FormatBool(r.ExcludeReferenced))
-	}
-	if r.Limit > 0 {
-		params.Add("limit", strconv.Itoa(r.Limit))
-	}
-	if !r.Modified.IsZero() {
-		params.Add("modified", r.Modified.Format(time.RFC3339))
+		if excludeReferenced != nil && !*excludeReferenced {
+			return "", fmt.Errorf(
+				"the exclude_referenced filter is set twice with different values: use ExcludeReferencedFilter alone",
+			)
+		}
+		excludeReferenced = &r.ExcludeReferenced
 	}
-	if r.Offset > 0 {
-		params.Add("offset", strconv.Itoa(r.Offset))
-	}
-	if r.Ordering != "" {
-		params.Add("ordering", r.Ordering)
-	}
-	if r.ServiceID != "" {
-		params.Add("service_id", r.ServiceID)
-	}
-	if r.ServiceItemID != "" {
-		params.Add("service_item_id", r.ServiceItemID)
-	}
-	if r.ServiceName != "" {
-		params.Add("service_name", r.ServiceName)
-	}
-	if r.ServiceOwnerTeamID != "" {
-		params.Add("service_owner_team_id", r.ServiceOwnerTeamID)
-	}
-	if !r.StartDate.IsZero() {
-		params.Add("start_date", r.StartDate.Format(time.RFC3339))
-	}
-	if r.State != "" {
-		params.Add("state", r.State)
-	}
-	if r.SubmissionID != "" {
-		params.Add("submission_id", r.SubmissionID)
-	}
-
-	return params.Encode(), nil
+	params.SetBool("exclude_referenced", excludeReferenced)
+
+	params.SetString("commit_id", r.CommitID)
+	params.SetString("service_name", r.ServiceName)
+	params.SetTime("start_date", r.StartDate)
+	params.SetTime("end_date", r.EndDate)
+	params.SetTime("modified", r.Modified)
+	params.SetInt("limit", r.Limit)
+	params.SetInt("offset", r.Offset)
+	params.SetString("ordering", r.Ordering)
+
+	return strings.TrimPrefix(params.Encode(), "?"), nil
 }
 
 // GetChangeInstancesResponse represents the paginated response returned by the API.
@@ -165,13 +265,13 @@ type ChangeInstance struct {
 	// URL is the API endpoint for the change instance.
 	URL string `json:"url"`
 	// State is the current state of the change instance (e.g., "PENDING", "APPROVED").
-	State string `json:"state"`
+	State ChangeInstanceState `json:"state"`
 	// Created is the timestamp when the change instance was created.
 	Created time.Time `json:"created"`
 	// Modified is the timestamp when the change instance was last modified.
 	Modified time.Time `json:"modified"`
-	// ChangeType is the type of change (e.g., "CREATE", "UPDATE", "DELETE").
-	ChangeType string `json:"change_type"`
+	// ChangeType is the type of change (e.g., "CREATE", "MODIFY", "DELETE").
+	ChangeType ChangeType `json:"change_type"`
 	// Log is a string containing the log or message associated with the change instance.
 	Log string `json:"log"`
 	// Owner is the team responsible for the Service.
@@ -240,8 +340,8 @@ type UpdateChangeInstanceRequest struct {
 // the provided filters. It builds the endpoint URL based on the POV, converts the filters into
 // a query parameter string, sets up the HTTP GET request with necessary headers and a timeout,
 // and decodes the JSON response into a GetChangeInstancesResponse object.
-// Prefer GetChangeInstancesWithContext where a context is available - it lets the caller
-// cancel the request, which matters for anything long-running.
+//
+// Deprecated: Use GetChangeInstancesWithContext, which lets the caller cancel the request.
 func (c *Client) GetChangeInstances(filters *GetChangeInstancesRequest) (*GetChangeInstancesResponse, error) {
 	return c.GetChangeInstancesWithContext(context.Background(), filters)
 }
@@ -255,6 +355,15 @@ func (c *Client) GetChangeInstancesWithContext(
 	return c.listChangeInstances(ctx, filters, "")
 }
 
+// ListAllChangeInstances fetches every change instance matching the filters, across all pages:
+// 100 at a time unless the filters set another Limit, starting from their Offset.
+func (c *Client) ListAllChangeInstances(
+	ctx context.Context,
+	filters *GetChangeInstancesRequest,
+) ([]ChangeInstance, error) {
+	return c.listAllChangeInstances(ctx, filters, "")
+}
+
 // GetDependantChangeInstances fetches the change instances raised against services your team
 // owns but handed to a different team to fulfil - the copies your dependant teams are working
 // on, which the plain listing hides because it only shows changes your own team owns.
@@ -311,7 +420,7 @@ func (c *Client) listChangeInstances(
 
 	// The trailing slash is the canonical DRF route; without it the API replies 301 to the
 	// slashed URL, doubling the round trips.
-	endpoint := fmt.Sprintf("orcabase/%s/change_instances/%s", POV(filters.POV).orDefault(), action)
+	endpoint := fmt.Sprintf("orcabase/%s/change_instances/%s", filters.POV.orDefault(), action)
 	if params != "" {
 		endpoint += "?" + params
 	}
@@ -387,73 +496,185 @@ func (c *Client) ListChangeInstanceHistory(
 }
 
 // ApproveChangeInstance approves a change instance by updating its state to "APPROVED".
+//
+// Deprecated: Use ApproveChangeInstanceWithContext, which takes a context and a POV.
 func (c *Client) ApproveChangeInstance(id int, logStr string, deployedItem json.RawMessage) (*ChangeInstance, error) {
-	return c.updateChangeInstanceState(id, ChangeInstanceAPPROVED, logStr, deployedItem)
+	return c.ApproveChangeInstanceWithContext(context.Background(), POVServiceOwner, id, logStr, deployedItem)
 }
 
 // RejectChangeInstance rejects a change instance by updating its state to "REJECTED".
+//
+// Deprecated: Use RejectChangeInstanceWithContext, which takes a context and a POV.
 func (c *Client) RejectChangeInstance(id int, logStr string, deployedItem json.RawMessage) (*ChangeInstance, error) {
-	return c.updateChangeInstanceState(id, ChangeInstanceREJECTED, logStr, deployedItem)
+	return c.RejectChangeInstanceWithContext(context.Background(), POVServiceOwner, id, logStr, deployedItem)
 }
 
 // CompleteChangeInstance completes a change instance by updating its state to "COMPLETED".
+//
+// Deprecated: Use CompleteChangeInstanceWithContext, which takes a context and a POV.
 func (c *Client) CompleteChangeInstance(id int, logStr string, deployedItem json.RawMessage) (*ChangeInstance, error) {
-	return c.updateChangeInstanceState(id, ChangeInstanceCOMPLETED, logStr, deployedItem)
+	return c.CompleteChangeInstanceWithContext(context.Background(), POVServiceOwner, id, logStr, deployedItem)
 }
 
 // CloseChangeInstance closes a change instance by updating its state to "CLOSED".
+//
+// Deprecated: Use CloseChangeInstanceWithContext, which takes a context and a POV.
 func (c *Client) CloseChangeInstance(id int, logStr string, deployedItem json.RawMessage) (*ChangeInstance, error) {
-	return c.updateChangeInstanceState(id, ChangeInstanceCLOSED, logStr, deployedItem)
+	return c.CloseChangeInstanceWithContext(context.Background(), POVServiceOwner, id, logStr, deployedItem)
 }
 
 // SetErrorChangeInstance sets the error state for a change instance by updating its state to "ERROR".
+//
+// Deprecated: Use SetErrorChangeInstanceWithContext, which takes a context and a POV.
 func (c *Client) SetErrorChangeInstance(id int, logStr string, deployedItem json.RawMessage) (*ChangeInstance, error) {
-	return c.updateChangeInstanceState(id, ChangeInstanceERROR, logStr, deployedItem)
+	return c.SetErrorChangeInstanceWithContext(context.Background(), POVServiceOwner, id, logStr, deployedItem)
 }
 
 // PendingChangeInstance sets the pending state for a change instance by updating its state to "PENDING".
+//
+// Deprecated: Use PendingChangeInstanceWithContext, which takes a context and a POV.
 func (c *Client) PendingChangeInstance(id int, logStr string, deployedItem json.RawMessage) (*ChangeInstance, error) {
-	return c.updateChangeInstanceState(id, ChangeInstancePENDING, logStr, deployedItem)
+	return c.PendingChangeInstanceWithContext(context.Background(), POVServiceOwner, id, logStr, deployedItem)
 }
 
-// UpdateChangeInstanceState transitions a change instance to the given state, honouring the
-// caller's context.
-//
-// The log string is recorded against the change as the reason for the transition, and is what a
-// consumer reads when their request is rejected - write it for them, not for a machine. Pass a
-// nil deployedItem to leave the linked deployed item untouched.
-//
-// The platform enforces which transitions are legal (a COMPLETED change must have been APPROVED,
-// for instance); an illegal one comes back as an error wrapping ErrBadRequest.
-func (c *Client) UpdateChangeInstanceState(
+// ApproveChangeInstanceWithContext moves a change instance to APPROVED. It is shorthand for
+// UpdateChangeInstanceState, whose documentation covers the log and deployed item arguments.
+func (c *Client) ApproveChangeInstanceWithContext(
 	ctx context.Context,
 	pov POV,
 	id int,
-	state ChangeInstanceState,
 	logStr string,
 	deployedItem json.RawMessage,
+	opts ...TransitionOption,
 ) (*ChangeInstance, error) {
-	endpoint := fmt.Sprintf("orcabase/%s/change_instances/%d/", pov.orDefault(), id)
+	return c.UpdateChangeInstanceState(ctx, pov, id, ChangeInstanceAPPROVED, logStr, deployedItem, opts...)
+}
 
-	body := UpdateChangeInstanceRequest{
-		State:        state,
-		Log:          logStr,
-		DeployedItem: deployedItem,
-	}
+// RejectChangeInstanceWithContext moves a change instance to REJECTED. The log is what the
+// consumer reads to learn why, so it is worth writing for them.
+func (c *Client) RejectChangeInstanceWithContext(
+	ctx context.Context,
+	pov POV,
+	id int,
+	logStr string,
+	deployedItem json.RawMessage,
+	opts ...TransitionOption,
+) (*ChangeInstance, error) {
+	return c.UpdateChangeInstanceState(ctx, pov, id, ChangeInstanceREJECTED, logStr, deployedItem, opts...)
+}
 
-	var response ChangeInstance
-	if err := c.doRequest(ctx, "PATCH", endpoint, body, &response); err != nil {
+// CompleteChangeInstanceWithContext moves a change instance to COMPLETED, typically with the
+// deployed item that records what was built.
+func (c *Client) CompleteChangeInstanceWithContext(
+	ctx context.Context,
+	pov POV,
+	id int,
+	logStr string,
+	deployedItem json.RawMessage,
+	opts ...TransitionOption,
+) (*ChangeInstance, error) {
+	return c.UpdateChangeInstanceState(ctx, pov, id, ChangeInstanceCOMPLETED, logStr, deployedItem, opts...)
+}
+
+// CloseChangeInstanceWithContext moves a change instance to CLOSED.
+func (c *Client) CloseChangeInstanceWithContext(
+	ctx context.Context,
+	pov POV,
+	id int,
+	logStr string,
+	deployedItem json.RawMessage,
+	opts ...TransitionOption,
+) (*ChangeInstance, error) {
+	return c.UpdateChangeInstanceState(ctx, pov, id, ChangeInstanceCLOSED, logStr, deployedItem, opts...)
+}
+
+// SetErrorChangeInstanceWithContext moves a change instance to ERROR - the state automation
+// reports when it tried and failed, as opposed to REJECTED, which is a decision not to try.
+func (c *Client) SetErrorChangeInstanceWithContext(
+	ctx context.Context,
+	pov POV,
+	id int,
+	logStr string,
+	deployedItem json.RawMessage,
+	opts ...TransitionOption,
+) (*ChangeInstance, error) {
+	return c.UpdateChangeInstanceState(ctx, pov, id, ChangeInstanceERROR, logStr, deployedItem, opts...)
+}
+
+// PendingChangeInstanceWithContext moves a change instance back to PENDING.
+func (c *Client) PendingChangeInstanceWithContext(
+	ctx context.Context,
+	pov POV,
+	id int,
+	logStr string,
+	deployedItem json.RawMessage,
+	opts ...TransitionOption,
+) (*ChangeInstance, error) {
+	return c.UpdateChangeInstanceState(ctx, pov, id, ChangeInstancePENDING, logStr, deployedItem, opts...)
+}
+
+// LogChangeInstance records a log entry against a change instance without moving it: the change
+// is read, and its current state written back with the new log. It is how a change explains a
+// wait - a deferral, a dependency still in flight - to the consumer watching it.
+//
+// Pass AppendLog to add to the log rather than replace it; the one read serves both. Gates and
+// Guarded do not apply, since the change does not move.
+func (c *Client) LogChangeInstance(
+	ctx context.Context,
+	pov POV,
+	id int,
+	logStr string,
+	opts ...TransitionOption,
+) (*ChangeInstance, error) {
+	current, err := c.GetChangeInstance(ctx, pov, id)
+	if err != nil {
 		return nil, err
 	}
-	return &response, nil
+	settings := newTransitionSettings(opts)
+	settings.guarded = false
+	return c.writeTransition(ctx, pov, id, current.State, logStr, nil, settings, current)
 }
 
-// updateChangeInstanceState is the serviceowner-POV shorthand the state-specific helpers use.
-func (c *Client) updateChangeInstanceState(
+// UpdateChangeInstanceState transitions a change instance to the given state, honouring the
+// caller's context.
+//
+// The log string is recorded against the change as the reason for the transition, and is what a
+// consumer reads when their request is rejected - write it for them, not for a machine. It
+// replaces the change's log; pass AppendLog to add a ChangeLog's entries to it instead. Pass a
+// nil deployedItem to leave the linked deployed item untouched, or WithDeployedItem to give it
+// as a typed value.
+//
+// The platform enforces which transitions are legal (a COMPLETED change must have been APPROVED,
+// for instance); an illegal one comes back as an error wrapping ErrBadRequest. Pass Guarded() to
+// check the move against the same rules first and get an *InvalidTransitionError naming the
+// allowed targets instead - see CanTransition for the table. Pass WithGate to hold the move back
+// while a gate, such as a change window, is closed.
+func (c *Client) UpdateChangeInstanceState(
+	ctx context.Context,
+	pov POV,
 	id int,
 	state ChangeInstanceState,
 	logStr string,
 	deployedItem json.RawMessage,
+	opts ...TransitionOption,
 ) (*ChangeInstance, error) {
-	return c.UpdateChangeInstanceState(context.Background(), POVServiceOwner, id, state, logStr, deployedItem)
+	settings := newTransitionSettings(opts)
+	deployedItem, err := settings.resolveDeployedItem(deployedItem)
+	if err != nil {
+		return nil, err
+	}
+	// A guarded move is checked before it is gated, so an illegal one never logs a deferral.
+	var current *ChangeInstance
+	if settings.guarded && len(settings.gates) > 0 {
+		if current, err = c.GetChangeInstance(ctx, pov, id); err != nil {
+			return nil, err
+		}
+		if err := ValidateTransition(current, state); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.gateTransition(ctx, pov, id, state, settings.gates); err != nil {
+		return nil, err
+	}
+	return c.writeTransition(ctx, pov, id, state, logStr, deployedItem, settings, current)
 }
@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// BulkTransition is one change instance move in a bulk update.
+type BulkTransition struct {
+	// ID is the change instance to move.
+	ID int
+	// State is the state to move it to.
+	State ChangeInstanceState
+	// Log is recorded against the change, as for UpdateChangeInstanceState.
+	Log string
+	// DeployedItem is recorded against the change; nil leaves it untouched.
+	DeployedItem json.RawMessage
+	// ChangeLog, when set, is appended to the change's log as by AppendLog, with Log as its
+	// final entry.
+	ChangeLog *ChangeLog
+}
+
+// BulkMode decides what a bulk update does after a transition fails.
+type BulkMode int
+
+const (
+	// BulkBestEffort sends every transition regardless of failures. It is the default.
+	BulkBestEffort BulkMode = iota
+	// BulkStopOnError sends no further transitions once one fails. Those already in flight
+	// finish; the rest are reported with ErrBulkAborted.
+	BulkStopOnError
+)
+
+// BulkOptions tunes BulkUpdateChangeInstanceState. The zero value (or nil) runs best effort,
+// eight at a time, without a preview.
+type BulkOptions struct {
+	// Concurrency is how many requests run at once. Defaults to 8.
+	Concurrency int
+	// Mode decides what happens after a failure.
+	Mode BulkMode
+	// PreviewFirst runs PreviewChangeInstanceTransitions before sending anything, and sends
+	// nothing if any transition would be refused.
+	PreviewFirst bool
+	// TransitionOptions are passed to every UpdateChangeInstanceState call.
+	TransitionOptions []TransitionOption
+}
+
+// Validate checks the options are usable.
+func (o *BulkOptions) Validate() error {
+	if o.Concurrency < 0 {
+		return fmt.Errorf("bulk concurrency cannot be negative")
+	}
+	if o.Mode != BulkBestEffort && o.Mode != BulkStopOnError {
+		return fmt.Errorf("unknown bulk mode %d", o.Mode)
+	}
+	return nil
+}
+
+// BulkResult is the outcome of one transition in a bulk update or preview.
+type BulkResult struct {
+	// ID is the change instance.
+	ID int
+	// ChangeInstance is the change after the update, or as it stands now for a preview. Nil
+	// when the request failed.
+	ChangeInstance *ChangeInstance
+	// Err is why the transition failed or would fail; nil on success.
+	Err error
+}
+
+// BulkResults holds the outcomes of a bulk update, in the order the transitions were given.
+type BulkResults []BulkResult
+
+// Failed returns the results that carry an error.
+func (r BulkResults) Failed() BulkResults {
+	var failed BulkResults
+	for _, result := range r {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err joins the errors of the failed results, each prefixed with its change instance id. It is
+// nil when every transition succeeded.
+func (r BulkResults) Err() error {
+	var errs []error
+	for _, result := range r.Failed() {
+		errs = append(errs, fmt.Errorf("change instance %d: %w", result.ID, result.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// BulkUpdateChangeInstanceState moves many change instances at once - approving or closing a
+// maintenance window's worth of changes, say - with bounded concurrency.
+//
+// Each transition's outcome is reported in the returned results, in input order; a failed one
+// does not fail the call. The error return is reserved for a batch that could not be attempted:
+// invalid options, a transition with no id or state, the same id listed twice, or a preview that
+// found a problem (in which case the preview's results are returned, and nothing was sent).
+func (c *Client) BulkUpdateChangeInstanceState(
+	ctx context.Context,
+	pov POV,
+	transitions []BulkTransition,
+	opts *BulkOptions,
+) (BulkResults, error) {
+	if opts == nil {
+		opts = &BulkOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if err := validateBulkTransitions(transitions); err != nil {
+		return nil, err
+	}
+
+	if opts.PreviewFirst {
+		preview := c.previewTransitions(ctx, pov, transitions, opts.Concurrency)
+		if failed := len(preview.Failed()); failed > 0 {
+			return preview, fmt.Errorf("%w: the preview refused %d of %d transitions, so none was sent",
+				ErrBulkAborted, failed, len(transitions))
+		}
+	}
+
+	results := make(BulkResults, len(transitions))
+	var stopped atomic.Bool
+	runBulk(len(transitions), opts.Concurrency, func(i int) {
+		transition := transitions[i]
+		results[i].ID = transition.ID
+		if stopped.Load() {
+			results[i].Err = ErrBulkAborted
+			return
+		}
+
+		transitionOpts := opts.TransitionOptions
+		if transition.ChangeLog != nil {
+			transitionOpts = append(transitionOpts[:len(transitionOpts):len(transitionOpts)],
+				AppendLog(transition.ChangeLog))
+		}
+		ci, err := c.UpdateChangeInstanceState(ctx, pov, transition.ID, transition.State, transition.Log,
+			transition.DeployedItem, transitionOpts...)
+		results[i].ChangeInstance, results[i].Err = ci, err
+		if err != nil && opts.Mode == BulkStopOnError {
+			stopped.Store(true)
+		}
+	})
+	return results, nil
+}
+
+// PreviewChangeInstanceTransitions checks a bulk update against the state machine without
+// changing anything. It fetches each change instance and reports, per transition, the change as
+// it stands and the *InvalidTransitionError (or fetch error) that sending it would meet.
+//
+// The preview is a snapshot: changes can still move before the update is sent.
+func (c *Client) PreviewChangeInstanceTransitions(
+	ctx context.Context,
+	pov POV,
+	transitions []BulkTransition,
+) (BulkResults, error) {
+	if err := validateBulkTransitions(transitions); err != nil {
+		return nil, err
+	}
+	return c.previewTransitions(ctx, pov, transitions, 0), nil
+}
+
+// previewTransitions is the shared implementation of the preview.
+func (c *Client) previewTransitions(
+	ctx context.Context,
+	pov POV,
+	transitions []BulkTransition,
+	concurrency int,
+) BulkResults {
+	results := make(BulkResults, len(transitions))
+	runBulk(len(transitions), concurrency, func(i int) {
+		transition := transitions[i]
+		results[i].ID = transition.ID
+
+		current, err := c.GetChangeInstance(ctx, pov, transition.ID)
+		if err != nil {
+			results[i].Err = err
+			return
+		}
+		results[i].ChangeInstance = current
+		results[i].Err = ValidateTransition(current, transition.State)
+	})
+	return results
+}
+
+// validateBulkTransitions rejects a batch that cannot be run as given. The same id twice would
+// race two writes against one change, with an outcome depending on scheduling.
+func validateBulkTransitions(transitions []BulkTransition) error {
+	seen := make(map[int]bool, len(transitions))
+	for i, transition := range transitions {
+		if transition.ID <= 0 {
+			return fmt.Errorf("bulk transition %d has no change instance id", i)
+		}
+		if transition.State == "" {
+			return fmt.Errorf("bulk transition for change instance %d has no state", transition.ID)
+		}
+		if seen[transition.ID] {
+			return fmt.Errorf("change instance %d appears more than once in the bulk update", transition.ID)
+		}
+		seen[transition.ID] = true
+	}
+	return nil
+}
+
+// runBulk calls work for each index from 0 to n-1, at most concurrency at a time (8 when zero),
+// and returns once every call has. It leaves cancellation to the work: each call makes its
+// request with the caller's context, so after it ends the rest fail fast with its error.
+func runBulk(n, concurrency int, work func(i int)) {
+	if concurrency == 0 {
+		concurrency = 8
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range n {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			work(i)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,155 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var changeInstanceDetail = regexp.MustCompile(`/orcabase/serviceowner/change_instances/(\d+)/$`)
+
+// registerBulkResponders answers PATCHes by echoing the requested state back, except for the ids
+// in failing, which get a 400. GETs report each change as the given state.
+func registerBulkResponders(current client.ChangeInstanceState, failing ...int) {
+	failed := map[string]bool{}
+	for _, id := range failing {
+		failed[fmt.Sprint(id)] = true
+	}
+
+	httpmock.RegisterRegexpResponder("PATCH", changeInstanceDetail, func(req *http.Request) (*http.Response, error) {
+		id := changeInstanceDetail.FindStringSubmatch(req.URL.Path)[1]
+		if failed[id] {
+			return httpmock.NewStringResponse(400, `{"state":["Invalid transition"]}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"id":`+id+`,"state":"CLOSED"}`), nil
+	})
+	httpmock.RegisterRegexpResponder("GET", changeInstanceDetail, func(req *http.Request) (*http.Response, error) {
+		id := changeInstanceDetail.FindStringSubmatch(req.URL.Path)[1]
+		return httpmock.NewStringResponse(200,
+			`{"id":`+id+`,"state":"`+string(current)+`","service":{"name":"vm","allow_manual_approval":true}}`), nil
+	})
+}
+
+func closeAll(ids ...int) []client.BulkTransition {
+	transitions := make([]client.BulkTransition, 0, len(ids))
+	for _, id := range ids {
+		transitions = append(transitions, client.BulkTransition{
+			ID: id, State: client.ChangeInstanceCLOSED, Log: "closed after the maintenance window",
+		})
+	}
+	return transitions
+}
+
+func TestBulkUpdateChangeInstanceState(t *testing.T) {
+	t.Run("best effort reports every outcome in order", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBulkResponders(client.ChangeInstancePENDING, 2)
+
+		nc := newPackTestClient(t)
+		results, err := nc.BulkUpdateChangeInstanceState(
+			context.Background(), client.POVServiceOwner, closeAll(1, 2, 3), &client.BulkOptions{Concurrency: 2},
+		)
+		require.NoError(t, err)
+
+		require.Len(t, results, 3)
+		for i, id := range []int{1, 2, 3} {
+			assert.Equal(t, id, results[i].ID)
+		}
+		assert.Equal(t, client.ChangeInstanceCLOSED, results[0].ChangeInstance.State)
+		require.ErrorIs(t, results[1].Err, client.ErrBadRequest)
+		assert.Nil(t, results[1].ChangeInstance)
+		assert.NoError(t, results[2].Err)
+
+		require.Len(t, results.Failed(), 1)
+		require.ErrorIs(t, results.Err(), client.ErrBadRequest)
+		assert.Contains(t, results.Err().Error(), "change instance 2: ")
+		assert.Equal(t, 3, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("stop on error sends nothing after the first failure", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBulkResponders(client.ChangeInstancePENDING, 1)
+
+		nc := newPackTestClient(t)
+		results, err := nc.BulkUpdateChangeInstanceState(context.Background(), client.POVServiceOwner,
+			closeAll(1, 2, 3), &client.BulkOptions{Concurrency: 1, Mode: client.BulkStopOnError})
+		require.NoError(t, err)
+
+		require.ErrorIs(t, results[0].Err, client.ErrBadRequest)
+		require.ErrorIs(t, results[1].Err, client.ErrBulkAborted)
+		require.ErrorIs(t, results[2].Err, client.ErrBulkAborted)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("a batch that cannot run is refused up front", func(t *testing.T) {
+		nc := newPackTestClient(t)
+		_, err := nc.BulkUpdateChangeInstanceState(context.Background(), client.POVServiceOwner,
+			closeAll(1, 2, 1), nil)
+		require.ErrorContains(t, err, "change instance 1 appears more than once")
+
+		_, err = nc.BulkUpdateChangeInstanceState(context.Background(), client.POVServiceOwner,
+			[]client.BulkTransition{{ID: 4}}, nil)
+		require.ErrorContains(t, err, "has no state")
+
+		_, err = nc.BulkUpdateChangeInstanceState(context.Background(), client.POVServiceOwner,
+			closeAll(1), &client.BulkOptions{Concurrency: -1})
+		require.Error(t, err)
+	})
+}
+
+func TestPreviewChangeInstanceTransitions(t *testing.T) {
+	t.Run("reports refusals without writing", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBulkResponders(client.ChangeInstanceREJECTED)
+
+		nc := newPackTestClient(t)
+		preview, err := nc.PreviewChangeInstanceTransitions(context.Background(), client.POVServiceOwner,
+			closeAll(7, 8))
+		require.NoError(t, err)
+
+		require.Len(t, preview.Failed(), 2)
+		require.ErrorIs(t, preview[0].Err, client.ErrInvalidTransition)
+		assert.Equal(t, client.ChangeInstanceREJECTED, preview[0].ChangeInstance.State)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount(), "only the two reads were made")
+	})
+
+	t.Run("preview first sends nothing when any transition would fail", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBulkResponders(client.ChangeInstanceCOMPLETED)
+
+		nc := newPackTestClient(t)
+		transitions := closeAll(1)
+		transitions = append(transitions, client.BulkTransition{ID: 2, State: client.ChangeInstancePENDING})
+
+		results, err := nc.BulkUpdateChangeInstanceState(context.Background(), client.POVServiceOwner,
+			transitions, &client.BulkOptions{PreviewFirst: true})
+		require.ErrorIs(t, err, client.ErrBulkAborted)
+		assert.NoError(t, results[0].Err, "COMPLETED to CLOSED is allowed")
+		require.ErrorIs(t, results[1].Err, client.ErrInvalidTransition)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount(), "only the two reads were made")
+	})
+
+	t.Run("preview first sends everything once the preview passes", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		registerBulkResponders(client.ChangeInstanceAPPROVED)
+
+		nc := newPackTestClient(t)
+		results, err := nc.BulkUpdateChangeInstanceState(context.Background(), client.POVServiceOwner,
+			closeAll(1, 2), &client.BulkOptions{PreviewFirst: true})
+		require.NoError(t, err)
+		require.NoError(t, results.Err())
+		assert.Equal(t, 4, httpmock.GetTotalCallCount())
+	})
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// unresolvedStates are the states in which a change instance still has work ahead of it. ERROR
+// counts: automation may retry it, and until it is decided it still stands in the way of the
+// changes behind it.
+var unresolvedStates = []ChangeInstanceState{ChangeInstancePENDING, ChangeInstanceAPPROVED, ChangeInstanceERROR}
+
+// ChangeConflict is two or more unresolved change instances on one service item - an UPDATE
+// queued behind another, or a DELETE racing a MODIFY - which must be applied in order.
+type ChangeConflict struct {
+	// ServiceItem is the item the changes share, as the first of them carries it.
+	ServiceItem ServiceItem
+	// ChangeInstances are the unresolved changes, oldest first: see OrderChangeInstances.
+	ChangeInstances []ChangeInstance
+}
+
+// String describes the conflict: "service item web-1 (5): 10 MODIFY APPROVED, 12 DELETE PENDING".
+func (c ChangeConflict) String() string {
+	changes := make([]string, len(c.ChangeInstances))
+	for i, ci := range c.ChangeInstances {
+		changes[i] = fmt.Sprintf("%d %s %s", ci.ID, ci.ChangeType, ci.State)
+	}
+	return fmt.Sprintf("service item %s (%d): %s", c.ServiceItem.Name, c.ServiceItem.ID, strings.Join(changes, ", "))
+}
+
+// OrderChangeInstances sorts change instances into the order they should be applied in: by
+// submission, since a consumer's later commit supersedes an earlier one, then by creation time,
+// then by id.
+func OrderChangeInstances(changes []ChangeInstance) {
+	sort.SliceStable(changes, func(i, j int) bool { return changeInstanceBefore(&changes[i], &changes[j]) })
+}
+
+// changeInstanceBefore reports whether a should be applied before b.
+func changeInstanceBefore(a, b *ChangeInstance) bool {
+	if a.Submission.ID != b.Submission.ID {
+		return a.Submission.ID < b.Submission.ID
+	}
+	if !a.Created.Equal(b.Created) {
+		return a.Created.Before(b.Created)
+	}
+	return a.ID < b.ID
+}
+
+// DetectChangeConflicts finds the service items with more than one unresolved change instance
+// (PENDING, APPROVED or ERROR) among the given changes, ordered by service item id. Resolved
+// changes and changes with no service item are ignored.
+func DetectChangeConflicts(changes []ChangeInstance) []ChangeConflict {
+	byItem := map[int][]ChangeInstance{}
+	for _, ci := range changes {
+		if ci.ServiceItem.ID == 0 || !slices.Contains(unresolvedStates, ci.State) {
+			continue
+		}
+		byItem[ci.ServiceItem.ID] = append(byItem[ci.ServiceItem.ID], ci)
+	}
+
+	var conflicts []ChangeConflict
+	for _, group := range byItem {
+		if len(group) < 2 {
+			continue
+		}
+		OrderChangeInstances(group)
+		conflicts = append(conflicts, ChangeConflict{ServiceItem: group[0].ServiceItem, ChangeInstances: group})
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].ServiceItem.ID < conflicts[j].ServiceItem.ID })
+	return conflicts
+}
+
+// FindChangeConflicts lists the unresolved change instances matching the filters, across all
+// pages, and reports the service items that have more than one. The filters' States, and the
+// legacy State, are replaced with the unresolved states.
+func (c *Client) FindChangeConflicts(
+	ctx context.Context,
+	filters *GetChangeInstancesRequest,
+) ([]ChangeConflict, error) {
+	var listing GetChangeInstancesRequest
+	if filters != nil {
+		listing = *filters
+	}
+	listing.State = ""
+	listing.States = slices.Clone(unresolvedStates)
+	changes, err := c.listAllChangeInstances(ctx, &listing, "")
+	if err != nil {
+		return nil, err
+	}
+	return DetectChangeConflicts(changes), nil
+}
+
+// ConflictGateOptions configures ConflictGate. The zero value (or nil) refuses completions, as
+// the service owner.
+type ConflictGateOptions struct {
+	// POV is the point of view to read from. Defaults to the service owner.
+	POV POV
+	// States are the moves the gate checks. Defaults to COMPLETED alone: reviewing a newer
+	// change while an older one is in flight is harmless, applying it is not.
+	States []ChangeInstanceState
+	// Warn, when set, makes the gate warn instead of refusing: it is called with the conflict
+	// and the move goes ahead.
+	Warn func(ctx context.Context, conflict *ChangeConflictError)
+}
+
+// ConflictGate returns a TransitionGate that refuses to move a change instance while an older
+// change on the same service item is unresolved, returning a *ChangeConflictError. Pass it to
+// a state helper with WithGate, or to a worker as its Gate: the worker asks the gate before
+// calling the handler, so a refused change is not handled, only logged, and is offered again on
+// the next poll.
+//
+// Each check reads the change and lists the unresolved changes on its service item - two
+// requests per gated transition. A read that fails refuses the move with the read's error.
+func (c *Client) ConflictGate(opts *ConflictGateOptions) TransitionGate {
+	if opts == nil {
+		opts = &ConflictGateOptions{}
+	}
+	gated := opts.States
+	if len(gated) == 0 {
+		gated = []ChangeInstanceState{ChangeInstanceCOMPLETED}
+	}
+	pov, warn := opts.POV, opts.Warn
+
+	return func(ctx context.Context, id int, to ChangeInstanceState) error {
+		if !slices.Contains(gated, to) {
+			return nil
+		}
+		ci, err := c.GetChangeInstance(ctx, pov, id)
+		if err != nil {
+			return err
+		}
+		if ci.ServiceItem.ID == 0 {
+			return nil
+		}
+
+		siblings, err := c.listAllChangeInstances(ctx, &GetChangeInstancesRequest{
+			POV:            pov,
+			ServiceItemIDs: []int{ci.ServiceItem.ID},
+			States:         slices.Clone(unresolvedStates),
+		}, "")
+		if err != nil {
+			return err
+		}
+		OrderChangeInstances(siblings)
+
+		var blocking []int
+		for i := range siblings {
+			if siblings[i].ID != ci.ID && changeInstanceBefore(&siblings[i], ci) {
+				blocking = append(blocking, siblings[i].ID)
+			}
+		}
+		if len(blocking) == 0 {
+			return nil
+		}
+
+		conflict := &ChangeConflictError{ID: id, To: to, ServiceItemID: ci.ServiceItem.ID, Blocking: blocking}
+		if warn != nil {
+			warn(ctx, conflict)
+			return nil
+		}
+		return conflict
+	}
+}
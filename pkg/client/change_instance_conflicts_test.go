@@ -0,0 +1,147 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// itemChange builds a change instance on a service item, raised by a submission at a time.
+func itemChange(id, item, submission int, created time.Time, changeType client.ChangeType,
+	state client.ChangeInstanceState,
+) client.ChangeInstance {
+	return client.ChangeInstance{
+		ID:          id,
+		State:       state,
+		ChangeType:  changeType,
+		Created:     created,
+		Submission:  client.Submission{ID: submission},
+		ServiceItem: client.ServiceItem{ID: item, Name: "web-1"},
+	}
+}
+
+func TestDetectChangeConflicts(t *testing.T) {
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	conflicts := client.DetectChangeConflicts([]client.ChangeInstance{
+		itemChange(12, 5, 3, at, client.ChangeTypeDelete, client.ChangeInstancePENDING),
+		// created later, but by the earlier submission
+		itemChange(10, 5, 2, at.Add(time.Hour), client.ChangeTypeModify, client.ChangeInstanceAPPROVED),
+		itemChange(11, 5, 1, at, client.ChangeTypeCreate, client.ChangeInstanceCOMPLETED),
+		itemChange(14, 6, 4, at.Add(time.Minute), client.ChangeTypeModify, client.ChangeInstanceERROR),
+		itemChange(13, 6, 4, at, client.ChangeTypeModify, client.ChangeInstancePENDING),
+		itemChange(15, 7, 4, at, client.ChangeTypeModify, client.ChangeInstancePENDING),
+		itemChange(16, 0, 4, at, client.ChangeTypeCreate, client.ChangeInstancePENDING),
+		itemChange(17, 0, 4, at, client.ChangeTypeCreate, client.ChangeInstancePENDING),
+	})
+
+	require.Len(t, conflicts, 2)
+	assert.Equal(t, "service item web-1 (5): 10 MODIFY APPROVED, 12 DELETE PENDING", conflicts[0].String())
+	assert.Equal(t, 6, conflicts[1].ServiceItem.ID)
+	assert.Equal(t, 13, conflicts[1].ChangeInstances[0].ID, "same submission: the earlier created goes first")
+	assert.Equal(t, 14, conflicts[1].ChangeInstances[1].ID)
+}
+
+func TestFindChangeConflicts(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var query url.Values
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", func(req *http.Request) (*http.Response, error) {
+		query = req.URL.Query()
+		return httpmock.NewStringResponse(200, `{"count":2,"next":null,"results":[
+			{"id":1,"state":"PENDING","service_item":{"id":5}},
+			{"id":2,"state":"APPROVED","service_item":{"id":5}}]}`), nil
+	})
+
+	nc := newPackTestClient(t)
+	conflicts, err := nc.FindChangeConflicts(context.Background(), &client.GetChangeInstancesRequest{
+		ServiceName: "vm",
+		States:      []client.ChangeInstanceState{client.ChangeInstanceCOMPLETED},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "PENDING,APPROVED,ERROR", query.Get("state"))
+	assert.Equal(t, "vm", query.Get("service_name"))
+	require.Len(t, conflicts, 1)
+	assert.Len(t, conflicts[0].ChangeInstances, 2)
+
+	_, err = nc.FindChangeConflicts(context.Background(), &client.GetChangeInstancesRequest{
+		State: string(client.ChangeInstanceCOMPLETED),
+	})
+	require.NoError(t, err, "the legacy State is replaced too")
+	assert.Equal(t, "PENDING,APPROVED,ERROR", query.Get("state"))
+}
+
+func TestConflictGate(t *testing.T) {
+	const detailURL = changeInstancesRoot + "/12/"
+	register := func() *url.Values {
+		var query url.Values
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200,
+			`{"id":12,"state":"APPROVED","submission":{"id":3},"service_item":{"id":5}}`))
+		httpmock.RegisterResponder("GET", changeInstancesRoot+"/", func(req *http.Request) (*http.Response, error) {
+			query = req.URL.Query()
+			return httpmock.NewStringResponse(200, `{"count":3,"next":null,"results":[
+				{"id":13,"state":"PENDING","submission":{"id":4},"service_item":{"id":5}},
+				{"id":12,"state":"APPROVED","submission":{"id":3},"service_item":{"id":5}},
+				{"id":10,"state":"ERROR","submission":{"id":2},"service_item":{"id":5}}]}`), nil
+		})
+		return &query
+	}
+
+	t.Run("refuses while an older change is open", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		query := register()
+
+		nc := newPackTestClient(t)
+		_, err := nc.CompleteChangeInstanceWithContext(context.Background(), client.POVServiceOwner, 12, "done", nil,
+			client.WithGate(nc.ConflictGate(nil)))
+
+		require.ErrorIs(t, err, client.ErrChangeConflict)
+		var conflict *client.ChangeConflictError
+		require.True(t, errors.As(err, &conflict))
+		assert.Equal(t, []int{10}, conflict.Blocking)
+		assert.EqualError(t, err, "netorca: change instance 12: cannot move to COMPLETED before older change "+
+			"instances on service item 5 are resolved: 10")
+		assert.Equal(t, "5", query.Get("service_item_id"))
+		assert.Equal(t, 2, httpmock.GetTotalCallCount(), "nothing is written")
+	})
+
+	t.Run("warns and lets the move through", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		register()
+		httpmock.RegisterResponder("PATCH", detailURL, httpmock.NewStringResponder(200, `{"id":12,"state":"COMPLETED"}`))
+
+		var warned []int
+		nc := newPackTestClient(t)
+		_, err := nc.CompleteChangeInstanceWithContext(context.Background(), client.POVServiceOwner, 12, "done", nil,
+			client.WithGate(nc.ConflictGate(&client.ConflictGateOptions{
+				Warn: func(_ context.Context, conflict *client.ChangeConflictError) { warned = conflict.Blocking },
+			})))
+
+		require.NoError(t, err)
+		assert.Equal(t, []int{10}, warned)
+	})
+
+	t.Run("other moves are not checked", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		httpmock.RegisterResponder("PATCH", detailURL, httpmock.NewStringResponder(200, `{"id":12,"state":"REJECTED"}`))
+
+		nc := newPackTestClient(t)
+		_, err := nc.RejectChangeInstanceWithContext(context.Background(), client.POVServiceOwner, 12, "no", nil,
+			client.WithGate(nc.ConflictGate(nil)))
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+}
@@ -0,0 +1,579 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ChangeSource records which listing a change instance in a dependency graph came from, which
+// decides the relationships it takes part in.
+type ChangeSource string
+
+const (
+	// ChangeSourceOwn is the plain listing: changes your team owns.
+	ChangeSourceOwn ChangeSource = "own"
+	// ChangeSourceDependant is GetDependantChangeInstances: copies handed to dependant teams.
+	ChangeSourceDependant ChangeSource = "dependant"
+	// ChangeSourceReferenced is GetReferencedChangeInstances: changes raised on another item
+	// because it names one of yours as related.
+	ChangeSourceReferenced ChangeSource = "referenced"
+)
+
+// changeSourceRank orders the sources by how much they say about a change. The plain listing
+// includes referenced changes unless ExcludeReferenced is set, so a change seen on both routes
+// is kept as referenced.
+var changeSourceRank = map[ChangeSource]int{
+	ChangeSourceOwn:        0,
+	ChangeSourceDependant:  1,
+	ChangeSourceReferenced: 2,
+}
+
+// DependencyReason says why one node of a dependency graph comes before another.
+type DependencyReason string
+
+const (
+	// DependencyDependant orders a dependant team's copy after the change it was copied from:
+	// the non-dependant change on the same service item in the same submission.
+	DependencyDependant DependencyReason = "dependant"
+	// DependencyReferenced orders a referenced change after the changes to the items its
+	// service item names as related - or, when the item's related list cannot be read, after
+	// the other changes of its submission.
+	DependencyReferenced DependencyReason = "referenced"
+	// DependencyRelated orders a change after the changes to the items its service item names
+	// as related, and a service item after the items it relates to.
+	DependencyRelated DependencyReason = "related"
+	// DependencyExplicit is an edge added with AddDependency.
+	DependencyExplicit DependencyReason = "explicit"
+)
+
+// GraphNodeKind distinguishes the two kinds of node in a dependency graph.
+type GraphNodeKind string
+
+const (
+	// GraphChangeInstance is a change instance node.
+	GraphChangeInstance GraphNodeKind = "change_instance"
+	// GraphServiceItem is a service item node.
+	GraphServiceItem GraphNodeKind = "service_item"
+)
+
+// GraphNode identifies a node of a dependency graph.
+type GraphNode struct {
+	// Kind says whether ID is a change instance or a service item.
+	Kind GraphNodeKind
+	// ID is the change instance or service item id.
+	ID int
+}
+
+// GraphEdge says that From comes before To. Edges join two change instances or two service
+// items; the link between a change and the item it acts on is implied by the change itself.
+type GraphEdge struct {
+	// From must be processed before To.
+	From GraphNode
+	// To depends on From.
+	To GraphNode
+	// Reason says which relationship the edge came from.
+	Reason DependencyReason
+}
+
+// graphChange is a change instance node with the listing it came from, and its service item's
+// related list, read once when the change is added rather than for every pair compared.
+type graphChange struct {
+	change  ChangeInstance
+	source  ChangeSource
+	related relatedItems
+}
+
+// DependencyGraph is a directed graph of change instances and the service items they act on,
+// built from the relationships the API exposes: dependant copies, referenced changes and
+// related service items. Layers and Order turn it into a processing order; DOT and Mermaid
+// render it for review.
+//
+// Build one with Client.BuildDependencyGraph, or assemble it from listings you already have
+// with NewDependencyGraph and AddChangeInstances. A graph is not safe for concurrent use.
+type DependencyGraph struct {
+	changes map[int]*graphChange
+	items   map[int]ServiceItem
+	// related holds each item's related list, read when the item is added.
+	related  map[int]relatedItems
+	explicit []GraphEdge
+}
+
+// NewDependencyGraph returns an empty graph.
+func NewDependencyGraph() *DependencyGraph {
+	return &DependencyGraph{
+		changes: map[int]*graphChange{},
+		items:   map[int]ServiceItem{},
+		related: map[int]relatedItems{},
+	}
+}
+
+// AddChangeInstances adds changes from one listing, along with the service items they act on.
+// A change already in the graph is replaced by the newer copy; it keeps whichever source says
+// more about it, referenced over dependant over own.
+func (g *DependencyGraph) AddChangeInstances(source ChangeSource, changes ...ChangeInstance) {
+	for _, change := range changes {
+		node := &graphChange{change: change, source: source, related: parseRelated(change.ServiceItem.Related)}
+		if existing, ok := g.changes[change.ID]; ok && changeSourceRank[existing.source] > changeSourceRank[source] {
+			node.source = existing.source
+		}
+		g.changes[change.ID] = node
+
+		if item := change.ServiceItem; item.ID != 0 {
+			g.items[item.ID] = item
+			g.related[item.ID] = node.related
+		}
+	}
+}
+
+// AddDependency records that change instance before must be processed ahead of change instance
+// after, for relationships only the caller knows about. Both must already be in the graph.
+func (g *DependencyGraph) AddDependency(before, after int) error {
+	for _, id := range []int{before, after} {
+		if _, ok := g.changes[id]; !ok {
+			return fmt.Errorf("netorca: change instance %d is not in the dependency graph", id)
+		}
+	}
+	g.explicit = append(g.explicit, GraphEdge{
+		From:   GraphNode{Kind: GraphChangeInstance, ID: before},
+		To:     GraphNode{Kind: GraphChangeInstance, ID: after},
+		Reason: DependencyExplicit,
+	})
+	return nil
+}
+
+// ChangeInstances returns the graph's change instances, by id.
+func (g *DependencyGraph) ChangeInstances() []ChangeInstance {
+	changes := make([]ChangeInstance, 0, len(g.changes))
+	for _, id := range g.changeIDs() {
+		changes = append(changes, g.changes[id].change)
+	}
+	return changes
+}
+
+// ServiceItems returns the service items the graph's changes act on, by id.
+func (g *DependencyGraph) ServiceItems() []ServiceItem {
+	items := make([]ServiceItem, 0, len(g.items))
+	for _, id := range g.itemIDs() {
+		items = append(items, g.items[id])
+	}
+	return items
+}
+
+// Edges returns every edge of the graph, derived from the changes currently in it plus those
+// added with AddDependency. A pair related in more than one way appears once per reason.
+func (g *DependencyGraph) Edges() []GraphEdge {
+	seen := map[GraphEdge]bool{}
+	var edges []GraphEdge
+	add := func(edge GraphEdge) {
+		if edge.From != edge.To && !seen[edge] {
+			seen[edge] = true
+			edges = append(edges, edge)
+		}
+	}
+
+	changeIDs := g.changeIDs()
+	for _, id := range changeIDs {
+		after := g.changes[id]
+		for _, otherID := range changeIDs {
+			if reason, ok := dependsOn(after, g.changes[otherID]); ok {
+				add(GraphEdge{
+					From:   GraphNode{Kind: GraphChangeInstance, ID: otherID},
+					To:     GraphNode{Kind: GraphChangeInstance, ID: id},
+					Reason: reason,
+				})
+			}
+		}
+	}
+
+	itemIDs := g.itemIDs()
+	for _, id := range itemIDs {
+		related := g.related[id]
+		for _, otherID := range itemIDs {
+			if related.matches(g.items[otherID]) {
+				add(GraphEdge{
+					From:   GraphNode{Kind: GraphServiceItem, ID: otherID},
+					To:     GraphNode{Kind: GraphServiceItem, ID: id},
+					Reason: DependencyRelated,
+				})
+			}
+		}
+	}
+
+	for _, edge := range g.explicit {
+		add(edge)
+	}
+	return edges
+}
+
+// dependsOn reports whether after must wait for before, and why.
+func dependsOn(after, before *graphChange) (DependencyReason, bool) {
+	a, b := after.change, before.change
+	if a.ID == b.ID {
+		return "", false
+	}
+
+	if a.IsDependant && !b.IsDependant && a.ServiceItem.ID != 0 &&
+		a.ServiceItem.ID == b.ServiceItem.ID && a.Submission.ID == b.Submission.ID {
+		return DependencyDependant, true
+	}
+
+	related := after.related
+	if after.source == ChangeSourceReferenced {
+		if before.source == ChangeSourceReferenced {
+			return "", false
+		}
+		if related.empty() {
+			// Without a readable related list, the submission that raised the referenced
+			// change is the best evidence of what it is waiting for.
+			if a.Submission.ID != 0 && a.Submission.ID == b.Submission.ID && a.ServiceItem.ID != b.ServiceItem.ID {
+				return DependencyReferenced, true
+			}
+			return "", false
+		}
+		if related.matches(b.ServiceItem) {
+			return DependencyReferenced, true
+		}
+		return "", false
+	}
+
+	if related.matches(b.ServiceItem) {
+		return DependencyRelated, true
+	}
+	return "", false
+}
+
+// Layers returns the change instances in processing order, grouped into layers: every change
+// in a layer depends only on changes in earlier layers, so a layer's changes can be processed
+// in parallel once the layer before it is done. Each layer is ordered by id.
+//
+// When the changes depend on each other in a loop there is no such order, and the error is a
+// *DependencyCycleError naming the loop. Only edges between change instances count: the edges
+// between service items are left out, since a loop among items holds nothing up by itself.
+// Where changes sit on the items of such a loop, their own related edges form the same loop
+// between the changes, and that is reported.
+func (g *DependencyGraph) Layers() ([][]ChangeInstance, error) {
+	successors := map[int][]int{}
+	predecessors := map[int][]int{}
+	indegree := map[int]int{}
+	for _, edge := range g.Edges() {
+		if edge.From.Kind != GraphChangeInstance {
+			continue
+		}
+		successors[edge.From.ID] = append(successors[edge.From.ID], edge.To.ID)
+		predecessors[edge.To.ID] = append(predecessors[edge.To.ID], edge.From.ID)
+		indegree[edge.To.ID]++
+	}
+
+	var layers [][]ChangeInstance
+	remaining := map[int]bool{}
+	var ready []int
+	for _, id := range g.changeIDs() {
+		remaining[id] = true
+		if indegree[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		layer := make([]ChangeInstance, 0, len(ready))
+		var next []int
+		for _, id := range ready {
+			layer = append(layer, g.changes[id].change)
+			delete(remaining, id)
+			for _, successor := range successors[id] {
+				indegree[successor]--
+				if indegree[successor] == 0 {
+					next = append(next, successor)
+				}
+			}
+		}
+		layers = append(layers, layer)
+		ready = next
+	}
+
+	if len(remaining) > 0 {
+		return nil, &DependencyCycleError{Cycle: findCycle(remaining, predecessors)}
+	}
+	return layers, nil
+}
+
+// Order returns the change instances in a single processing order: Layers, flattened.
+func (g *DependencyGraph) Order() ([]ChangeInstance, error) {
+	layers, err := g.Layers()
+	if err != nil {
+		return nil, err
+	}
+	order := make([]ChangeInstance, 0, len(g.changes))
+	for _, layer := range layers {
+		order = append(order, layer...)
+	}
+	return order, nil
+}
+
+// findCycle walks backwards from the lowest remaining change. Every change Layers could not
+// place waits on another it could not place, so the walk must come back on itself; the loop it
+// closes is returned in processing order, with its first change repeated at the end.
+func findCycle(remaining map[int]bool, predecessors map[int][]int) []int {
+	ids := make([]int, 0, len(remaining))
+	for id := range remaining {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	visited := map[int]int{}
+	var path []int
+	current := ids[0]
+	for {
+		if at, ok := visited[current]; ok {
+			path = path[at:]
+			break
+		}
+		visited[current] = len(path)
+		path = append(path, current)
+
+		next := -1
+		for _, predecessor := range predecessors[current] {
+			if remaining[predecessor] && (next == -1 || predecessor < next) {
+				next = predecessor
+			}
+		}
+		current = next
+	}
+
+	cycle := make([]int, 0, len(path)+1)
+	for i := len(path) - 1; i >= 0; i-- {
+		cycle = append(cycle, path[i])
+	}
+	return append(cycle, cycle[0])
+}
+
+// DOT renders the graph in Graphviz's DOT language: service items as folders, change instances
+// as boxes, a dashed line from each item to its changes and a labelled arrow for each edge.
+//
+//	dot -Tsvg graph.dot > graph.svg
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph netorca {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, item := range g.ServiceItems() {
+		fmt.Fprintf(&b, "\t%s [label=%s, shape=folder];\n", graphNodeID(GraphServiceItem, item.ID),
+			dotQuote(itemLabel(item)))
+	}
+	for _, change := range g.ChangeInstances() {
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", graphNodeID(GraphChangeInstance, change.ID),
+			dotQuote(changeLabel(change, "\n")))
+		if change.ServiceItem.ID != 0 {
+			fmt.Fprintf(&b, "\t%s -> %s [style=dashed, arrowhead=none];\n",
+				graphNodeID(GraphServiceItem, change.ServiceItem.ID), graphNodeID(GraphChangeInstance, change.ID))
+		}
+	}
+	for _, edge := range g.Edges() {
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", graphNodeID(edge.From.Kind, edge.From.ID),
+			graphNodeID(edge.To.Kind, edge.To.ID), dotQuote(string(edge.Reason)))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, which GitHub and most wikis draw inline
+// from a ```mermaid block. The layout matches DOT.
+func (g *DependencyGraph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, item := range g.ServiceItems() {
+		fmt.Fprintf(&b, "    %s[[%s]]\n", graphNodeID(GraphServiceItem, item.ID), mermaidQuote(itemLabel(item)))
+	}
+	for _, change := range g.ChangeInstances() {
+		fmt.Fprintf(&b, "    %s[%s]\n", graphNodeID(GraphChangeInstance, change.ID),
+			mermaidQuote(changeLabel(change, "<br/>")))
+		if change.ServiceItem.ID != 0 {
+			fmt.Fprintf(&b, "    %s -.- %s\n",
+				graphNodeID(GraphServiceItem, change.ServiceItem.ID), graphNodeID(GraphChangeInstance, change.ID))
+		}
+	}
+	for _, edge := range g.Edges() {
+		fmt.Fprintf(&b, "    %s -->|%s| %s\n", graphNodeID(edge.From.Kind, edge.From.ID), edge.Reason,
+			graphNodeID(edge.To.Kind, edge.To.ID))
+	}
+	return b.String()
+}
+
+// changeLabel describes a change instance node on two lines.
+func changeLabel(change ChangeInstance, newline string) string {
+	label := fmt.Sprintf("change %d%s%s %s", change.ID, newline, change.ChangeType, change.State)
+	if change.IsDependant {
+		label += " (dependant)"
+	}
+	return label
+}
+
+// itemLabel describes a service item node.
+func itemLabel(item ServiceItem) string {
+	if item.Name == "" {
+		return fmt.Sprintf("item %d", item.ID)
+	}
+	return fmt.Sprintf("%s (item %d)", item.Name, item.ID)
+}
+
+// dotQuote quotes a DOT string, keeping newlines as the \n escape DOT centres lines on.
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// mermaidQuote quotes a Mermaid label. Mermaid has no backslash escapes; a double quote has to
+// be written as an entity.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// graphNodeID names a node in DOT and Mermaid output; both accept the same identifiers.
+func graphNodeID(kind GraphNodeKind, id int) string {
+	if kind == GraphServiceItem {
+		return "item_" + strconv.Itoa(id)
+	}
+	return "change_" + strconv.Itoa(id)
+}
+
+func (g *DependencyGraph) changeIDs() []int {
+	ids := make([]int, 0, len(g.changes))
+	for id := range g.changes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (g *DependencyGraph) itemIDs() []int {
+	ids := make([]int, 0, len(g.items))
+	for id := range g.items {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// relatedItems is a service item's related list, read into the ids and names it names.
+type relatedItems struct {
+	ids   map[int]bool
+	names map[string]bool
+}
+
+func (r relatedItems) empty() bool {
+	return len(r.ids) == 0 && len(r.names) == 0
+}
+
+func (r relatedItems) matches(item ServiceItem) bool {
+	return (item.ID != 0 && r.ids[item.ID]) || (item.Name != "" && r.names[item.Name])
+}
+
+// parseRelated reads a service item's related field. The API documents it only as a string, so
+// this accepts the shapes it has been seen in: a JSON list of ids, names or {"id", "name"}
+// objects, a single such value, or a plain comma-separated list.
+func parseRelated(raw *string) relatedItems {
+	related := relatedItems{ids: map[int]bool{}, names: map[string]bool{}}
+	if raw == nil || strings.TrimSpace(*raw) == "" {
+		return related
+	}
+
+	var decoded any
+	if err := json.Unmarshal([]byte(*raw), &decoded); err != nil {
+		for _, part := range strings.Split(*raw, ",") {
+			related.add(strings.TrimSpace(part))
+		}
+		return related
+	}
+
+	values, ok := decoded.([]any)
+	if !ok {
+		values = []any{decoded}
+	}
+	for _, value := range values {
+		related.add(value)
+	}
+	return related
+}
+
+// add records one entry of a related list.
+func (r relatedItems) add(value any) {
+	switch v := value.(type) {
+	case float64:
+		r.ids[int(v)] = true
+	case string:
+		if v == "" {
+			return
+		}
+		if id, err := strconv.Atoi(v); err == nil {
+			r.ids[id] = true
+		} else {
+			r.names[v] = true
+		}
+	case map[string]any:
+		if id, ok := v["id"]; ok {
+			r.add(id)
+		}
+		if name, ok := v["name"].(string); ok {
+			r.add(name)
+		}
+	}
+}
+
+// DependencyGraphOptions configures BuildDependencyGraph.
+type DependencyGraphOptions struct {
+	// Sources are the listings to assemble the graph from. Defaults to all three; leave out
+	// the ones your POV cannot reach.
+	Sources []ChangeSource
+}
+
+// Validate rejects a source that is not one of the three listings.
+func (o *DependencyGraphOptions) Validate() error {
+	for _, source := range o.Sources {
+		if _, ok := changeSourceRank[source]; !ok {
+			return fmt.Errorf("netorca: unknown change source %q", source)
+		}
+	}
+	return nil
+}
+
+// changeSourceActions maps each source to its list route.
+var changeSourceActions = map[ChangeSource]string{
+	ChangeSourceOwn:        "",
+	ChangeSourceDependant:  "dependant/",
+	ChangeSourceReferenced: "referenced/",
+}
+
+// BuildDependencyGraph lists the change instances matching the filters from the plain,
+// dependant and referenced routes, every page of each, and assembles them into a graph. Pass
+// the graph's Layers to a worker pool, or its DOT or Mermaid to a reviewer.
+//
+// Ordering is ignored by the dependant and referenced routes; the graph orders by id anyway.
+func (c *Client) BuildDependencyGraph(
+	ctx context.Context,
+	filters *GetChangeInstancesRequest,
+	opts *DependencyGraphOptions,
+) (*DependencyGraph, error) {
+	if opts == nil {
+		opts = &DependencyGraphOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	sources := opts.Sources
+	if len(sources) == 0 {
+		sources = []ChangeSource{ChangeSourceOwn, ChangeSourceDependant, ChangeSourceReferenced}
+	}
+
+	graph := NewDependencyGraph()
+	for _, source := range sources {
+		changes, err := c.listAllChangeInstances(ctx, filters, changeSourceActions[source])
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s change instances: %w", source, err)
+		}
+		graph.AddChangeInstances(source, changes...)
+	}
+	return graph, nil
+}
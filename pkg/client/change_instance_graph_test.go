@@ -0,0 +1,220 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// graphChange builds a change acting on the given item within submission 5.
+func graphChange(id, itemID int, itemName string, related *string) client.ChangeInstance {
+	return client.ChangeInstance{
+		ID:          id,
+		State:       client.ChangeInstancePENDING,
+		ChangeType:  client.ChangeTypeModify,
+		ServiceItem: client.ServiceItem{ID: itemID, Name: itemName, Related: related},
+		Submission:  client.Submission{ID: 5},
+	}
+}
+
+func layerIDs(t *testing.T, graph *client.DependencyGraph) [][]int {
+	t.Helper()
+	layers, err := graph.Layers()
+	require.NoError(t, err)
+
+	ids := make([][]int, 0, len(layers))
+	for _, layer := range layers {
+		var layerIDs []int
+		for _, change := range layer {
+			layerIDs = append(layerIDs, change.ID)
+		}
+		ids = append(ids, layerIDs)
+	}
+	return ids
+}
+
+func TestDependencyGraphLayers(t *testing.T) {
+	related := `[1]`
+	dependant := graphChange(11, 1, "web-vm", nil)
+	dependant.IsDependant = true
+
+	graph := client.NewDependencyGraph()
+	graph.AddChangeInstances(client.ChangeSourceOwn,
+		graphChange(10, 1, "web-vm", nil),
+		graphChange(30, 3, "dns", nil),
+	)
+	graph.AddChangeInstances(client.ChangeSourceDependant, dependant)
+	graph.AddChangeInstances(client.ChangeSourceReferenced, graphChange(20, 2, "web-lb", &related))
+
+	// The load balancer waits for both halves of the virtual machine's change: the owner's and
+	// the dependant team's copy.
+	assert.Equal(t, [][]int{{10, 30}, {11}, {20}}, layerIDs(t, graph))
+
+	order, err := graph.Order()
+	require.NoError(t, err)
+	require.Len(t, order, 4)
+	assert.Equal(t, 10, order[0].ID)
+
+	assert.Equal(t, []client.GraphEdge{
+		{
+			From:   client.GraphNode{Kind: client.GraphChangeInstance, ID: 10},
+			To:     client.GraphNode{Kind: client.GraphChangeInstance, ID: 11},
+			Reason: client.DependencyDependant,
+		},
+		{
+			From:   client.GraphNode{Kind: client.GraphChangeInstance, ID: 10},
+			To:     client.GraphNode{Kind: client.GraphChangeInstance, ID: 20},
+			Reason: client.DependencyReferenced,
+		},
+		{
+			From:   client.GraphNode{Kind: client.GraphChangeInstance, ID: 11},
+			To:     client.GraphNode{Kind: client.GraphChangeInstance, ID: 20},
+			Reason: client.DependencyReferenced,
+		},
+		{
+			From:   client.GraphNode{Kind: client.GraphServiceItem, ID: 1},
+			To:     client.GraphNode{Kind: client.GraphServiceItem, ID: 2},
+			Reason: client.DependencyRelated,
+		},
+	}, graph.Edges())
+}
+
+func TestDependencyGraphRelatedShapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		related string
+	}{
+		{name: "id list", related: `[1]`},
+		{name: "name list", related: `["web-vm"]`},
+		{name: "objects", related: `[{"id": 1, "name": "web-vm"}]`},
+		{name: "single id", related: `1`},
+		{name: "comma separated", related: `web-vm, dns`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := client.NewDependencyGraph()
+			graph.AddChangeInstances(client.ChangeSourceOwn,
+				graphChange(10, 1, "web-vm", nil),
+				graphChange(20, 2, "web-lb", &tt.related),
+			)
+			assert.Equal(t, [][]int{{10}, {20}}, layerIDs(t, graph))
+		})
+	}
+}
+
+func TestDependencyGraphReferencedFallsBackToSubmission(t *testing.T) {
+	other := graphChange(40, 4, "elsewhere", nil)
+	other.Submission.ID = 6
+
+	graph := client.NewDependencyGraph()
+	graph.AddChangeInstances(client.ChangeSourceOwn, graphChange(10, 1, "web-vm", nil), other)
+	graph.AddChangeInstances(client.ChangeSourceReferenced, graphChange(20, 2, "web-lb", nil))
+
+	assert.Equal(t, [][]int{{10, 40}, {20}}, layerIDs(t, graph))
+}
+
+func TestDependencyGraphCycle(t *testing.T) {
+	graph := client.NewDependencyGraph()
+	graph.AddChangeInstances(client.ChangeSourceOwn,
+		graphChange(1, 1, "a", nil),
+		graphChange(2, 2, "b", nil),
+		graphChange(3, 3, "c", nil),
+		graphChange(4, 4, "d", nil),
+	)
+	require.NoError(t, graph.AddDependency(1, 2))
+	require.NoError(t, graph.AddDependency(2, 3))
+	require.NoError(t, graph.AddDependency(3, 2))
+	require.Error(t, graph.AddDependency(1, 99))
+
+	_, err := graph.Layers()
+	require.ErrorIs(t, err, client.ErrDependencyCycle)
+
+	var cycleErr *client.DependencyCycleError
+	require.ErrorAs(t, err, &cycleErr)
+	assert.Equal(t, []int{3, 2, 3}, cycleErr.Cycle)
+	assert.EqualError(t, err, "netorca: change instances depend on each other in a cycle: 3 -> 2 -> 3")
+
+	_, err = graph.Order()
+	assert.ErrorIs(t, err, client.ErrDependencyCycle)
+}
+
+func TestDependencyGraphRelatedCycle(t *testing.T) {
+	relatesTo := func(name string) *string { return &name }
+
+	graph := client.NewDependencyGraph()
+	graph.AddChangeInstances(client.ChangeSourceOwn,
+		graphChange(1, 1, "a", relatesTo("b")),
+		graphChange(2, 2, "b", relatesTo("a")),
+	)
+
+	_, err := graph.Layers()
+	var cycleErr *client.DependencyCycleError
+	require.ErrorAs(t, err, &cycleErr, "a loop among service items reaches the changes on them")
+	assert.Equal(t, []int{2, 1, 2}, cycleErr.Cycle)
+}
+
+func TestDependencyGraphRender(t *testing.T) {
+	related := `["web-vm"]`
+	graph := client.NewDependencyGraph()
+	graph.AddChangeInstances(client.ChangeSourceOwn,
+		graphChange(10, 1, "web-vm", nil),
+		graphChange(20, 2, `lb "blue"`, &related),
+	)
+
+	assert.Equal(t, `digraph netorca {
+	rankdir=LR;
+	node [shape=box];
+	item_1 [label="web-vm (item 1)", shape=folder];
+	item_2 [label="lb \"blue\" (item 2)", shape=folder];
+	change_10 [label="change 10\nMODIFY PENDING"];
+	item_1 -> change_10 [style=dashed, arrowhead=none];
+	change_20 [label="change 20\nMODIFY PENDING"];
+	item_2 -> change_20 [style=dashed, arrowhead=none];
+	change_10 -> change_20 [label="related"];
+	item_1 -> item_2 [label="related"];
+}
+`, graph.DOT())
+
+	assert.Equal(t, `flowchart LR
+    item_1[["web-vm (item 1)"]]
+    item_2[["lb #quot;blue#quot; (item 2)"]]
+    change_10["change 10<br/>MODIFY PENDING"]
+    item_1 -.- change_10
+    change_20["change 20<br/>MODIFY PENDING"]
+    item_2 -.- change_20
+    change_10 -->|related| change_20
+    item_1 -->|related| item_2
+`, graph.Mermaid())
+}
+
+func TestBuildDependencyGraph(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// The plain listing includes the referenced change too; the graph must still treat it
+	// as referenced, or it would lose its place after the virtual machine's change.
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", httpmock.NewStringResponder(200,
+		`{"count":2,"next":null,"previous":null,"results":[
+			{"id":10,"state":"PENDING","service_item":{"id":1,"name":"web-vm"},"submission":{"id":5}},
+			{"id":20,"state":"PENDING","service_item":{"id":2,"name":"web-lb"},"submission":{"id":5}}]}`))
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/dependant/", httpmock.NewStringResponder(200,
+		`{"count":0,"next":null,"previous":null,"results":[]}`))
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/referenced/", httpmock.NewStringResponder(200,
+		`{"count":1,"next":null,"previous":null,"results":[
+			{"id":20,"state":"PENDING","service_item":{"id":2,"name":"web-lb"},"submission":{"id":5}}]}`))
+
+	nc := newPackTestClient(t)
+	graph, err := nc.BuildDependencyGraph(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, httpmock.GetTotalCallCount())
+	assert.Equal(t, [][]int{{10}, {20}}, layerIDs(t, graph))
+
+	_, err = nc.BuildDependencyGraph(context.Background(), nil, &client.DependencyGraphOptions{
+		Sources: []client.ChangeSource{"everything"},
+	})
+	require.EqualError(t, err, `netorca: unknown change source "everything"`)
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// LogLevel grades a ChangeLog entry.
+type LogLevel string
+
+const (
+	LogDebug   LogLevel = "DEBUG"
+	LogInfo    LogLevel = "INFO"
+	LogWarning LogLevel = "WARNING"
+	LogError   LogLevel = "ERROR"
+)
+
+// changeLogTruncated marks the start of a log whose oldest lines were dropped to fit the limit.
+const changeLogTruncated = "[earlier entries truncated]\n"
+
+// ChangeLog builds up log entries over the steps of a piece of automation and appends them to
+// whatever the change instance's log already says, so a later transition no longer overwrites
+// an earlier one's explanation. Pass it to any state helper with AppendLog:
+//
+//	steps := client.NewChangeLog()
+//	steps.Info("allocated {{.Declaration.cpu}} CPUs for {{.Declaration.name}}")
+//	steps.Warn("no backup policy given, using the default")
+//	_, err := nc.CompleteChangeInstanceWithContext(ctx, pov, id, "built", item, client.AppendLog(steps))
+//
+// Each entry is one line - "2026-10-18T21:04:05Z INFO allocated 4 CPUs for web-1" - stamped
+// when it was added rather than when it was sent. Messages are text/template templates executed
+// against the change's ChangeLogData, so they can quote declaration values; a message with no
+// actions is written as it stands. A log that outgrows Limit, when one is set, loses its oldest
+// lines first.
+//
+// A ChangeLog is not safe for concurrent use.
+type ChangeLog struct {
+	// Limit caps the length of the whole log, old and new entries together, in characters. Zero
+	// leaves the log whole: the API documents no limit for the field, so a cap is the caller's
+	// to choose.
+	Limit int
+	// Now stamps entries as they are added. Defaults to time.Now.
+	Now func() time.Time
+
+	entries []changeLogEntry
+	errs    []error
+}
+
+// changeLogEntry is one added entry, its message parsed once it is known to be a template.
+type changeLogEntry struct {
+	at       time.Time
+	level    LogLevel
+	message  string
+	template *template.Template
+}
+
+// ChangeLogData is what ChangeLog message templates are executed against.
+type ChangeLogData struct {
+	// ID is the change instance's id.
+	ID int
+	// State is the state the change is in before the transition.
+	State ChangeInstanceState
+	// ChangeType is CREATE, MODIFY or DELETE.
+	ChangeType ChangeType
+	// Service is the service's name.
+	Service string
+	// ConsumerTeam is the consuming team's name.
+	ConsumerTeam string
+	// Declaration is the change's new declaration, decoded: {{.Declaration.vip.port}}.
+	Declaration any
+	// OldDeclaration is the declaration being replaced, nil for a CREATE.
+	OldDeclaration any
+}
+
+// NewChangeLog returns an empty ChangeLog.
+func NewChangeLog() *ChangeLog {
+	return &ChangeLog{}
+}
+
+// Debug adds a DEBUG entry.
+func (l *ChangeLog) Debug(message string) *ChangeLog {
+	return l.Add(LogDebug, message)
+}
+
+// Info adds an INFO entry.
+func (l *ChangeLog) Info(message string) *ChangeLog {
+	return l.Add(LogInfo, message)
+}
+
+// Warn adds a WARNING entry.
+func (l *ChangeLog) Warn(message string) *ChangeLog {
+	return l.Add(LogWarning, message)
+}
+
+// Error adds an ERROR entry.
+func (l *ChangeLog) Error(message string) *ChangeLog {
+	return l.Add(LogError, message)
+}
+
+// Add adds an entry at the given level. A message that does not parse as a template is kept
+// back and reported by Err and Render, so a chain of calls needs checking only once.
+func (l *ChangeLog) Add(level LogLevel, message string) *ChangeLog {
+	entry := changeLogEntry{at: l.now(), level: level, message: message}
+	if strings.Contains(message, "{{") {
+		tmpl, err := template.New("").Option("missingkey=error").Parse(message)
+		if err != nil {
+			l.errs = append(l.errs, fmt.Errorf("change log entry %d: %w", len(l.entries)+len(l.errs)+1, err))
+			return l
+		}
+		entry.template = tmpl
+	}
+	l.entries = append(l.entries, entry)
+	return l
+}
+
+// Len returns the number of entries added.
+func (l *ChangeLog) Len() int {
+	return len(l.entries)
+}
+
+// Err returns the problems met while adding entries, or nil.
+func (l *ChangeLog) Err() error {
+	return errors.Join(l.errs...)
+}
+
+// Render returns the change's log with the entries appended, trimmed to Limit if one is set.
+// AppendLog calls it with the change as it stands just before the transition; it is exported for
+// callers who write the log some other way.
+func (l *ChangeLog) Render(ci *ChangeInstance) (string, error) {
+	if err := l.Err(); err != nil {
+		return "", err
+	}
+
+	data, err := changeLogDataOf(ci)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, 0, len(l.entries)+1)
+	if existing := strings.TrimRight(ci.Log, "\n"); existing != "" {
+		lines = append(lines, existing)
+	}
+	for i, entry := range l.entries {
+		message := entry.message
+		if entry.template != nil {
+			var b strings.Builder
+			if err := entry.template.Execute(&b, data); err != nil {
+				return "", fmt.Errorf("change log entry %d: %w", i+1, err)
+			}
+			message = b.String()
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s", entry.at.UTC().Format(time.RFC3339), entry.level, message))
+	}
+	log := strings.Join(lines, "\n")
+	if l.Limit > 0 {
+		log = truncateChangeLog(log, l.Limit)
+	}
+	return log, nil
+}
+
+func (l *ChangeLog) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// changeLogDataOf decodes the change's declarations for the templates.
+func changeLogDataOf(ci *ChangeInstance) (*ChangeLogData, error) {
+	data := &ChangeLogData{
+		ID:           ci.ID,
+		State:        ci.State,
+		ChangeType:   ci.ChangeType,
+		Service:      ci.Service.Name,
+		ConsumerTeam: ci.ConsumerTeam.Name,
+	}
+	if len(ci.NewDeclaration.Declaration) > 0 {
+		if err := json.Unmarshal(ci.NewDeclaration.Declaration, &data.Declaration); err != nil {
+			return nil, fmt.Errorf("failed to decode the declaration of change instance %d: %w", ci.ID, err)
+		}
+	}
+	if ci.OldDeclaration != nil && len(ci.OldDeclaration.Declaration) > 0 {
+		if err := json.Unmarshal(ci.OldDeclaration.Declaration, &data.OldDeclaration); err != nil {
+			return nil, fmt.Errorf("failed to decode the old declaration of change instance %d: %w", ci.ID, err)
+		}
+	}
+	return data, nil
+}
+
+// truncateChangeLog keeps the newest part of a log within limit characters. It cuts at a line
+// boundary when one is in reach, and never inside a multi-byte character.
+func truncateChangeLog(log string, limit int) string {
+	runes := []rune(log)
+	if len(runes) <= limit {
+		return log
+	}
+	marker := []rune(changeLogTruncated)
+	if limit <= len(marker) {
+		return string(runes[len(runes)-limit:])
+	}
+	kept := string(runes[len(runes)-(limit-len(marker)):])
+	if newline := strings.IndexByte(kept, '\n'); newline >= 0 && newline < len(kept)-1 {
+		kept = kept[newline+1:]
+	}
+	return changeLogTruncated + kept
+}
@@ -0,0 +1,157 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedChangeLog returns a ChangeLog whose clock stands still at 21:04:05 UTC.
+func fixedChangeLog() *client.ChangeLog {
+	return &client.ChangeLog{Now: func() time.Time { return time.Date(2026, 10, 18, 21, 4, 5, 0, time.UTC) }}
+}
+
+func TestChangeLogRender(t *testing.T) {
+	ci := &client.ChangeInstance{
+		ID:             53,
+		Log:            "approved by the pipeline\n",
+		Service:        client.ChangeInstanceService{Name: "vm"},
+		NewDeclaration: client.Declaration{Declaration: json.RawMessage(`{"name":"web-1","cpu":4,"vip":{"port":443}}`)},
+		OldDeclaration: &client.Declaration{Declaration: json.RawMessage(`{"cpu":2}`)},
+	}
+
+	log := fixedChangeLog().
+		Info("resized {{.Declaration.name}} from {{.OldDeclaration.cpu}} to {{.Declaration.cpu}} CPUs").
+		Warn("port {{.Declaration.vip.port}} on {{.Service}} change {{.ID}}").
+		Debug("plain text, {not a template}")
+	require.NoError(t, log.Err())
+	assert.Equal(t, 3, log.Len())
+
+	got, err := log.Render(ci)
+	require.NoError(t, err)
+	assert.Equal(t, "approved by the pipeline\n"+
+		"2026-10-18T21:04:05Z INFO resized web-1 from 2 to 4 CPUs\n"+
+		"2026-10-18T21:04:05Z WARNING port 443 on vm change 53\n"+
+		"2026-10-18T21:04:05Z DEBUG plain text, {not a template}", got)
+
+	got, err = fixedChangeLog().Error("failed").Render(&client.ChangeInstance{})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10-18T21:04:05Z ERROR failed", got, "an empty log gains no blank line")
+}
+
+func TestChangeLogErrors(t *testing.T) {
+	log := fixedChangeLog().Info("fine").Info("broken {{.Declaration.name")
+	require.ErrorContains(t, log.Err(), "change log entry 2:")
+	_, err := log.Render(&client.ChangeInstance{})
+	require.ErrorContains(t, err, "change log entry 2:")
+
+	log = fixedChangeLog().Info("{{.Declaration.missing}}")
+	_, err = log.Render(&client.ChangeInstance{
+		NewDeclaration: client.Declaration{Declaration: json.RawMessage(`{"name":"web-1"}`)},
+	})
+	assert.ErrorContains(t, err, `change log entry 1:`)
+	assert.ErrorContains(t, err, `map has no entry for key "missing"`)
+}
+
+func TestChangeLogTruncation(t *testing.T) {
+	old := strings.Repeat("older line ü\n", 10)
+	log := fixedChangeLog()
+	log.Limit = 80
+	got, err := log.Info("newest").Render(&client.ChangeInstance{Log: old})
+	require.NoError(t, err)
+
+	assert.LessOrEqual(t, len([]rune(got)), 80)
+	assert.Equal(t, "[earlier entries truncated]\nolder line ü\n2026-10-18T21:04:05Z INFO newest", got,
+		"whole lines are dropped from the front")
+
+	log = fixedChangeLog()
+	long := strings.Repeat("older line ü\n", 2000)
+	got, err = log.Info("newest").Render(&client.ChangeInstance{Log: long})
+	require.NoError(t, err)
+	assert.Equal(t, long+"2026-10-18T21:04:05Z INFO newest", got, "without a Limit the log is kept whole")
+}
+
+func TestAppendLog(t *testing.T) {
+	const detailURL = packTestBaseURL + "/v1/orcabase/serviceowner/change_instances/53/"
+	const approved = `{"id":53,"state":"APPROVED","log":"approved",` +
+		`"service":{"name":"vm","allow_manual_completion":true},` +
+		`"new_declaration":{"declaration":{"name":"web-1"}}}`
+
+	t.Run("state helpers append", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var capturedBody string
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200, approved))
+		httpmock.RegisterResponder("PATCH", detailURL,
+			captureBodyResponder(&capturedBody, 200, `{"id":53,"state":"COMPLETED"}`))
+
+		nc := newPackTestClient(t)
+		_, err := nc.CompleteChangeInstanceWithContext(context.Background(), client.POVServiceOwner, 53, "done", nil,
+			client.Guarded(), client.AppendLog(fixedChangeLog().Info("built {{.Declaration.name}}")))
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"state":"COMPLETED","log":"approved\n`+
+			`2026-10-18T21:04:05Z INFO built web-1\n2026-10-18T21:04:05Z INFO done"}`, capturedBody)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount(), "Guarded and AppendLog share one read")
+	})
+
+	t.Run("LogChangeInstance appends without moving", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var capturedBody string
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200, approved))
+		httpmock.RegisterResponder("PATCH", detailURL, captureBodyResponder(&capturedBody, 200, approved))
+
+		nc := newPackTestClient(t)
+		_, err := nc.LogChangeInstance(context.Background(), client.POVServiceOwner, 53, "",
+			client.AppendLog(fixedChangeLog().Warn("waiting on the load balancer")))
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{"state":"APPROVED","log":"approved\n2026-10-18T21:04:05Z WARNING waiting on the load balancer"}`,
+			capturedBody)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("a broken log sends nothing", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200, approved))
+
+		nc := newPackTestClient(t)
+		_, err := nc.CompleteChangeInstanceWithContext(context.Background(), client.POVServiceOwner, 53, "done", nil,
+			client.AppendLog(fixedChangeLog().Info("{{.Declaration.size}}")))
+		require.Error(t, err)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("bulk transitions take a log each", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		httpmock.RegisterRegexpResponder("GET", changeInstanceDetail, httpmock.NewStringResponder(200, approved))
+		patches := capturePatches(t)
+
+		nc := newPackTestClient(t)
+		results, err := nc.BulkUpdateChangeInstanceState(context.Background(), client.POVServiceOwner,
+			[]client.BulkTransition{
+				{ID: 53, State: client.ChangeInstanceCLOSED, ChangeLog: fixedChangeLog().Info("closing")},
+				{ID: 54, State: client.ChangeInstanceCLOSED, Log: "closed"},
+			}, nil)
+		require.NoError(t, err)
+		assert.Empty(t, results.Failed())
+
+		assert.Equal(t, "approved\n2026-10-18T21:04:05Z INFO closing", patches()[53]["log"])
+		assert.Equal(t, "closed", patches()[54]["log"])
+		assert.Equal(t, 3, httpmock.GetTotalCallCount(), "only the change with a ChangeLog is read")
+	})
+}
@@ -0,0 +1,333 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// SLA metrics measured from a change instance's timeline.
+const (
+	// SLATimeToApprove is the time from creation until the change first reached APPROVED.
+	SLATimeToApprove = "time_to_approve"
+	// SLATimeToComplete is the time from creation until the change first reached COMPLETED.
+	SLATimeToComplete = "time_to_complete"
+)
+
+// SLATarget is the most a change may take to reach each milestone. A zero duration sets no
+// target for that milestone.
+type SLATarget struct {
+	TimeToApprove  time.Duration
+	TimeToComplete time.Duration
+}
+
+// SLAReportOptions configures BuildSLAReport. The zero value (or nil) reports the default
+// percentiles with no targets.
+type SLAReportOptions struct {
+	// Default is the target for services not listed in Services.
+	Default SLATarget
+	// Services overrides Default by service name.
+	Services map[string]SLATarget
+	// Percentiles are reported for each metric, as values between 0 and 100. Defaults to 50,
+	// 90, 95 and 99.
+	Percentiles []float64
+	// AsOf is when open changes are judged: one still short of a milestone whose target has
+	// already elapsed counts as a breach. Defaults to now.
+	AsOf time.Time
+}
+
+// Validate checks the targets are not negative and the percentiles are in range.
+func (o *SLAReportOptions) Validate() error {
+	targets := []SLATarget{o.Default}
+	for _, target := range o.Services {
+		targets = append(targets, target)
+	}
+	for _, target := range targets {
+		if target.TimeToApprove < 0 || target.TimeToComplete < 0 {
+			return fmt.Errorf("SLA targets cannot be negative")
+		}
+	}
+	for _, p := range o.Percentiles {
+		if p <= 0 || p > 100 {
+			return fmt.Errorf("percentile %v is outside (0, 100]", p)
+		}
+	}
+	return nil
+}
+
+// SLASample is one change instance for an SLA report: the change itself, for its service and
+// consumer team, and its timeline.
+type SLASample struct {
+	ChangeInstance ChangeInstance
+	Timeline       *ChangeInstanceTimeline
+}
+
+// DurationStats summarises a set of durations.
+type DurationStats struct {
+	// Count is how many durations were measured. The other fields are zero when it is.
+	Count int
+	Min   time.Duration
+	Max   time.Duration
+	Mean  time.Duration
+	// Percentiles maps each requested percentile to its nearest-rank value.
+	Percentiles map[float64]time.Duration
+}
+
+// SLAGroup aggregates the changes of one service, or of one service and consumer team.
+type SLAGroup struct {
+	// Service is the service name.
+	Service string
+	// ConsumerTeam is the consumer team's name, empty for a whole-service group.
+	ConsumerTeam string
+	// Changes is how many changes the group holds.
+	Changes int
+	// TimeToApprove and TimeToComplete cover the changes that reached each milestone.
+	TimeToApprove  DurationStats
+	TimeToComplete DurationStats
+	// Breaches is how many of the group's changes breached at least one target.
+	Breaches int
+}
+
+// SLABreach is one change that missed, or is already past, a target.
+type SLABreach struct {
+	ChangeInstanceID int
+	Service          string
+	ConsumerTeam     string
+	// Metric is SLATimeToApprove or SLATimeToComplete.
+	Metric string
+	Target time.Duration
+	// Actual is the time taken, or for an open breach the time elapsed so far.
+	Actual time.Duration
+	// Open is set for a change that has not reached the milestone yet.
+	Open bool
+}
+
+// SLAReport is the aggregate of many change instances' timelines.
+type SLAReport struct {
+	// Services holds one group per service, sorted by name.
+	Services []SLAGroup
+	// ServiceTeams holds one group per service and consumer team, sorted by service then team.
+	ServiceTeams []SLAGroup
+	// Breaches lists every target missed, ordered by change instance id then metric.
+	Breaches []SLABreach
+}
+
+// BuildSLAReport aggregates timelines per service and per service and consumer team, with
+// percentiles for the time to approve and to complete, and lists the changes that breached
+// their targets.
+//
+// A change counts against a target when it reached the milestone late, or when it has not
+// reached it yet but still could and the target has already elapsed. A rejected or closed
+// change that never got there is not a breach: it was decided, not left waiting.
+func BuildSLAReport(samples []SLASample, opts *SLAReportOptions) (*SLAReport, error) {
+	if opts == nil {
+		opts = &SLAReportOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	percentiles := opts.Percentiles
+	if len(percentiles) == 0 {
+		percentiles = []float64{50, 90, 95, 99}
+	}
+	asOf := opts.AsOf
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+
+	type groupKey struct{ service, team string }
+	type groupData struct {
+		changes, breaches int
+		approve, complete []time.Duration
+	}
+	groups := map[groupKey]*groupData{}
+	group := func(key groupKey) *groupData {
+		if groups[key] == nil {
+			groups[key] = &groupData{}
+		}
+		return groups[key]
+	}
+
+	report := &SLAReport{}
+	for _, sample := range samples {
+		if sample.Timeline == nil || len(sample.Timeline.Intervals) == 0 {
+			continue
+		}
+		service := sample.ChangeInstance.Service.Name
+		team := sample.ChangeInstance.ConsumerTeam.Name
+		target := opts.Default
+		if override, ok := opts.Services[service]; ok {
+			target = override
+		}
+
+		breached := false
+		measure := func(metric string, state ChangeInstanceState, limit time.Duration) (time.Duration, bool) {
+			took, reached := sample.Timeline.TimeTo(state)
+			elapsed := took
+			if !reached {
+				if !milestoneReachable(sample.Timeline.Current(), state) {
+					return 0, false
+				}
+				elapsed = asOf.Sub(sample.Timeline.Created())
+			}
+			if limit > 0 && elapsed > limit {
+				breached = true
+				report.Breaches = append(report.Breaches, SLABreach{
+					ChangeInstanceID: sample.ChangeInstance.ID,
+					Service:          service,
+					ConsumerTeam:     team,
+					Metric:           metric,
+					Target:           limit,
+					Actual:           elapsed,
+					Open:             !reached,
+				})
+			}
+			return took, reached
+		}
+		approve, approved := measure(SLATimeToApprove, ChangeInstanceAPPROVED, target.TimeToApprove)
+		complete, completed := measure(SLATimeToComplete, ChangeInstanceCOMPLETED, target.TimeToComplete)
+
+		// A change with no consumer team only counts towards its service's group.
+		keys := []groupKey{{service, ""}}
+		if team != "" {
+			keys = append(keys, groupKey{service, team})
+		}
+		for _, key := range keys {
+			data := group(key)
+			data.changes++
+			if breached {
+				data.breaches++
+			}
+			if approved {
+				data.approve = append(data.approve, approve)
+			}
+			if completed {
+				data.complete = append(data.complete, complete)
+			}
+		}
+	}
+
+	keys := make([]groupKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		return keys[i].team < keys[j].team
+	})
+	for _, key := range keys {
+		data := groups[key]
+		summary := SLAGroup{
+			Service:        key.service,
+			ConsumerTeam:   key.team,
+			Changes:        data.changes,
+			TimeToApprove:  summariseDurations(data.approve, percentiles),
+			TimeToComplete: summariseDurations(data.complete, percentiles),
+			Breaches:       data.breaches,
+		}
+		if key.team == "" {
+			report.Services = append(report.Services, summary)
+		} else {
+			report.ServiceTeams = append(report.ServiceTeams, summary)
+		}
+	}
+
+	sort.SliceStable(report.Breaches, func(i, j int) bool {
+		if report.Breaches[i].ChangeInstanceID != report.Breaches[j].ChangeInstanceID {
+			return report.Breaches[i].ChangeInstanceID < report.Breaches[j].ChangeInstanceID
+		}
+		return report.Breaches[i].Metric < report.Breaches[j].Metric
+	})
+	return report, nil
+}
+
+// milestoneReachable reports whether a change in the given state can still reach the milestone,
+// judged by the transition table.
+func milestoneReachable(current, milestone ChangeInstanceState) bool {
+	seen := map[ChangeInstanceState]bool{current: true}
+	queue := []ChangeInstanceState{current}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for _, next := range changeInstanceTransitions[state] {
+			if next == milestone {
+				return true
+			}
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// summariseDurations computes the statistics of a set of durations, with nearest-rank
+// percentiles.
+func summariseDurations(durations []time.Duration, percentiles []float64) DurationStats {
+	if len(durations) == 0 {
+		return DurationStats{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	stats := DurationStats{
+		Count:       len(sorted),
+		Min:         sorted[0],
+		Max:         sorted[len(sorted)-1],
+		Mean:        total / time.Duration(len(sorted)),
+		Percentiles: make(map[float64]time.Duration, len(percentiles)),
+	}
+	for _, p := range percentiles {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		stats.Percentiles[p] = sorted[max(rank, 1)-1]
+	}
+	return stats
+}
+
+// ChangeInstanceSLAReport lists every change instance matching the filters, fetches each one's
+// history (a few at a time) and builds an SLA report from them. Bound the listing with the
+// filters - a StartDate and EndDate window, a ServiceName - since each change costs a request.
+func (c *Client) ChangeInstanceSLAReport(
+	ctx context.Context,
+	filters *GetChangeInstancesRequest,
+	opts *SLAReportOptions,
+) (*SLAReport, error) {
+	changes, err := c.listAllChangeInstances(ctx, filters, "")
+	if err != nil {
+		return nil, err
+	}
+	var pov POV
+	if filters != nil {
+		pov = filters.POV
+	}
+
+	asOf := time.Now()
+	if opts != nil && !opts.AsOf.IsZero() {
+		asOf = opts.AsOf
+	}
+	samples := make([]SLASample, len(changes))
+	errs := make([]error, len(changes))
+	runBulk(len(changes), 0, func(i int) {
+		entries, err := c.ListChangeInstanceHistory(ctx, pov, changes[i].ID)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to fetch the history of change instance %d: %w", changes[i].ID, err)
+			return
+		}
+		samples[i] = SLASample{ChangeInstance: changes[i], Timeline: BuildTimeline(changes[i].ID, entries, asOf)}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return BuildSLAReport(samples, opts)
+}
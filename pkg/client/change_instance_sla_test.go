@@ -0,0 +1,122 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var slaStart = time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+
+// slaSample builds a change that was approved and completed the given times after creation; a
+// zero time means it never got there, and final is the state it ended in instead.
+func slaSample(id int, service, team string, approve, complete time.Duration,
+	final client.ChangeInstanceState,
+) client.SLASample {
+	entries := []client.ChangeInstanceHistoryEntry{historyEntry(client.ChangeInstancePENDING, slaStart, "", "")}
+	if approve > 0 {
+		entries = append(entries, historyEntry(client.ChangeInstanceAPPROVED, slaStart.Add(approve), "", ""))
+	}
+	if complete > 0 {
+		entries = append(entries, historyEntry(client.ChangeInstanceCOMPLETED, slaStart.Add(complete), "", ""))
+	}
+	if final != "" {
+		entries = append(entries, historyEntry(final, slaStart.Add(time.Minute), "", ""))
+	}
+
+	ci := client.ChangeInstance{
+		ID:           id,
+		Service:      client.ChangeInstanceService{Name: service},
+		ConsumerTeam: client.Team{Name: team},
+	}
+	return client.SLASample{ChangeInstance: ci, Timeline: client.BuildTimeline(id, entries, slaStart.Add(48*time.Hour))}
+}
+
+func TestBuildSLAReport(t *testing.T) {
+	samples := []client.SLASample{
+		slaSample(1, "vm", "web", time.Hour, 2*time.Hour, ""),
+		slaSample(2, "vm", "web", 3*time.Hour, 5*time.Hour, ""),
+		slaSample(3, "vm", "data", 30*time.Minute, 0, ""),               // approved, still building
+		slaSample(4, "vm", "data", 0, 0, ""),                            // still pending
+		slaSample(5, "vm", "data", 0, 0, client.ChangeInstanceREJECTED), // decided, not waiting
+		slaSample(6, "dns", "", 5*time.Minute, 10*time.Minute, ""),
+	}
+
+	report, err := client.BuildSLAReport(samples, &client.SLAReportOptions{
+		Default:     client.SLATarget{TimeToApprove: 2 * time.Hour, TimeToComplete: 8 * time.Hour},
+		Services:    map[string]client.SLATarget{"dns": {TimeToComplete: 5 * time.Minute}},
+		Percentiles: []float64{50, 100},
+		AsOf:        slaStart.Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, report.Services, 2)
+	dns, vm := report.Services[0], report.Services[1]
+	assert.Equal(t, "dns", dns.Service)
+	assert.Equal(t, 1, dns.Breaches)
+
+	assert.Equal(t, "vm", vm.Service)
+	assert.Equal(t, 5, vm.Changes)
+	assert.Equal(t, client.DurationStats{
+		Count: 3, Min: 30 * time.Minute, Max: 3 * time.Hour, Mean: 90 * time.Minute,
+		Percentiles: map[float64]time.Duration{50: time.Hour, 100: 3 * time.Hour},
+	}, vm.TimeToApprove)
+	assert.Equal(t, 2, vm.TimeToComplete.Count)
+	assert.Equal(t, 3, vm.Breaches)
+
+	require.Len(t, report.ServiceTeams, 2, "the team-less dns change has no team group")
+	assert.Equal(t, "data", report.ServiceTeams[0].ConsumerTeam)
+	assert.Equal(t, 3, report.ServiceTeams[0].Changes)
+	assert.Equal(t, "web", report.ServiceTeams[1].ConsumerTeam)
+
+	assert.Equal(t, []client.SLABreach{
+		{ChangeInstanceID: 2, Service: "vm", ConsumerTeam: "web", Metric: client.SLATimeToApprove,
+			Target: 2 * time.Hour, Actual: 3 * time.Hour},
+		{ChangeInstanceID: 3, Service: "vm", ConsumerTeam: "data", Metric: client.SLATimeToComplete,
+			Target: 8 * time.Hour, Actual: 24 * time.Hour, Open: true},
+		{ChangeInstanceID: 4, Service: "vm", ConsumerTeam: "data", Metric: client.SLATimeToApprove,
+			Target: 2 * time.Hour, Actual: 24 * time.Hour, Open: true},
+		{ChangeInstanceID: 4, Service: "vm", ConsumerTeam: "data", Metric: client.SLATimeToComplete,
+			Target: 8 * time.Hour, Actual: 24 * time.Hour, Open: true},
+		{ChangeInstanceID: 6, Service: "dns", Metric: client.SLATimeToComplete,
+			Target: 5 * time.Minute, Actual: 10 * time.Minute},
+	}, report.Breaches)
+
+	_, err = client.BuildSLAReport(samples, &client.SLAReportOptions{Percentiles: []float64{0}})
+	require.Error(t, err)
+}
+
+func TestChangeInstanceSLAReport(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", httpmock.NewStringResponder(200,
+		`{"count":2,"next":null,"previous":null,"results":[
+			{"id":7,"service":{"name":"vm"},"consumer_team":{"name":"web"}},
+			{"id":8,"service":{"name":"vm"},"consumer_team":{"name":"web"}}]}`))
+	for _, id := range []int{7, 8} {
+		httpmock.RegisterResponder("GET", fmt.Sprintf("%s/%d/history/", changeInstancesRoot, id),
+			func(*http.Request) (*http.Response, error) {
+				return httpmock.NewStringResponse(200, changeInstanceHistory), nil
+			})
+	}
+
+	nc := newPackTestClient(t)
+	report, err := nc.ChangeInstanceSLAReport(context.Background(), &client.GetChangeInstancesRequest{ServiceName: "vm"},
+		&client.SLAReportOptions{Default: client.SLATarget{TimeToComplete: time.Hour}})
+	require.NoError(t, err)
+
+	require.Len(t, report.Services, 1)
+	assert.Equal(t, 2, report.Services[0].Changes)
+	// the fixture's change was approved at 11:20 and completed at 14:03, with no earlier entry,
+	// so it is measured from its approval
+	assert.Equal(t, 163*time.Minute, report.Services[0].TimeToComplete.Max)
+	assert.Len(t, report.Breaches, 2)
+}
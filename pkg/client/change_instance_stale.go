@@ -0,0 +1,370 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StaleAction is what a stale rule does with the change instances it flags.
+type StaleAction string
+
+const (
+	// StaleReportOnly lists the change in the report and does nothing else. It is the default.
+	StaleReportOnly StaleAction = ""
+	// StaleSetError moves the change to ERROR, with the rule's message as the log.
+	StaleSetError StaleAction = "error"
+	// StaleClose moves the change to CLOSED, with the rule's message as the log.
+	StaleClose StaleAction = "close"
+	// StaleNotify passes the change to StaleScanOptions.OnStale.
+	StaleNotify StaleAction = "notify"
+)
+
+// StaleRule says how long a change instance may sit in a state before it counts as stuck, and
+// what to do about it.
+type StaleRule struct {
+	// Name identifies the rule in reports and in the default message.
+	Name string
+	// States are the states the rule watches. Empty means PENDING and APPROVED, where changes
+	// wait on people.
+	States []ChangeInstanceState
+	// Services limits the rule to these services. Empty means every service.
+	Services []string
+	// After is how long a change may stay in the state before the rule flags it.
+	After time.Duration
+	// Action is what to do with a flagged change.
+	Action StaleAction
+	// Message is the log StaleSetError and StaleClose record. Defaults to one naming the state,
+	// the time spent in it and the rule.
+	Message string
+}
+
+// StaleScanOptions configures a stale scan. Rules are tried in order and the first that covers a
+// change's state and service decides, so list service-specific rules before general ones.
+type StaleScanOptions struct {
+	// Rules are the thresholds. At least one is required.
+	Rules []StaleRule
+	// AsOf is when the changes are judged. Defaults to now.
+	AsOf time.Time
+	// DryRun reports what each rule would do without doing it.
+	DryRun bool
+	// OnStale receives the changes StaleNotify rules flag. Required when a rule notifies. An
+	// error it returns is recorded against the change, and the scan carries on.
+	OnStale func(ctx context.Context, stale StaleChange) error
+	// Concurrency bounds the history requests and actions. Defaults to 8.
+	Concurrency int
+	// TransitionOptions are passed to the state changes StaleSetError and StaleClose make.
+	TransitionOptions []TransitionOption
+}
+
+// Validate checks the rules are complete and the actions known.
+func (o *StaleScanOptions) Validate() error {
+	if len(o.Rules) == 0 {
+		return errors.New("a stale scan needs at least one rule")
+	}
+	if o.Concurrency < 0 {
+		return errors.New("stale scan concurrency cannot be negative")
+	}
+	for i, rule := range o.Rules {
+		where := fmt.Sprintf("stale rule %d (%s)", i+1, rule.Name)
+		if rule.After <= 0 {
+			return fmt.Errorf("%s: After must be positive", where)
+		}
+		switch rule.Action {
+		case StaleReportOnly, StaleSetError, StaleClose:
+		case StaleNotify:
+			if o.OnStale == nil {
+				return fmt.Errorf("%s: notifies, but OnStale is not set", where)
+			}
+		default:
+			return fmt.Errorf("%s: unknown action %q", where, rule.Action)
+		}
+	}
+	return nil
+}
+
+// states returns the states the rule watches.
+func (r *StaleRule) states() []ChangeInstanceState {
+	if len(r.States) == 0 {
+		return []ChangeInstanceState{ChangeInstancePENDING, ChangeInstanceAPPROVED}
+	}
+	return r.States
+}
+
+// covers reports whether the rule applies to a change in the state for the service.
+func (r *StaleRule) covers(state ChangeInstanceState, service string) bool {
+	return slices.Contains(r.states(), state) && (len(r.Services) == 0 || slices.Contains(r.Services, service))
+}
+
+// StaleChange is a change instance a scan flagged.
+type StaleChange struct {
+	ChangeInstance ChangeInstance
+	// Rule is the name of the rule that flagged it.
+	Rule string
+	// State is the state it is stuck in.
+	State ChangeInstanceState
+	// Since is when it entered that state.
+	Since time.Time
+	// Age is how long it has been there.
+	Age time.Duration
+	// Action is what the rule does with it.
+	Action StaleAction
+	// Message is the log StaleSetError and StaleClose record for it: the rule's Message, or the
+	// default one naming the state, the age and the rule.
+	Message string
+	// Acted is set once the action was taken. It stays unset in a dry run and for a report-only
+	// rule.
+	Acted bool
+	// Err is why the action failed.
+	Err error
+}
+
+// StaleGroup is the stale changes between one service owner team and one consumer team.
+type StaleGroup struct {
+	ServiceOwnerTeam string
+	ConsumerTeam     string
+	Changes          []StaleChange
+}
+
+// StaleReport is the outcome of a stale scan.
+type StaleReport struct {
+	// Scanned is how many change instances were considered.
+	Scanned int
+	// Stale lists the flagged changes by id.
+	Stale []StaleChange
+	// Groups holds the same changes by service owner team and then consumer team, sorted.
+	Groups []StaleGroup
+}
+
+// Summary renders the report's headline: "3 of 120 change instances are stale: 2 moved to
+// ERROR, 1 reported".
+func (r *StaleReport) Summary() string {
+	if len(r.Stale) == 0 {
+		return fmt.Sprintf("none of %d change instances is stale", r.Scanned)
+	}
+	counts := map[string]int{}
+	for _, stale := range r.Stale {
+		outcome := "reported"
+		switch {
+		case stale.Err != nil:
+			outcome = "failed"
+		case stale.Action == StaleSetError && stale.Acted:
+			outcome = "moved to ERROR"
+		case stale.Action == StaleClose && stale.Acted:
+			outcome = "closed"
+		case stale.Action == StaleNotify && stale.Acted:
+			outcome = "notified"
+		}
+		counts[outcome]++
+	}
+	var parts []string
+	for _, outcome := range []string{"moved to ERROR", "closed", "notified", "reported", "failed"} {
+		if counts[outcome] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[outcome], outcome))
+		}
+	}
+	return fmt.Sprintf("%d of %d change instances are stale: %s", len(r.Stale), r.Scanned, strings.Join(parts, ", "))
+}
+
+// Err joins the errors of the actions that failed, each prefixed with its change instance id.
+func (r *StaleReport) Err() error {
+	var errs []error
+	for _, stale := range r.Stale {
+		if stale.Err != nil {
+			errs = append(errs, fmt.Errorf("change instance %d: %w", stale.ChangeInstance.ID, stale.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FindStaleChangeInstances applies the rules to changes whose timelines are already known,
+// without acting on them. A change's time in its state runs from its timeline's last interval;
+// a sample without a timeline is judged from its Modified time, which a log-only update resets,
+// so it may be flagged late but never early.
+func FindStaleChangeInstances(samples []SLASample, opts *StaleScanOptions) ([]StaleChange, error) {
+	if opts == nil {
+		opts = &StaleScanOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	asOf := opts.AsOf
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+
+	var stale []StaleChange
+	for _, sample := range samples {
+		ci := sample.ChangeInstance
+		since := ci.Modified
+		if sample.Timeline != nil && len(sample.Timeline.Intervals) > 0 {
+			since = sample.Timeline.Intervals[len(sample.Timeline.Intervals)-1].Start
+		}
+		for _, rule := range opts.Rules {
+			if !rule.covers(ci.State, ci.Service.Name) {
+				continue
+			}
+			if age := asOf.Sub(since); age > rule.After {
+				message := rule.Message
+				if message == "" {
+					message = fmt.Sprintf("no progress in %s for %s (stale rule %s)",
+						ci.State, formatStaleAge(age), rule.Name)
+				}
+				stale = append(stale, StaleChange{
+					ChangeInstance: ci,
+					Rule:           rule.Name,
+					State:          ci.State,
+					Since:          since,
+					Age:            age,
+					Action:         rule.Action,
+					Message:        message,
+				})
+			}
+			break
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].ChangeInstance.ID < stale[j].ChangeInstance.ID })
+	return stale, nil
+}
+
+// ScanStaleChangeInstances finds the change instances matching the filters that have sat in a
+// state longer than their rule allows, takes each rule's action unless DryRun is set, and
+// reports the result by team.
+//
+// The time in the current state comes from each change's history, which costs a request per
+// change; changes created too recently to break any rule are ruled out first, and when the
+// filters name no state, in States or State, only the states the rules watch are listed. Action
+// failures are recorded in the report rather than stopping the scan - see StaleReport.Err -
+// while a failure to list or to read a history returns an error.
+func (c *Client) ScanStaleChangeInstances(
+	ctx context.Context,
+	filters *GetChangeInstancesRequest,
+	opts *StaleScanOptions,
+) (*StaleReport, error) {
+	if opts == nil {
+		opts = &StaleScanOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	scan := *opts
+	if scan.AsOf.IsZero() {
+		scan.AsOf = time.Now()
+	}
+
+	var listing GetChangeInstancesRequest
+	if filters != nil {
+		listing = *filters
+	}
+	if len(listing.States) == 0 && listing.State == "" {
+		for _, rule := range scan.Rules {
+			for _, state := range rule.states() {
+				if !slices.Contains(listing.States, state) {
+					listing.States = append(listing.States, state)
+				}
+			}
+		}
+	}
+	shortest := scan.Rules[0].After
+	for _, rule := range scan.Rules {
+		shortest = min(shortest, rule.After)
+	}
+
+	changes, err := c.listAllChangeInstances(ctx, &listing, "")
+	if err != nil {
+		return nil, err
+	}
+	var candidates []ChangeInstance
+	for _, ci := range changes {
+		if ci.Created.IsZero() || scan.AsOf.Sub(ci.Created) > shortest {
+			candidates = append(candidates, ci)
+		}
+	}
+
+	samples := make([]SLASample, len(candidates))
+	errs := make([]error, len(candidates))
+	runBulk(len(candidates), scan.Concurrency, func(i int) {
+		entries, err := c.ListChangeInstanceHistory(ctx, listing.POV, candidates[i].ID)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to fetch the history of change instance %d: %w", candidates[i].ID, err)
+			return
+		}
+		samples[i] = SLASample{ChangeInstance: candidates[i], Timeline: BuildTimeline(candidates[i].ID, entries, scan.AsOf)}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	stale, err := FindStaleChangeInstances(samples, &scan)
+	if err != nil {
+		return nil, err
+	}
+	if !scan.DryRun {
+		c.actOnStale(ctx, listing.POV, stale, &scan)
+	}
+	return &StaleReport{Scanned: len(changes), Stale: stale, Groups: groupStale(stale)}, nil
+}
+
+// actOnStale takes each flagged change's action, recording the outcome on it.
+func (c *Client) actOnStale(ctx context.Context, pov POV, stale []StaleChange, opts *StaleScanOptions) {
+	runBulk(len(stale), opts.Concurrency, func(i int) {
+		change := &stale[i]
+
+		var err error
+		switch change.Action {
+		case StaleReportOnly:
+			return
+		case StaleSetError:
+			_, err = c.SetErrorChangeInstanceWithContext(ctx, pov, change.ChangeInstance.ID, change.Message, nil,
+				opts.TransitionOptions...)
+		case StaleClose:
+			_, err = c.CloseChangeInstanceWithContext(ctx, pov, change.ChangeInstance.ID, change.Message, nil,
+				opts.TransitionOptions...)
+		case StaleNotify:
+			err = opts.OnStale(ctx, *change)
+		}
+		change.Acted, change.Err = err == nil, err
+	})
+}
+
+// groupStale groups flagged changes by service owner team and consumer team.
+func groupStale(stale []StaleChange) []StaleGroup {
+	type key struct{ owner, consumer string }
+	index := map[key]int{}
+	var groups []StaleGroup
+	for _, change := range stale {
+		owner := change.ChangeInstance.ServiceOwnerTeam.Name
+		if owner == "" {
+			owner = change.ChangeInstance.Owner.Name
+		}
+		k := key{owner, change.ChangeInstance.ConsumerTeam.Name}
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, StaleGroup{ServiceOwnerTeam: k.owner, ConsumerTeam: k.consumer})
+		}
+		groups[i].Changes = append(groups[i].Changes, change)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].ServiceOwnerTeam != groups[j].ServiceOwnerTeam {
+			return groups[i].ServiceOwnerTeam < groups[j].ServiceOwnerTeam
+		}
+		return groups[i].ConsumerTeam < groups[j].ConsumerTeam
+	})
+	return groups
+}
+
+// formatStaleAge renders an age in days and hours once it passes a day: "23d4h".
+func formatStaleAge(age time.Duration) string {
+	if age < 24*time.Hour {
+		return age.Round(time.Minute).String()
+	}
+	days := int(age / (24 * time.Hour))
+	hours := int(age % (24 * time.Hour) / time.Hour)
+	return fmt.Sprintf("%dd%dh", days, hours)
+}
@@ -0,0 +1,201 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var staleAsOf = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+// staleSample builds a change that entered its current state the given time before staleAsOf.
+func staleSample(id int, service string, state client.ChangeInstanceState, age time.Duration) client.SLASample {
+	entries := []client.ChangeInstanceHistoryEntry{historyEntry(client.ChangeInstancePENDING, staleAsOf.Add(-age), "", "")}
+	if state != client.ChangeInstancePENDING {
+		entries[0].Modified = entries[0].Modified.Add(-time.Hour)
+		entries = append(entries, historyEntry(state, staleAsOf.Add(-age), "", ""))
+	}
+	ci := client.ChangeInstance{ID: id, State: state, Service: client.ChangeInstanceService{Name: service}}
+	return client.SLASample{ChangeInstance: ci, Timeline: client.BuildTimeline(id, entries, staleAsOf)}
+}
+
+func TestFindStaleChangeInstances(t *testing.T) {
+	const day = 24 * time.Hour
+	noTimeline := client.SLASample{ChangeInstance: client.ChangeInstance{
+		ID: 6, State: client.ChangeInstancePENDING, Modified: staleAsOf.Add(-30 * day),
+	}}
+
+	stale, err := client.FindStaleChangeInstances([]client.SLASample{
+		staleSample(5, "vm", client.ChangeInstanceAPPROVED, 4*day),
+		staleSample(1, "dns", client.ChangeInstancePENDING, 2*day),
+		staleSample(2, "vm", client.ChangeInstancePENDING, 2*day),
+		staleSample(3, "vm", client.ChangeInstanceCOMPLETED, 90*day),
+		staleSample(4, "vm", client.ChangeInstanceAPPROVED, 2*day),
+		noTimeline,
+	}, &client.StaleScanOptions{
+		Rules: []client.StaleRule{
+			{Name: "dns-fast", Services: []string{"dns"}, After: day},
+			{Name: "waiting", After: 3 * day},
+		},
+		AsOf: staleAsOf,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, stale, 3)
+	assert.Equal(t, 1, stale[0].ChangeInstance.ID)
+	assert.Equal(t, "dns-fast", stale[0].Rule, "the service's own rule comes first")
+	assert.Equal(t, 2*day, stale[0].Age)
+	assert.Equal(t, 5, stale[1].ChangeInstance.ID)
+	assert.Equal(t, client.ChangeInstanceAPPROVED, stale[1].State)
+	assert.Equal(t, staleAsOf.Add(-4*day), stale[1].Since, "measured from entering APPROVED, not from creation")
+	assert.Equal(t, 6, stale[2].ChangeInstance.ID, "without a timeline, judged from Modified")
+}
+
+// TestFindStaleChangeInstancesMessages gives two rules the same name, so each change must carry
+// the message of the rule that actually flagged it.
+func TestFindStaleChangeInstancesMessages(t *testing.T) {
+	const day = 24 * time.Hour
+	stale, err := client.FindStaleChangeInstances([]client.SLASample{
+		staleSample(1, "dns", client.ChangeInstancePENDING, 2*day),
+		staleSample(2, "vm", client.ChangeInstancePENDING, 2*day),
+		staleSample(3, "lb", client.ChangeInstancePENDING, 2*day),
+	}, &client.StaleScanOptions{
+		Rules: []client.StaleRule{
+			{Name: "stuck", Services: []string{"dns"}, After: day, Message: "ask the network team"},
+			{Name: "stuck", Services: []string{"vm"}, After: day, Message: "ask the compute team"},
+			{Name: "stuck", After: day},
+		},
+		AsOf: staleAsOf,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, stale, 3)
+	assert.Equal(t, "ask the network team", stale[0].Message)
+	assert.Equal(t, "ask the compute team", stale[1].Message)
+	assert.Equal(t, "no progress in PENDING for 2d0h (stale rule stuck)", stale[2].Message)
+}
+
+func TestStaleScanOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   client.StaleScanOptions
+		errMsg string
+	}{
+		{"no rules", client.StaleScanOptions{}, "needs at least one rule"},
+		{"no threshold", client.StaleScanOptions{Rules: []client.StaleRule{{Name: "a"}}},
+			"stale rule 1 (a): After must be positive"},
+		{"unknown action", client.StaleScanOptions{Rules: []client.StaleRule{{Name: "a", After: time.Hour, Action: "page"}}},
+			`stale rule 1 (a): unknown action "page"`},
+		{"notify without a callback", client.StaleScanOptions{Rules: []client.StaleRule{
+			{Name: "a", After: time.Hour, Action: client.StaleNotify},
+		}}, "notifies, but OnStale is not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, tt.opts.Validate(), tt.errMsg)
+		})
+	}
+}
+
+func TestScanStaleChangeInstances(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	const day = 24 * time.Hour
+	var query url.Values
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", httpmock.Responder(
+		func(req *http.Request) (*http.Response, error) {
+			query = req.URL.Query()
+			return httpmock.NewStringResponse(200, fmt.Sprintf(`{"count":3,"next":null,"results":[
+				{"id":1,"state":"APPROVED","created":%[1]q,"service":{"name":"vm"},
+					"service_owner_team":{"name":"infra"},"consumer_team":{"name":"web"}},
+				{"id":2,"state":"PENDING","created":%[1]q,"service":{"name":"dns"},
+					"service_owner_team":{"name":"network"},"consumer_team":{"name":"web"}},
+				{"id":3,"state":"PENDING","created":%[2]q,"service":{"name":"vm"}}]}`,
+				staleAsOf.Add(-10*day).Format(time.RFC3339), staleAsOf.Add(-time.Hour).Format(time.RFC3339))), nil
+		}))
+	for id, age := range map[int]time.Duration{1: 8 * day, 2: 5 * day} {
+		raw, err := json.Marshal([]client.ChangeInstanceHistoryEntry{
+			historyEntry(client.ChangeInstancePENDING, staleAsOf.Add(-10*day), "", ""),
+			historyEntry(client.ChangeInstanceAPPROVED, staleAsOf.Add(-age), "", ""),
+		})
+		require.NoError(t, err)
+		if id == 2 {
+			raw, err = json.Marshal([]client.ChangeInstanceHistoryEntry{
+				historyEntry(client.ChangeInstancePENDING, staleAsOf.Add(-age), "", ""),
+			})
+			require.NoError(t, err)
+		}
+		httpmock.RegisterResponder("GET", fmt.Sprintf("%s/%d/history/", changeInstancesRoot, id),
+			httpmock.NewBytesResponder(200, raw))
+	}
+	patches := capturePatches(t)
+
+	var mu sync.Mutex
+	var notified []int
+	opts := &client.StaleScanOptions{
+		Rules: []client.StaleRule{
+			{Name: "stuck-build", States: []client.ChangeInstanceState{client.ChangeInstanceAPPROVED},
+				After: 7 * day, Action: client.StaleSetError},
+			{Name: "unreviewed", After: 3 * day, Action: client.StaleNotify},
+		},
+		AsOf: staleAsOf,
+		OnStale: func(_ context.Context, stale client.StaleChange) error {
+			mu.Lock()
+			defer mu.Unlock()
+			notified = append(notified, stale.ChangeInstance.ID)
+			return errors.New("pager offline")
+		},
+	}
+
+	nc := newPackTestClient(t)
+	report, err := nc.ScanStaleChangeInstances(context.Background(), nil, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, "APPROVED,PENDING", query.Get("state"), "only the states the rules watch are listed")
+	assert.Equal(t, 3, report.Scanned)
+	assert.Equal(t, map[string]any{
+		"state": "ERROR",
+		"log":   "no progress in APPROVED for 8d0h (stale rule stuck-build)",
+	}, patches()[1])
+	assert.Equal(t, []int{2}, notified)
+	assert.Equal(t, "2 of 3 change instances are stale: 1 moved to ERROR, 1 failed", report.Summary())
+	assert.EqualError(t, report.Err(), "change instance 2: pager offline")
+
+	require.Len(t, report.Groups, 2)
+	assert.Equal(t, "infra", report.Groups[0].ServiceOwnerTeam)
+	assert.Equal(t, "network", report.Groups[1].ServiceOwnerTeam)
+	assert.Equal(t, "web", report.Groups[1].ConsumerTeam)
+	assert.Equal(t, 1+2+1, httpmock.GetTotalCallCount(), "the change too young to be stale is not read")
+}
+
+func TestScanStaleChangeInstancesLegacyState(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var query url.Values
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", httpmock.Responder(
+		func(req *http.Request) (*http.Response, error) {
+			query = req.URL.Query()
+			return httpmock.NewStringResponse(200, `{"count":0,"next":null,"results":[]}`), nil
+		}))
+
+	nc := newPackTestClient(t)
+	_, err := nc.ScanStaleChangeInstances(context.Background(),
+		&client.GetChangeInstancesRequest{State: string(client.ChangeInstanceAPPROVED)},
+		&client.StaleScanOptions{Rules: []client.StaleRule{{Name: "waiting", After: time.Hour}}, AsOf: staleAsOf})
+	require.NoError(t, err)
+
+	assert.Equal(t, "APPROVED", query.Get("state"), "the caller's state is not widened to the rules'")
+}
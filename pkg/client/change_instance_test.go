@@ -110,6 +110,88 @@ func TestChangeInstancesToQueryParams(t *testing.T) {
 	}
 }
 
+//nolint:funlen // one subtest per typed filter; each pins its own wire form
+func TestChangeInstancesTypedFilters(t *testing.T) {
+	t.Run("encodes typed ids and states as in lookups", func(t *testing.T) {
+		request := &client.GetChangeInstancesRequest{
+			ServiceIDs:     []int{4, 9},
+			SubmissionIDs:  []int{31},
+			States:         []client.ChangeInstanceState{client.ChangeInstancePENDING, client.ChangeInstanceAPPROVED},
+			ServiceItemIDs: []int{389},
+		}
+
+		query, err := request.ToQueryParams()
+
+		require.NoError(t, err)
+		assert.Equal(t, "service_id=4%2C9&service_item_id=389&state=PENDING%2CAPPROVED&submission_id=31", query)
+	})
+
+	t.Run("sends exclude_referenced=false when asked to", func(t *testing.T) {
+		exclude := false
+		request := &client.GetChangeInstancesRequest{ExcludeReferencedFilter: &exclude}
+
+		query, err := request.ToQueryParams()
+
+		require.NoError(t, err)
+		assert.Equal(t, "exclude_referenced=false", query)
+	})
+
+	t.Run("accepts the plain bool and the pointer when they agree", func(t *testing.T) {
+		exclude := true
+		request := &client.GetChangeInstancesRequest{ExcludeReferenced: true, ExcludeReferencedFilter: &exclude}
+
+		query, err := request.ToQueryParams()
+
+		require.NoError(t, err)
+		assert.Equal(t, "exclude_referenced=true", query)
+	})
+
+	t.Run("rejects the plain bool and the pointer when they disagree", func(t *testing.T) {
+		exclude := false
+		request := &client.GetChangeInstancesRequest{ExcludeReferenced: true, ExcludeReferencedFilter: &exclude}
+
+		_, err := request.ToQueryParams()
+
+		require.ErrorContains(t, err, "exclude_referenced")
+	})
+
+	t.Run("encodes a declaration filter as compact JSON", func(t *testing.T) {
+		request := &client.GetChangeInstancesRequest{
+			DeclarationRegexFilter: map[string]any{"name": "^web-"},
+		}
+
+		query, err := request.ToQueryParams()
+
+		require.NoError(t, err)
+		assert.Equal(t, "declaration_regex=%7B%22name%22%3A%22%5Eweb-%22%7D", query)
+	})
+
+	t.Run("rejects a filter set in both forms", func(t *testing.T) {
+		request := &client.GetChangeInstancesRequest{
+			State:  "PENDING",
+			States: []client.ChangeInstanceState{client.ChangeInstanceAPPROVED},
+		}
+
+		_, err := request.ToQueryParams()
+
+		require.ErrorContains(t, err, "state filter is set twice")
+	})
+
+	t.Run("fails the listing rather than sending an ambiguous filter", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		nc := newPackTestClient(t)
+		_, err := nc.GetChangeInstancesWithContext(context.Background(), &client.GetChangeInstancesRequest{
+			Declaration:       `{"name":"web"}`,
+			DeclarationFilter: map[string]any{"name": "web"},
+		})
+
+		require.ErrorContains(t, err, "declaration filter is set twice")
+		assert.Equal(t, 0, httpmock.GetTotalCallCount())
+	})
+}
+
 func exampleChangeInstance() *client.ChangeInstance { //nolint:funlen
 	// Example response from the API: testdata/200_single_change_instance_response.json
 
@@ -404,7 +486,7 @@ func TestClientApproveChangeInstance(t *testing.T) { //nolint:funlen
 		assert.NotEqual(t, client.ChangeInstance{}, *changeInstance)
 		assert.Equal(t, 53, changeInstance.ID)
 		assert.Equal(t, "http://api-aws.demo.netorca.io/v1/orcabase/serviceowner/change_instances/53/", changeInstance.URL)
-		assert.Equal(t, string(client.ChangeInstanceAPPROVED), changeInstance.State)
+		assert.Equal(t, client.ChangeInstanceAPPROVED, changeInstance.State)
 		assert.Equal(t, "test log", changeInstance.Log)
 		assert.JSONEq(t, `{"comment":"approved"}`, string(changeInstance.ServiceItem.DeployedItem))
 	})
@@ -471,7 +553,7 @@ func TestClientCompleteChangeInstance(t *testing.T) { //nolint:funlen
 		assert.NotEqual(t, client.ChangeInstance{}, *changeInstance)
 		assert.Equal(t, 53, changeInstance.ID)
 		assert.Equal(t, "http://api-aws.demo.netorca.io/v1/orcabase/serviceowner/change_instances/53/", changeInstance.URL)
-		assert.Equal(t, string(client.ChangeInstanceCOMPLETED), changeInstance.State)
+		assert.Equal(t, client.ChangeInstanceCOMPLETED, changeInstance.State)
 		assert.Equal(t, "test log", changeInstance.Log)
 		assert.JSONEq(t, `{"comment":"completed"}`, string(changeInstance.ServiceItem.DeployedItem))
 	})
@@ -538,7 +620,7 @@ func TestClientCloseChangeInstance(t *testing.T) { //nolint:funlen
 		assert.NotEqual(t, client.ChangeInstance{}, *changeInstance)
 		assert.Equal(t, 53, changeInstance.ID)
 		assert.Equal(t, "http://api-aws.demo.netorca.io/v1/orcabase/serviceowner/change_instances/53/", changeInstance.URL)
-		assert.Equal(t, string(client.ChangeInstanceCLOSED), changeInstance.State)
+		assert.Equal(t, client.ChangeInstanceCLOSED, changeInstance.State)
 		assert.Equal(t, "test log", changeInstance.Log)
 		assert.JSONEq(t, `{"comment":"closed"}`, string(changeInstance.ServiceItem.DeployedItem))
 	})
@@ -605,7 +687,7 @@ func TestClientRejectChangeInstance(t *testing.T) { //nolint:funlen
 		assert.NotEqual(t, client.ChangeInstance{}, *changeInstance)
 		assert.Equal(t, 53, changeInstance.ID)
 		assert.Equal(t, "http://api-aws.demo.netorca.io/v1/orcabase/serviceowner/change_instances/53/", changeInstance.URL)
-		assert.Equal(t, string(client.ChangeInstanceREJECTED), changeInstance.State)
+		assert.Equal(t, client.ChangeInstanceREJECTED, changeInstance.State)
 		assert.Equal(t, "test log", changeInstance.Log)
 		assert.JSONEq(t, `{"comment":"rejected"}`, string(changeInstance.ServiceItem.DeployedItem))
 	})
@@ -674,7 +756,7 @@ func TestClientSetErrorChangeInstance(t *testing.T) { //nolint:funlen
 		assert.NotEqual(t, client.ChangeInstance{}, *changeInstance)
 		assert.Equal(t, 53, changeInstance.ID)
 		assert.Equal(t, "http://api-aws.demo.netorca.io/v1/orcabase/serviceowner/change_instances/53/", changeInstance.URL)
-		assert.Equal(t, string(client.ChangeInstanceERROR), changeInstance.State)
+		assert.Equal(t, client.ChangeInstanceERROR, changeInstance.State)
 		assert.Equal(t, "test log", changeInstance.Log)
 		assert.JSONEq(t, `{"comment":"error"}`, string(changeInstance.ServiceItem.DeployedItem))
 	})
@@ -736,6 +818,41 @@ func TestUpdateChangeInstanceOptionalFields(t *testing.T) {
 	})
 }
 
+// TestChangeInstanceStateHelpersWithContext checks the context-first helpers reach the route of
+// the POV they are given and send the state they are named after - the legacy forms hardcode
+// serviceowner, which is exactly what a consumer-side caller cannot live with.
+func TestChangeInstanceStateHelpersWithContext(t *testing.T) {
+	const consumerPatchURL = packTestBaseURL + "/v1/orcabase/consumer/change_instances/53/"
+
+	helpers := map[client.ChangeInstanceState]func(
+		*client.Client, context.Context, client.POV, int, string, json.RawMessage, ...client.TransitionOption,
+	) (*client.ChangeInstance, error){
+		client.ChangeInstanceAPPROVED:  (*client.Client).ApproveChangeInstanceWithContext,
+		client.ChangeInstanceREJECTED:  (*client.Client).RejectChangeInstanceWithContext,
+		client.ChangeInstanceCOMPLETED: (*client.Client).CompleteChangeInstanceWithContext,
+		client.ChangeInstanceCLOSED:    (*client.Client).CloseChangeInstanceWithContext,
+		client.ChangeInstanceERROR:     (*client.Client).SetErrorChangeInstanceWithContext,
+		client.ChangeInstancePENDING:   (*client.Client).PendingChangeInstanceWithContext,
+	}
+
+	for state, helper := range helpers {
+		t.Run(string(state), func(t *testing.T) {
+			httpmock.Activate()
+			defer httpmock.DeactivateAndReset()
+
+			var capturedBody string
+			httpmock.RegisterResponder("PATCH", consumerPatchURL,
+				captureBodyResponder(&capturedBody, 200, `{"id":53,"state":"`+string(state)+`"}`))
+
+			nc := newPackTestClient(t)
+			_, err := helper(nc, context.Background(), client.POVConsumer, 53, "note", nil)
+
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"state":"`+string(state)+`","log":"note"}`, capturedBody)
+		})
+	}
+}
+
 // TestGetChangeInstancesFilterWire pins the new filters to the wire. The backend's filterset
 // rejects any parameter it does not declare with a 400, so the names matter as much as the
 // values - and application_id in particular had no way of being sent at all before.
@@ -789,6 +906,27 @@ func TestGetChangeInstancesFilterWire(t *testing.T) {
 	})
 }
 
+func TestListAllChangeInstances(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var offsets []string
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", func(req *http.Request) (*http.Response, error) {
+		offsets = append(offsets, req.URL.Query().Get("offset"))
+		if req.URL.Query().Get("offset") == "" {
+			return httpmock.NewStringResponse(200, `{"count":3,"next":"more","results":[{"id":1},{"id":2}]}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"count":3,"next":null,"results":[{"id":3}]}`), nil
+	})
+
+	nc := newPackTestClient(t)
+	changes, err := nc.ListAllChangeInstances(context.Background(), &client.GetChangeInstancesRequest{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	assert.Equal(t, 3, changes[2].ID)
+	assert.Equal(t, []string{"", "2"}, offsets)
+}
+
 func TestGetDependantChangeInstances(t *testing.T) {
 	t.Run("queries the dependant route carrying the filters", func(t *testing.T) {
 		httpmock.Activate()
@@ -861,7 +999,7 @@ func TestGetReferencedChangeInstances(t *testing.T) {
 
 		nc := newPackTestClient(t)
 		_, err := nc.GetReferencedChangeInstances(context.Background(), &client.GetChangeInstancesRequest{
-			POV: string(client.POVConsumer),
+			POV: client.POVConsumer,
 		})
 
 		require.NoError(t, err)
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// StateInterval is a span of time a change instance spent in one state.
+type StateInterval struct {
+	// State is the state held throughout the interval.
+	State ChangeInstanceState
+	// Start is when the change entered the state.
+	Start time.Time
+	// End is when the change left the state. Zero for the state it is still in.
+	End time.Time
+	// Duration is End less Start, or for the current state the time from Start until the
+	// timeline was built.
+	Duration time.Duration
+	// ChangedBy is who moved the change into the state: a username, an API key's name suffixed
+	// " (Api Key)", or "SYSTEM". Empty when the platform recorded no one.
+	ChangedBy string
+	// ChangedByTeam is the team ChangedBy was acting for, when known.
+	ChangedByTeam string
+	// Reason is the log recorded with the transition into the state.
+	Reason string
+}
+
+// ChangeInstanceTimeline is a change instance's history arranged as consecutive state intervals,
+// oldest first.
+type ChangeInstanceTimeline struct {
+	// ID is the change instance.
+	ID int
+	// Intervals are the states the change has held, in order. The last is the current state.
+	Intervals []StateInterval
+}
+
+// BuildTimeline arranges history entries - as ListChangeInstanceHistory returns them, in any
+// order - into state intervals. Entries that only rewrote the log, leaving the state as it was,
+// do not start an interval. asOf closes the measurement of the current state; pass time.Now()
+// for a live change.
+func BuildTimeline(id int, entries []ChangeInstanceHistoryEntry, asOf time.Time) *ChangeInstanceTimeline {
+	ordered := make([]ChangeInstanceHistoryEntry, len(entries))
+	copy(ordered, entries)
+	// Stable, so entries sharing a timestamp keep the order the platform returned them in,
+	// reversed along with the rest: the platform lists newest first.
+	for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Modified.Before(ordered[j].Modified)
+	})
+
+	timeline := &ChangeInstanceTimeline{ID: id}
+	for _, entry := range ordered {
+		state := ChangeInstanceState(entry.State)
+		if n := len(timeline.Intervals); n > 0 && timeline.Intervals[n-1].State == state {
+			continue
+		}
+
+		interval := StateInterval{State: state, Start: entry.Modified, Reason: entry.Log}
+		if entry.ChangedBy != nil {
+			interval.ChangedBy = *entry.ChangedBy
+		}
+		if entry.ChangedByTeam != nil {
+			interval.ChangedByTeam = *entry.ChangedByTeam
+		}
+		if n := len(timeline.Intervals); n > 0 {
+			previous := &timeline.Intervals[n-1]
+			previous.End = entry.Modified
+			previous.Duration = previous.End.Sub(previous.Start)
+		}
+		timeline.Intervals = append(timeline.Intervals, interval)
+	}
+
+	if n := len(timeline.Intervals); n > 0 {
+		current := &timeline.Intervals[n-1]
+		current.Duration = max(0, asOf.Sub(current.Start))
+	}
+	return timeline
+}
+
+// Created returns when the change's first recorded state began, or zero for an empty timeline.
+func (t *ChangeInstanceTimeline) Created() time.Time {
+	if len(t.Intervals) == 0 {
+		return time.Time{}
+	}
+	return t.Intervals[0].Start
+}
+
+// Current returns the state the change is in now, or "" for an empty timeline.
+func (t *ChangeInstanceTimeline) Current() ChangeInstanceState {
+	if len(t.Intervals) == 0 {
+		return ""
+	}
+	return t.Intervals[len(t.Intervals)-1].State
+}
+
+// TimeIn returns the total time spent in a state, across every visit to it.
+func (t *ChangeInstanceTimeline) TimeIn(state ChangeInstanceState) time.Duration {
+	var total time.Duration
+	for _, interval := range t.Intervals {
+		if interval.State == state {
+			total += interval.Duration
+		}
+	}
+	return total
+}
+
+// TimeTo returns how long after its creation the change first reached a state, and whether it
+// ever has.
+func (t *ChangeInstanceTimeline) TimeTo(state ChangeInstanceState) (time.Duration, bool) {
+	for _, interval := range t.Intervals {
+		if interval.State == state {
+			return interval.Start.Sub(t.Created()), true
+		}
+	}
+	return 0, false
+}
+
+// GetChangeInstanceTimeline fetches a change instance's history and arranges it into a timeline
+// measured up to now.
+func (c *Client) GetChangeInstanceTimeline(ctx context.Context, pov POV, id int) (*ChangeInstanceTimeline, error) {
+	entries, err := c.ListChangeInstanceHistory(ctx, pov, id)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTimeline(id, entries, time.Now()), nil
+}
@@ -0,0 +1,96 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyEntry builds one history row.
+func historyEntry(state client.ChangeInstanceState, at time.Time, log, by string) client.ChangeInstanceHistoryEntry {
+	entry := client.ChangeInstanceHistoryEntry{ID: 7, State: string(state), Log: log, Modified: at, Reason: "state"}
+	if by != "" {
+		entry.ChangedBy = &by
+	}
+	return entry
+}
+
+func TestBuildTimeline(t *testing.T) {
+	created := time.Date(2026, 7, 19, 9, 0, 0, 0, time.UTC)
+	approved := created.Add(2 * time.Hour)
+	completed := approved.Add(30 * time.Minute)
+
+	logEdit := historyEntry(client.ChangeInstanceAPPROVED, approved.Add(10*time.Minute),
+		"Capacity confirmed, rack B.", "alice")
+	logEdit.Reason = "log"
+
+	// newest first, as the platform returns them, with a log-only edit in the middle
+	entries := []client.ChangeInstanceHistoryEntry{
+		historyEntry(client.ChangeInstanceCOMPLETED, completed, "Deployed to lon-dc1.", "SYSTEM"),
+		logEdit,
+		historyEntry(client.ChangeInstanceAPPROVED, approved, "Capacity confirmed.", "terraform-key (Api Key)"),
+		historyEntry(client.ChangeInstancePENDING, created, "", ""),
+	}
+
+	timeline := client.BuildTimeline(7, entries, completed.Add(time.Hour))
+
+	require.Len(t, timeline.Intervals, 3)
+	assert.Equal(t, client.StateInterval{
+		State: client.ChangeInstancePENDING, Start: created, End: approved, Duration: 2 * time.Hour,
+	}, timeline.Intervals[0])
+	assert.Equal(t, client.StateInterval{
+		State: client.ChangeInstanceAPPROVED, Start: approved, End: completed, Duration: 30 * time.Minute,
+		ChangedBy: "terraform-key (Api Key)", Reason: "Capacity confirmed.",
+	}, timeline.Intervals[1])
+	assert.Equal(t, client.StateInterval{
+		State: client.ChangeInstanceCOMPLETED, Start: completed, Duration: time.Hour,
+		ChangedBy: "SYSTEM", Reason: "Deployed to lon-dc1.",
+	}, timeline.Intervals[2], "the current state is open, measured up to asOf")
+
+	assert.Equal(t, created, timeline.Created())
+	assert.Equal(t, client.ChangeInstanceCOMPLETED, timeline.Current())
+	assert.Equal(t, 2*time.Hour, timeline.TimeIn(client.ChangeInstancePENDING))
+
+	took, ok := timeline.TimeTo(client.ChangeInstanceCOMPLETED)
+	assert.True(t, ok)
+	assert.Equal(t, 150*time.Minute, took)
+	_, ok = timeline.TimeTo(client.ChangeInstanceREJECTED)
+	assert.False(t, ok)
+
+	t.Run("repeated visits add up", func(t *testing.T) {
+		retried := client.BuildTimeline(7, []client.ChangeInstanceHistoryEntry{
+			historyEntry(client.ChangeInstancePENDING, created, "", ""),
+			historyEntry(client.ChangeInstanceERROR, created.Add(time.Hour), "boom", ""),
+			historyEntry(client.ChangeInstancePENDING, created.Add(90*time.Minute), "retry", ""),
+		}, created.Add(2*time.Hour))
+		assert.Equal(t, 90*time.Minute, retried.TimeIn(client.ChangeInstancePENDING))
+	})
+
+	assert.Empty(t, client.BuildTimeline(7, nil, created).Intervals)
+}
+
+func TestGetChangeInstanceTimeline(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/7/history/",
+		httpmock.NewStringResponder(200, changeInstanceHistory))
+
+	nc := newPackTestClient(t)
+	timeline, err := nc.GetChangeInstanceTimeline(context.Background(), client.POVServiceOwner, 7)
+	require.NoError(t, err)
+
+	require.Len(t, timeline.Intervals, 2)
+	assert.Equal(t, client.ChangeInstanceAPPROVED, timeline.Intervals[0].State)
+	assert.Equal(t, "AWS", timeline.Intervals[0].ChangedByTeam)
+	assert.Equal(t, "SYSTEM", timeline.Intervals[1].ChangedBy)
+
+	var entries []client.ChangeInstanceHistoryEntry
+	require.NoError(t, json.Unmarshal([]byte(changeInstanceHistory), &entries))
+	assert.Equal(t, entries[1].Modified, timeline.Created())
+}
@@ -0,0 +1,310 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// changeInstanceTransitions is the state machine the platform enforces on change instances,
+// keyed by the state a change is in and listing the states it may move to.
+//
+// A change moves forward through review (PENDING) and fulfilment (APPROVED) to one of the
+// outcomes. REJECTED and CLOSED are final, and a COMPLETED change can only be CLOSED - never
+// reopened. ERROR is the exception among the outcomes: it records a failed attempt, and
+// automation may retry from it - but only by going back through PENDING or APPROVED, since a
+// COMPLETED change must have been approved and ERROR does not say whether this one was.
+var changeInstanceTransitions = map[ChangeInstanceState][]ChangeInstanceState{
+	ChangeInstancePENDING: {
+		ChangeInstanceAPPROVED, ChangeInstanceREJECTED, ChangeInstanceERROR, ChangeInstanceCLOSED,
+	},
+	ChangeInstanceAPPROVED: {
+		ChangeInstanceCOMPLETED, ChangeInstanceERROR, ChangeInstanceCLOSED,
+	},
+	ChangeInstanceERROR: {
+		ChangeInstancePENDING, ChangeInstanceAPPROVED, ChangeInstanceREJECTED, ChangeInstanceCLOSED,
+	},
+	ChangeInstanceCOMPLETED: {ChangeInstanceCLOSED},
+	ChangeInstanceREJECTED:  nil,
+	ChangeInstanceCLOSED:    nil,
+}
+
+// AllowedTransitions returns the states a change instance in the given state may move to, with
+// the service's own restrictions applied. Pass a nil service to consult the table alone.
+//
+// It returns nil for a state nothing leaves, and for a state this package does not know - in
+// which case CanTransition defers to the server rather than refusing.
+func AllowedTransitions(from ChangeInstanceState, service *ChangeInstanceService) []ChangeInstanceState {
+	var allowed []ChangeInstanceState
+	for _, to := range changeInstanceTransitions[from] {
+		if serviceForbids(to, service) == "" {
+			allowed = append(allowed, to)
+		}
+	}
+	return allowed
+}
+
+// CanTransition reports whether a change instance may move from one state to another on the
+// given service. Pass a nil service to consult the table alone.
+//
+// Staying in the same state is always allowed: it is how a log is rewritten without moving the
+// change. A state this package does not know, on either side, is allowed too - the platform may
+// have grown states since this release, and the server remains the authority on them.
+func CanTransition(from, to ChangeInstanceState, service *ChangeInstanceService) bool {
+	return transitionError(0, from, to, service) == nil
+}
+
+// ValidateTransition reports whether the change instance may move to the given state, using the
+// state and service it carries. It returns an *InvalidTransitionError, which unwraps to
+// ErrInvalidTransition, when it may not.
+func ValidateTransition(ci *ChangeInstance, to ChangeInstanceState) error {
+	if err := transitionError(ci.ID, ci.State, to, &ci.Service); err != nil {
+		return err
+	}
+	return nil
+}
+
+// transitionError is the shared implementation of CanTransition and ValidateTransition. It
+// returns a typed nil-able pointer so each caller can decide how to surface it.
+func transitionError(id int, from, to ChangeInstanceState, service *ChangeInstanceService) *InvalidTransitionError {
+	if from == to || !from.IsKnown() || !to.IsKnown() {
+		return nil
+	}
+
+	refusal := &InvalidTransitionError{
+		ID:      id,
+		From:    from,
+		To:      to,
+		Allowed: AllowedTransitions(from, service),
+	}
+
+	inTable := false
+	for _, candidate := range changeInstanceTransitions[from] {
+		if candidate == to {
+			inTable = true
+			break
+		}
+	}
+	if !inTable {
+		return refusal
+	}
+
+	if reason := serviceForbids(to, service); reason != "" {
+		refusal.Reason = reason
+		return refusal
+	}
+	return nil
+}
+
+// serviceForbids returns why the service refuses a target state the table would allow, or ""
+// when it does not. A service that disallows manual approval or completion leaves APPROVED or
+// COMPLETED to the platform's own automation, so a client sending it only earns a 400.
+func serviceForbids(to ChangeInstanceState, service *ChangeInstanceService) string {
+	if service == nil {
+		return ""
+	}
+	switch {
+	case to == ChangeInstanceAPPROVED && !service.AllowManualApproval:
+		return "service " + service.Name + " does not allow manual approval"
+	case to == ChangeInstanceCOMPLETED && !service.AllowManualCompletion:
+		return "service " + service.Name + " does not allow manual completion"
+	}
+	return ""
+}
+
+// TransitionOption adjusts how UpdateChangeInstanceState and the state helpers built on it
+// perform a transition.
+type TransitionOption func(*transitionSettings)
+
+// transitionSettings collects the options passed to a single transition.
+type transitionSettings struct {
+	guarded bool
+	gates   []TransitionGate
+	log     *ChangeLog
+
+	deployedItem    json.RawMessage
+	deployedItemErr error
+}
+
+// newTransitionSettings applies the options in order.
+func newTransitionSettings(opts []TransitionOption) transitionSettings {
+	var settings transitionSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	return settings
+}
+
+// Guarded makes a transition check itself before it is sent. The change instance is fetched
+// first and its current state and service run through the state machine; an illegal move
+// returns an *InvalidTransitionError naming the allowed targets, and nothing is written.
+//
+// It costs one extra request, and the check is advisory rather than atomic - another writer can
+// still move the change between the read and the write, in which case the server's own
+// validation is what catches it.
+func Guarded() TransitionOption {
+	return func(settings *transitionSettings) {
+		settings.guarded = true
+	}
+}
+
+// TransitionGate decides whether a transition may go ahead now. It returns nil to let it
+// through, a *DeferredTransitionError to hold it back until later, or any other error to refuse
+// it outright. Gates see only the target state, so they suit rules about when a change may move
+// - change windows and freeze periods - rather than rules about the change itself.
+type TransitionGate func(ctx context.Context, id int, to ChangeInstanceState) error
+
+// WithGate has a transition consult gate before it is sent. A deferral is not silent: the
+// gate's reason is appended to the change's log, leaving its state as it was, and the
+// *DeferredTransitionError is returned. A deferral the log already ends with is not written
+// again, so a move retried while its gate stays closed is recorded once. Any other error from
+// the gate is returned with nothing written. Several gates may be given; the first to object
+// wins. With Guarded too, the move is checked before any gate is asked, so an illegal move is
+// refused without a deferral ever reaching the log.
+func WithGate(gate TransitionGate) TransitionOption {
+	return func(settings *transitionSettings) {
+		settings.gates = append(settings.gates, gate)
+	}
+}
+
+// gateTransition runs the WithGate gates, recording a deferral in the change's log.
+func (c *Client) gateTransition(
+	ctx context.Context,
+	pov POV,
+	id int,
+	to ChangeInstanceState,
+	gates []TransitionGate,
+) error {
+	for _, gate := range gates {
+		err := gate(ctx, id, to)
+		if err == nil {
+			continue
+		}
+
+		var deferred *DeferredTransitionError
+		if errors.As(err, &deferred) {
+			if logErr := c.recordDeferral(ctx, pov, id, to, deferred.Reason); logErr != nil {
+				return errors.Join(err, fmt.Errorf("failed to record the deferral: %w", logErr))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// RecordDeferral appends a gate's deferral to the change's log as WithGate does - an INFO entry
+// reading "COMPLETED deferred: outside the change windows" - leaving its state alone. A
+// deferral the log already ends with is not written again, so a change held back poll after
+// poll is recorded once. It is for callers that consult a gate themselves, before doing the
+// work the move would report.
+func (c *Client) RecordDeferral(ctx context.Context, pov POV, deferral *DeferredTransitionError) error {
+	return c.recordDeferral(ctx, pov, deferral.ID, deferral.To, deferral.Reason)
+}
+
+// recordDeferral is RecordDeferral for a move of change id to the given state.
+func (c *Client) recordDeferral(ctx context.Context, pov POV, id int, to ChangeInstanceState, reason string) error {
+	logStr := fmt.Sprintf("%s deferred: %s", to, reason)
+	current, err := c.GetChangeInstance(ctx, pov, id)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(strings.TrimRight(current.Log, "\n"), fmt.Sprintf(" %s %s", LogInfo, logStr)) {
+		return nil
+	}
+	settings := newTransitionSettings([]TransitionOption{AppendLog(NewChangeLog())})
+	_, err = c.writeTransition(ctx, pov, id, current.State, logStr, nil, settings, current)
+	return err
+}
+
+// AppendLog has a transition add log's entries to the change's log instead of replacing it. The
+// change is fetched first - once, if Guarded is given too - and its log, the rendered entries and
+// the helper's own log string, as a final INFO entry when it is not empty, are written back
+// together. A log whose entries fail to parse or render stops the transition before it is sent.
+//
+// The read and the write are not atomic: an entry another writer adds in between is lost.
+func AppendLog(log *ChangeLog) TransitionOption {
+	return func(settings *transitionSettings) {
+		settings.log = log
+	}
+}
+
+// WithDeployedItem records value, encoded as JSON, as the transition's deployed item - the typed
+// alternative to the helpers' raw deployedItem argument, which must then be nil:
+//
+//	_, err := nc.CompleteChangeInstanceWithContext(ctx, pov, id, "built", nil, client.WithDeployedItem(vm))
+//
+// A value that does not encode stops the transition before anything is sent.
+func WithDeployedItem[T any](value T) TransitionOption {
+	raw, err := json.Marshal(value)
+	return func(settings *transitionSettings) {
+		settings.deployedItem, settings.deployedItemErr = raw, err
+		if err != nil {
+			settings.deployedItemErr = fmt.Errorf("failed to encode the deployed item: %w", err)
+		}
+	}
+}
+
+// resolveDeployedItem reconciles the raw deployed item argument with WithDeployedItem.
+func (s *transitionSettings) resolveDeployedItem(deployedItem json.RawMessage) (json.RawMessage, error) {
+	switch {
+	case s.deployedItemErr != nil:
+		return nil, s.deployedItemErr
+	case s.deployedItem == nil:
+		return deployedItem, nil
+	case deployedItem != nil:
+		return nil, errors.New("the deployed item is set twice: pass it raw or with WithDeployedItem, not both")
+	}
+	return s.deployedItem, nil
+}
+
+// writeTransition sends a transition that has passed its gates. current is the change as read
+// beforehand, or nil to have it read here if the Guarded or AppendLog options need it.
+func (c *Client) writeTransition(
+	ctx context.Context,
+	pov POV,
+	id int,
+	state ChangeInstanceState,
+	logStr string,
+	deployedItem json.RawMessage,
+	settings transitionSettings,
+	current *ChangeInstance,
+) (*ChangeInstance, error) {
+	if current == nil && (settings.guarded || settings.log != nil) {
+		var err error
+		if current, err = c.GetChangeInstance(ctx, pov, id); err != nil {
+			return nil, err
+		}
+	}
+	if settings.guarded {
+		if err := ValidateTransition(current, state); err != nil {
+			return nil, err
+		}
+	}
+	if settings.log != nil {
+		log := *settings.log
+		if logStr != "" {
+			log.entries = append(log.entries[:len(log.entries):len(log.entries)],
+				changeLogEntry{at: log.now(), level: LogInfo, message: logStr})
+		}
+		var err error
+		if logStr, err = log.Render(current); err != nil {
+			return nil, err
+		}
+	}
+
+	endpoint := fmt.Sprintf("orcabase/%s/change_instances/%d/", pov.orDefault(), id)
+
+	body := UpdateChangeInstanceRequest{
+		State:        state,
+		Log:          logStr,
+		DeployedItem: deployedItem,
+	}
+
+	var response ChangeInstance
+	if err := c.doRequest(ctx, "PATCH", endpoint, body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,267 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCanTransition walks the table through the moves that matter in practice: the forward path,
+// the retry path out of ERROR, and the final states that cannot be reopened.
+func TestCanTransition(t *testing.T) {
+	manual := &client.ChangeInstanceService{Name: "vm", AllowManualApproval: true, AllowManualCompletion: true}
+
+	tests := []struct {
+		name    string
+		from    client.ChangeInstanceState
+		to      client.ChangeInstanceState
+		service *client.ChangeInstanceService
+		want    bool
+	}{
+		{"approve a pending change", client.ChangeInstancePENDING, client.ChangeInstanceAPPROVED, manual, true},
+		{"complete an approved change", client.ChangeInstanceAPPROVED, client.ChangeInstanceCOMPLETED, manual, true},
+		{"retry from error", client.ChangeInstanceERROR, client.ChangeInstancePENDING, manual, true},
+		{"complete straight out of error", client.ChangeInstanceERROR, client.ChangeInstanceCOMPLETED, manual, false},
+		{"complete a pending change", client.ChangeInstancePENDING, client.ChangeInstanceCOMPLETED, manual, false},
+		{"reopen a completed change", client.ChangeInstanceCOMPLETED, client.ChangeInstancePENDING, manual, false},
+		{"close a completed change", client.ChangeInstanceCOMPLETED, client.ChangeInstanceCLOSED, manual, true},
+		{"reopen a rejected change", client.ChangeInstanceREJECTED, client.ChangeInstanceAPPROVED, manual, false},
+		{"rewrite the log in place", client.ChangeInstanceCOMPLETED, client.ChangeInstanceCOMPLETED, manual, true},
+		{"manual approval disallowed", client.ChangeInstancePENDING, client.ChangeInstanceAPPROVED,
+			&client.ChangeInstanceService{Name: "vm"}, false},
+		{"manual completion disallowed", client.ChangeInstanceAPPROVED, client.ChangeInstanceCOMPLETED,
+			&client.ChangeInstanceService{Name: "vm", AllowManualApproval: true}, false},
+		{"no service consults the table alone", client.ChangeInstancePENDING, client.ChangeInstanceAPPROVED,
+			nil, true},
+		{"unknown state defers to the server", client.ChangeInstanceState("ON_HOLD"), client.ChangeInstanceAPPROVED,
+			manual, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, client.CanTransition(tt.from, tt.to, tt.service))
+		})
+	}
+}
+
+func TestAllowedTransitions(t *testing.T) {
+	assert.Equal(t,
+		[]client.ChangeInstanceState{
+			client.ChangeInstanceAPPROVED, client.ChangeInstanceREJECTED,
+			client.ChangeInstanceERROR, client.ChangeInstanceCLOSED,
+		},
+		client.AllowedTransitions(client.ChangeInstancePENDING, nil))
+
+	// a service without manual approval drops APPROVED from the list
+	assert.Equal(t,
+		[]client.ChangeInstanceState{
+			client.ChangeInstanceREJECTED, client.ChangeInstanceERROR, client.ChangeInstanceCLOSED,
+		},
+		client.AllowedTransitions(client.ChangeInstancePENDING, &client.ChangeInstanceService{}))
+
+	assert.Empty(t, client.AllowedTransitions(client.ChangeInstanceCLOSED, nil))
+}
+
+func TestValidateTransition(t *testing.T) {
+	ci := &client.ChangeInstance{
+		ID:      53,
+		State:   client.ChangeInstanceCOMPLETED,
+		Service: client.ChangeInstanceService{Name: "vm", AllowManualApproval: true},
+	}
+
+	err := client.ValidateTransition(ci, client.ChangeInstancePENDING)
+	require.Error(t, err)
+	require.ErrorIs(t, err, client.ErrInvalidTransition)
+
+	var transitionErr *client.InvalidTransitionError
+	require.ErrorAs(t, err, &transitionErr)
+	assert.Equal(t, 53, transitionErr.ID)
+	assert.Equal(t, []client.ChangeInstanceState{client.ChangeInstanceCLOSED}, transitionErr.Allowed)
+	assert.EqualError(t, err, "netorca: change instance 53 cannot move from COMPLETED to PENDING (allowed: CLOSED)")
+
+	ci.State = client.ChangeInstancePENDING
+	ci.Service.AllowManualApproval = false
+	err = client.ValidateTransition(ci, client.ChangeInstanceAPPROVED)
+	require.ErrorIs(t, err, client.ErrInvalidTransition)
+	assert.EqualError(t, err, "netorca: change instance 53 cannot move from PENDING to APPROVED "+
+		"(allowed: REJECTED, ERROR, CLOSED): service vm does not allow manual approval")
+
+	require.NoError(t, client.ValidateTransition(ci, client.ChangeInstanceREJECTED))
+}
+
+// TestGuardedTransition checks the guarded mode reads before it writes, and that a refused move
+// never reaches the PATCH.
+func TestGuardedTransition(t *testing.T) {
+	const detailURL = packTestBaseURL + "/v1/orcabase/serviceowner/change_instances/53/"
+	const completed = `{"id":53,"state":"COMPLETED","service":{"id":1,"name":"vm","allow_manual_approval":true,` +
+		`"allow_manual_completion":true}}`
+	const approved = `{"id":53,"state":"APPROVED","service":{"id":1,"name":"vm","allow_manual_approval":true,` +
+		`"allow_manual_completion":true}}`
+
+	t.Run("refuses an illegal move without writing", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200, completed))
+
+		nc := newPackTestClient(t)
+		_, err := nc.PendingChangeInstanceWithContext(
+			context.Background(), client.POVServiceOwner, 53, "retry", nil, client.Guarded(),
+		)
+
+		var transitionErr *client.InvalidTransitionError
+		require.True(t, errors.As(err, &transitionErr))
+		assert.Equal(t, client.ChangeInstanceCOMPLETED, transitionErr.From)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("sends a legal move", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var capturedBody string
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200, approved))
+		httpmock.RegisterResponder("PATCH", detailURL,
+			captureBodyResponder(&capturedBody, 200, `{"id":53,"state":"COMPLETED"}`))
+
+		nc := newPackTestClient(t)
+		ci, err := nc.CompleteChangeInstanceWithContext(
+			context.Background(), client.POVServiceOwner, 53, "done", nil, client.Guarded(),
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, client.ChangeInstanceCOMPLETED, ci.State)
+		assert.JSONEq(t, `{"state":"COMPLETED","log":"done"}`, capturedBody)
+		assert.Equal(t, 2, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("unguarded skips the read", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		httpmock.RegisterResponder("PATCH", detailURL,
+			httpmock.NewStringResponder(200, `{"id":53,"state":"PENDING"}`))
+
+		nc := newPackTestClient(t)
+		_, err := nc.PendingChangeInstanceWithContext(context.Background(), client.POVServiceOwner, 53, "", nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount())
+	})
+}
+
+// TestWithGate checks a deferring gate leaves the state alone but records why, and that any other
+// gate error stops the move before a request is made.
+func TestWithGate(t *testing.T) {
+	const detailURL = packTestBaseURL + "/v1/orcabase/serviceowner/change_instances/53/"
+	const approved = `{"id":53,"state":"APPROVED"}`
+	deferring := func(_ context.Context, id int, to client.ChangeInstanceState) error {
+		return &client.DeferredTransitionError{ID: id, To: to, Reason: "outside the change windows"}
+	}
+
+	t.Run("appends a deferral to the log", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var capturedBody string
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200,
+			`{"id":53,"state":"APPROVED","log":"2026-10-18T09:00:00Z INFO approved by the CAB"}`))
+		httpmock.RegisterResponder("PATCH", detailURL, captureBodyResponder(&capturedBody, 200, approved))
+
+		nc := newPackTestClient(t)
+		_, err := nc.CompleteChangeInstanceWithContext(
+			context.Background(), client.POVServiceOwner, 53, "done", nil, client.WithGate(deferring),
+		)
+
+		require.ErrorIs(t, err, client.ErrTransitionDeferred)
+		assert.EqualError(t, err,
+			"netorca: change instance 53: move to COMPLETED deferred: outside the change windows")
+		var body struct {
+			State string `json:"state"`
+			Log   string `json:"log"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(capturedBody), &body))
+		assert.Equal(t, "APPROVED", body.State)
+		assert.Regexp(t, `^2026-10-18T09:00:00Z INFO approved by the CAB\n`+
+			`\S+ INFO COMPLETED deferred: outside the change windows$`, body.Log,
+			"the existing log survives the deferral")
+	})
+
+	t.Run("records a repeated deferral once", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200,
+			`{"id":53,"state":"APPROVED","log":"2026-10-18T09:00:00Z INFO approved by the CAB\n`+
+				`2026-10-18T09:05:00Z INFO COMPLETED deferred: outside the change windows"}`))
+
+		nc := newPackTestClient(t)
+		_, err := nc.CompleteChangeInstanceWithContext(
+			context.Background(), client.POVServiceOwner, 53, "done", nil, client.WithGate(deferring),
+		)
+
+		require.ErrorIs(t, err, client.ErrTransitionDeferred)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount(), "the log is read but not written")
+	})
+
+	t.Run("a guarded illegal move is refused before the gate is asked", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		httpmock.RegisterResponder("GET", detailURL, httpmock.NewStringResponder(200, `{"id":53,"state":"PENDING"}`))
+
+		asked := false
+		nc := newPackTestClient(t)
+		_, err := nc.CompleteChangeInstanceWithContext(
+			context.Background(), client.POVServiceOwner, 53, "done", nil, client.Guarded(),
+			client.WithGate(func(ctx context.Context, id int, to client.ChangeInstanceState) error {
+				asked = true
+				return deferring(ctx, id, to)
+			}),
+		)
+
+		require.ErrorIs(t, err, client.ErrInvalidTransition)
+		assert.False(t, asked)
+		assert.Equal(t, 1, httpmock.GetTotalCallCount(), "no deferral is written")
+	})
+
+	t.Run("stops on other gate errors", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		gateErr := errors.New("calendar unavailable")
+		nc := newPackTestClient(t)
+		_, err := nc.CompleteChangeInstanceWithContext(
+			context.Background(), client.POVServiceOwner, 53, "done", nil,
+			client.WithGate(func(context.Context, int, client.ChangeInstanceState) error { return gateErr }),
+		)
+
+		require.ErrorIs(t, err, gateErr)
+		assert.Zero(t, httpmock.GetTotalCallCount())
+	})
+
+	t.Run("an open gate lets the move through", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var capturedBody string
+		httpmock.RegisterResponder("PATCH", detailURL,
+			captureBodyResponder(&capturedBody, 200, `{"id":53,"state":"COMPLETED"}`))
+
+		nc := newPackTestClient(t)
+		ci, err := nc.CompleteChangeInstanceWithContext(
+			context.Background(), client.POVServiceOwner, 53, "done", nil,
+			client.WithGate(func(context.Context, int, client.ChangeInstanceState) error { return nil }),
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, client.ChangeInstanceCOMPLETED, ci.State)
+		assert.JSONEq(t, `{"state":"COMPLETED","log":"done"}`, capturedBody)
+	})
+}
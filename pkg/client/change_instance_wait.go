@@ -0,0 +1,298 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SubmissionRef names the submission a consumer made: by the commit id their pipeline pushed,
+// or by the submission's own id. Set one.
+type SubmissionRef struct {
+	// CommitID is the commit the submission was made from.
+	CommitID string
+	// SubmissionID is the submission's id.
+	SubmissionID int
+}
+
+// String describes the reference for messages.
+func (r SubmissionRef) String() string {
+	if r.CommitID != "" {
+		return "commit " + r.CommitID
+	}
+	return fmt.Sprintf("submission %d", r.SubmissionID)
+}
+
+// WaitOptions tunes WaitForChangeInstances. The zero value (or nil) waits as the consumer,
+// polling every 15 seconds, and gives the platform two minutes to raise the first change.
+type WaitOptions struct {
+	// POV is the point of view to list from. Defaults to the consumer, who made the submission.
+	POV POV
+	// Interval is how long to wait between polls. Defaults to 15 seconds.
+	Interval time.Duration
+	// AppearTimeout is how long to wait for the submission's first change instance to appear.
+	// A submission that changed nothing raises none, so the wait ends empty-handed after it.
+	// Defaults to two minutes.
+	AppearTimeout time.Duration
+	// Progress, when set, is called after every poll with the changes as they stand - for a CI
+	// log line, say. It runs on the waiting goroutine, so it should not block.
+	Progress func(WaitProgress)
+}
+
+// Validate checks the options are usable.
+func (o *WaitOptions) Validate() error {
+	if o.POV != "" {
+		if err := o.POV.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.Interval < 0 {
+		return errors.New("wait interval cannot be negative")
+	}
+	if o.AppearTimeout < 0 {
+		return errors.New("wait appear timeout cannot be negative")
+	}
+	return nil
+}
+
+// orDefault returns the options with defaults filled in.
+func (o *WaitOptions) orDefault() WaitOptions {
+	opts := *o
+	if opts.POV == "" {
+		opts.POV = POVConsumer
+	}
+	if opts.Interval == 0 {
+		opts.Interval = 15 * time.Second
+	}
+	if opts.AppearTimeout == 0 {
+		opts.AppearTimeout = 2 * time.Minute
+	}
+	return opts
+}
+
+// WaitProgress is what a WaitOptions.Progress callback sees after each poll.
+type WaitProgress struct {
+	// Poll counts the polls made so far, from 1.
+	Poll int
+	// Elapsed is the time since the wait started.
+	Elapsed time.Duration
+	// Total is the number of change instances the submission has raised so far.
+	Total int
+	// Finished is how many of them have reached a final state.
+	Finished int
+	// States counts the changes in each state.
+	States map[ChangeInstanceState]int
+}
+
+// String renders the progress as one line: "3/5 finished (APPROVED 2, COMPLETED 3)".
+func (p WaitProgress) String() string {
+	states := make([]string, 0, len(p.States))
+	for state, n := range p.States {
+		states = append(states, fmt.Sprintf("%s %d", state, n))
+	}
+	sort.Strings(states)
+	if len(states) == 0 {
+		return fmt.Sprintf("%d/%d finished", p.Finished, p.Total)
+	}
+	return fmt.Sprintf("%d/%d finished (%s)", p.Finished, p.Total, strings.Join(states, ", "))
+}
+
+// WaitFailure is a change instance a wait saw end badly.
+type WaitFailure struct {
+	// ID is the change instance.
+	ID int
+	// Service is the service it was for.
+	Service string
+	// ServiceItem is the name of the service item it would have changed.
+	ServiceItem string
+	// State is REJECTED or ERROR.
+	State ChangeInstanceState
+	// Log is the service owner's explanation.
+	Log string
+}
+
+// WaitResult is what WaitForChangeInstances found.
+type WaitResult struct {
+	// Ref is the submission waited on.
+	Ref SubmissionRef
+	// ChangeInstances are the submission's changes as last seen, by id.
+	ChangeInstances []ChangeInstance
+	// States counts the changes in each state.
+	States map[ChangeInstanceState]int
+	// Failures are the changes that ended REJECTED or ERROR, with the owner's log.
+	Failures []WaitFailure
+	// Finished is set when every change reached a final state and a poll found no change the one
+	// before it had not, and when the submission raised none before AppearTimeout. It is unset
+	// for a wait cut short by its context.
+	Finished bool
+	// Polls is the number of polls made.
+	Polls int
+	// Elapsed is how long the wait took.
+	Elapsed time.Duration
+}
+
+// Succeeded reports whether the submission raised at least one change and every change
+// finished without being rejected or failing. A submission that raised none before AppearTimeout
+// has not succeeded - the ref may be wrong, or the platform may not have picked it up - though
+// its wait is Finished; RaisedNone tells that case apart.
+func (r *WaitResult) Succeeded() bool {
+	return r.Finished && len(r.ChangeInstances) > 0 && len(r.Failures) == 0
+}
+
+// RaisedNone reports whether the wait ended because no change appeared before AppearTimeout.
+func (r *WaitResult) RaisedNone() bool {
+	return r.Finished && len(r.ChangeInstances) == 0
+}
+
+// Summary renders the result for a CI log, one line per failure after the headline.
+func (r *WaitResult) Summary() string {
+	var b strings.Builder
+	switch {
+	case r.RaisedNone():
+		fmt.Fprintf(&b, "%s raised no change instances", r.Ref)
+	case !r.Finished:
+		fmt.Fprintf(&b, "%s: gave up waiting on %d change instances", r.Ref, len(r.ChangeInstances))
+	case len(r.Failures) == 0:
+		fmt.Fprintf(&b, "%s: all %d change instances finished", r.Ref, len(r.ChangeInstances))
+	default:
+		fmt.Fprintf(&b, "%s: %d of %d change instances failed", r.Ref, len(r.Failures), len(r.ChangeInstances))
+	}
+	for _, failure := range r.Failures {
+		fmt.Fprintf(&b, "\n  change instance %d (%s %s) %s: %s",
+			failure.ID, failure.Service, failure.ServiceItem, failure.State, failure.Log)
+	}
+	return b.String()
+}
+
+// changeInstanceFinished reports whether a wait is over for a change: COMPLETED, REJECTED,
+// CLOSED or ERROR. ERROR is not final for the service owner, who may retry, but it is an answer
+// for the consumer waiting on it.
+func changeInstanceFinished(state ChangeInstanceState) bool {
+	switch state {
+	case ChangeInstanceCOMPLETED, ChangeInstanceREJECTED, ChangeInstanceCLOSED, ChangeInstanceERROR:
+		return true
+	}
+	return false
+}
+
+// WaitForChangeInstances follows the change instances a submission raised until each has
+// reached COMPLETED, REJECTED, CLOSED or ERROR, and reports how they ended - with the service
+// owner's log for every rejection and error - so a consumer's pipeline can pass or fail on it.
+//
+// The platform raises changes shortly after a submission is made, so the wait allows
+// AppearTimeout for the first to show. It raises them one at a time, too, so the first changes
+// can all be final before the last has appeared: the wait is only over once a poll turns up no
+// change the previous one had not seen, which costs one more interval after the last change
+// finishes. Polls that fail with ErrServerUnavailable are retried at
+// the next interval; any other error ends the wait. When the context ends first, the result
+// holds the changes as last seen and the error wraps the context's.
+//
+// It returns an error without polling for an empty reference or invalid options.
+func (c *Client) WaitForChangeInstances(
+	ctx context.Context,
+	ref SubmissionRef,
+	opts *WaitOptions,
+) (*WaitResult, error) {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if ref.CommitID == "" && ref.SubmissionID <= 0 {
+		return nil, errors.New("wait for change instances: a commit id or submission id is required")
+	}
+	settings := opts.orDefault()
+
+	filters := &GetChangeInstancesRequest{POV: settings.POV, CommitID: ref.CommitID}
+	if ref.SubmissionID > 0 {
+		filters.SubmissionIDs = []int{ref.SubmissionID}
+	}
+
+	start := time.Now()
+	result := &WaitResult{Ref: ref}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			result.Elapsed = time.Since(start)
+			return result, fmt.Errorf("stopped waiting on %s: %w", ref, ctx.Err())
+		case <-timer.C:
+		}
+
+		changes, err := c.listAllChangeInstances(ctx, filters, "")
+		switch {
+		case err == nil:
+			result.Polls++
+			if result.record(changes) {
+				result.Finished = false // new changes may still be arriving
+			}
+		case errors.Is(err, ErrServerUnavailable) && ctx.Err() == nil:
+			timer.Reset(settings.Interval)
+			continue
+		case ctx.Err() != nil:
+			continue // reported by the select
+		default:
+			result.Elapsed = time.Since(start)
+			return result, fmt.Errorf("failed to poll %s: %w", ref, err)
+		}
+		result.Elapsed = time.Since(start)
+
+		if settings.Progress != nil {
+			settings.Progress(result.progress())
+		}
+		if result.Finished || (len(changes) == 0 && result.Elapsed >= settings.AppearTimeout) {
+			result.Finished = true
+			return result, nil
+		}
+		timer.Reset(settings.Interval)
+	}
+}
+
+// record takes in a poll's changes, setting Finished when every one is final, and reports
+// whether any of them is new since the last poll.
+func (r *WaitResult) record(changes []ChangeInstance) bool {
+	known := make(map[int]bool, len(r.ChangeInstances))
+	for _, ci := range r.ChangeInstances {
+		known[ci.ID] = true
+	}
+	grew := false
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	r.ChangeInstances = changes
+	r.States = map[ChangeInstanceState]int{}
+	r.Failures = nil
+	r.Finished = len(changes) > 0
+	for _, ci := range changes {
+		grew = grew || !known[ci.ID]
+		r.States[ci.State]++
+		if !changeInstanceFinished(ci.State) {
+			r.Finished = false
+		}
+		if ci.State == ChangeInstanceREJECTED || ci.State == ChangeInstanceERROR {
+			r.Failures = append(r.Failures, WaitFailure{
+				ID:          ci.ID,
+				Service:     ci.Service.Name,
+				ServiceItem: ci.ServiceItem.Name,
+				State:       ci.State,
+				Log:         ci.Log,
+			})
+		}
+	}
+	return grew
+}
+
+// progress describes the latest poll.
+func (r *WaitResult) progress() WaitProgress {
+	progress := WaitProgress{Poll: r.Polls, Elapsed: r.Elapsed, Total: len(r.ChangeInstances), States: r.States}
+	for state, n := range r.States {
+		if changeInstanceFinished(state) {
+			progress.Finished += n
+		}
+	}
+	return progress
+}
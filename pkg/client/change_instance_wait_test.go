@@ -0,0 +1,156 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const consumerChangeInstances = packTestBaseURL + "/v1/orcabase/consumer/change_instances/"
+
+// pollSequence answers successive polls with the given bodies, repeating the last.
+func pollSequence(queries *[]string, bodies ...string) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		*queries = append(*queries, req.URL.RawQuery)
+		body := bodies[min(len(*queries), len(bodies))-1]
+		if body == "" {
+			return httpmock.NewStringResponse(503, "try later"), nil
+		}
+		return httpmock.NewStringResponse(200, body), nil
+	}
+}
+
+func TestWaitForChangeInstances(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var queries []string
+	httpmock.RegisterResponder("GET", consumerChangeInstances, pollSequence(&queries,
+		`{"count":0,"next":null,"results":[]}`,
+		`{"count":2,"next":null,"results":[{"id":2,"state":"APPROVED"},{"id":1,"state":"PENDING"}]}`,
+		"",
+		`{"count":2,"next":null,"results":[{"id":1,"state":"COMPLETED"},`+
+			`{"id":2,"state":"REJECTED","log":"no public buckets","service":{"name":"bucket"},`+
+			`"service_item":{"name":"assets"}}]}`,
+	))
+
+	var progress []string
+	nc := newPackTestClient(t)
+	result, err := nc.WaitForChangeInstances(context.Background(), client.SubmissionRef{CommitID: "abc123"},
+		&client.WaitOptions{
+			Interval: time.Millisecond,
+			Progress: func(p client.WaitProgress) { progress = append(progress, p.String()) },
+		})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"commit_id=abc123&limit=100"}, queries[:1])
+	assert.Len(t, queries, 4, "the unavailable poll is retried")
+	assert.Equal(t, []string{
+		"0/0 finished",
+		"0/2 finished (APPROVED 1, PENDING 1)",
+		"2/2 finished (COMPLETED 1, REJECTED 1)",
+	}, progress)
+
+	assert.True(t, result.Finished)
+	assert.False(t, result.Succeeded())
+	assert.Equal(t, 3, result.Polls)
+	assert.Equal(t, map[client.ChangeInstanceState]int{
+		client.ChangeInstanceCOMPLETED: 1, client.ChangeInstanceREJECTED: 1,
+	}, result.States)
+	assert.Equal(t, "commit abc123: 1 of 2 change instances failed\n"+
+		"  change instance 2 (bucket assets) REJECTED: no public buckets", result.Summary())
+}
+
+// TestWaitForChangeInstancesSettles has the first change finish before the platform raises the
+// second, which the wait must not mistake for the end of the submission.
+func TestWaitForChangeInstancesSettles(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var queries []string
+	httpmock.RegisterResponder("GET", consumerChangeInstances, pollSequence(&queries,
+		`{"count":1,"next":null,"results":[{"id":1,"state":"COMPLETED"}]}`,
+		`{"count":2,"next":null,"results":[{"id":1,"state":"COMPLETED"},{"id":2,"state":"PENDING"}]}`,
+		`{"count":2,"next":null,"results":[{"id":1,"state":"COMPLETED"},{"id":2,"state":"COMPLETED"}]}`,
+	))
+
+	nc := newPackTestClient(t)
+	result, err := nc.WaitForChangeInstances(context.Background(), client.SubmissionRef{CommitID: "abc123"},
+		&client.WaitOptions{Interval: time.Millisecond})
+	require.NoError(t, err)
+
+	assert.True(t, result.Succeeded())
+	assert.Equal(t, 3, result.Polls, "a poll that turns up a new change is never the last")
+	assert.Len(t, result.ChangeInstances, 2)
+}
+
+func TestWaitForChangeInstancesEnds(t *testing.T) {
+	t.Run("a submission that raises nothing", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var queries []string
+		httpmock.RegisterResponder("GET", consumerChangeInstances,
+			pollSequence(&queries, `{"count":0,"next":null,"results":[]}`))
+
+		nc := newPackTestClient(t)
+		result, err := nc.WaitForChangeInstances(context.Background(), client.SubmissionRef{SubmissionID: 5},
+			&client.WaitOptions{Interval: time.Millisecond, AppearTimeout: 5 * time.Millisecond})
+		require.NoError(t, err)
+
+		assert.Equal(t, "limit=100&submission_id=5", queries[0])
+		assert.True(t, result.Finished)
+		assert.True(t, result.RaisedNone())
+		assert.False(t, result.Succeeded(), "nothing raised is not a success")
+		assert.Equal(t, "submission 5 raised no change instances", result.Summary())
+	})
+
+	t.Run("the context ends first", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var queries []string
+		httpmock.RegisterResponder("GET", consumerChangeInstances,
+			pollSequence(&queries, `{"count":1,"next":null,"results":[{"id":1,"state":"PENDING"}]}`))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		nc := newPackTestClient(t)
+		result, err := nc.WaitForChangeInstances(ctx, client.SubmissionRef{SubmissionID: 5},
+			&client.WaitOptions{Interval: time.Millisecond})
+
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotNil(t, result)
+		assert.False(t, result.Finished)
+		assert.Len(t, result.ChangeInstances, 1)
+		assert.Equal(t, "submission 5: gave up waiting on 1 change instances", result.Summary())
+	})
+
+	t.Run("other errors end the wait", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		httpmock.RegisterResponder("GET", consumerChangeInstances, httpmock.NewStringResponder(403, "no"))
+
+		nc := newPackTestClient(t)
+		_, err := nc.WaitForChangeInstances(context.Background(), client.SubmissionRef{CommitID: "abc123"}, nil)
+		require.ErrorIs(t, err, client.ErrForbidden)
+		assert.ErrorContains(t, err, "failed to poll commit abc123")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		nc := newPackTestClient(t)
+		_, err := nc.WaitForChangeInstances(context.Background(), client.SubmissionRef{}, nil)
+		require.ErrorContains(t, err, "a commit id or submission id is required")
+
+		_, err = nc.WaitForChangeInstances(context.Background(), client.SubmissionRef{SubmissionID: 5},
+			&client.WaitOptions{Interval: -time.Second})
+		require.ErrorContains(t, err, "wait interval cannot be negative")
+	})
+}
@@ -0,0 +1,372 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"time"
+)
+
+// ChangeInstanceEventType names what a watch saw happen to a change instance.
+type ChangeInstanceEventType string
+
+const (
+	// ChangeInstanceCreated is a change instance raised after the watch's checkpoint.
+	ChangeInstanceCreated ChangeInstanceEventType = "created"
+	// ChangeInstanceStateChanged is a change instance that moved to a new state.
+	ChangeInstanceStateChanged ChangeInstanceEventType = "state_changed"
+	// ChangeInstanceWatchError is a poll that failed. The watch carries on with the next one.
+	ChangeInstanceWatchError ChangeInstanceEventType = "error"
+)
+
+// ChangeInstanceEvent is one event from WatchChangeInstances.
+type ChangeInstanceEvent struct {
+	// Type is what happened.
+	Type ChangeInstanceEventType
+	// ChangeInstance is the change as the poll saw it. Zero for an error event.
+	ChangeInstance ChangeInstance
+	// OldState is the state the change moved from. It is empty for a created event, and for a
+	// change the watch had not seen before - one raised before the checkpoint, say - since then
+	// the previous state is not known.
+	OldState ChangeInstanceState
+	// NewState is the state the change moved to.
+	NewState ChangeInstanceState
+	// Checkpoint is where to resume a later watch so that it starts just after this event.
+	// Persist it once the event is handled.
+	Checkpoint WatchCheckpoint
+	// Err is why the poll failed, for an error event.
+	Err error
+}
+
+// WatchCheckpoint records how far a watch has got, so a restarted process can resume without
+// missing or repeating events. It encodes to JSON for storage:
+//
+//	{"since": "2026-10-18T09:00:00Z", "known": {"1": {"state": "APPROVED", "modified": "..."}}}
+//
+// Checkpoints handed out with events share what they know with each other rather than each
+// carrying a copy, so holding on to them is cheap; Known materialises it.
+type WatchCheckpoint struct {
+	// Since is the newest modification time the watch has seen. The next poll asks for changes
+	// modified at or after it.
+	Since time.Time
+
+	// known is what the watch knew when the poll that produced this checkpoint began, and
+	// recent what that poll had observed by the time of the event, oldest first. Neither is
+	// modified once handed out: later polls build new maps, and recent is only ever appended to.
+	known  map[int]WatchedChange
+	recent []observedChange
+}
+
+// WatchedChange is the state a watch last saw a change instance in.
+type WatchedChange struct {
+	State    ChangeInstanceState `json:"state"`
+	Modified time.Time           `json:"modified"`
+}
+
+// observedChange is a change a poll observed, by id.
+type observedChange struct {
+	id int
+	WatchedChange
+}
+
+// watchCheckpointJSON is the stored form of a WatchCheckpoint.
+type watchCheckpointJSON struct {
+	Since time.Time             `json:"since"`
+	Known map[int]WatchedChange `json:"known,omitempty"`
+}
+
+// Known returns the state the watch last saw each change instance in, by id - how the old state
+// of a transition is known and a change re-listed unmoved is not repeated. Changes in a state
+// nothing leaves are dropped once the watch has moved past them, and every change is dropped
+// once it has gone unmodified for the watch's retention period. The map is a copy.
+func (cp WatchCheckpoint) Known() map[int]WatchedChange {
+	known := maps.Clone(cp.known)
+	if known == nil {
+		known = map[int]WatchedChange{}
+	}
+	for _, change := range cp.recent {
+		known[change.id] = change.WatchedChange
+	}
+	return known
+}
+
+// MarshalJSON encodes the checkpoint for storage.
+func (cp WatchCheckpoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(watchCheckpointJSON{Since: cp.Since, Known: cp.Known()})
+}
+
+// UnmarshalJSON decodes a stored checkpoint.
+func (cp *WatchCheckpoint) UnmarshalJSON(data []byte) error {
+	var stored watchCheckpointJSON
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	*cp = WatchCheckpoint{Since: stored.Since, known: stored.Known}
+	return nil
+}
+
+// DefaultWatchRetention is how long a watch remembers a change instance that has not been
+// modified, unless told otherwise.
+const DefaultWatchRetention = 7 * 24 * time.Hour
+
+// WatchOption adjusts a watch.
+type WatchOption func(*watchSettings)
+
+// watchSettings collects the options passed to one watch.
+type watchSettings struct {
+	interval   time.Duration
+	retention  time.Duration
+	checkpoint *WatchCheckpoint
+}
+
+// WithPollInterval sets how long the watch waits between polls. Defaults to 30 seconds, which
+// is also what an interval of zero or less leaves in place.
+func WithPollInterval(interval time.Duration) WatchOption {
+	return func(settings *watchSettings) {
+		if interval > 0 {
+			settings.interval = interval
+		}
+	}
+}
+
+// WithRetention sets how long the watch remembers a change instance that has gone unmodified,
+// counting back from its newest modification time. A change forgotten and then moved is
+// reported without an old state. Defaults to DefaultWatchRetention; zero or less leaves it.
+func WithRetention(retention time.Duration) WatchOption {
+	return func(settings *watchSettings) {
+		if retention > 0 {
+			settings.retention = retention
+		}
+	}
+}
+
+// WithCheckpoint resumes a watch from a checkpoint taken from an earlier event. Without one the
+// watch starts from the current time, reporting only what happens after it starts.
+func WithCheckpoint(checkpoint WatchCheckpoint) WatchOption {
+	return func(settings *watchSettings) {
+		settings.checkpoint = &checkpoint
+	}
+}
+
+// WatchChangeInstances polls for change instances matching the filters and sends an event each
+// time one is created or moves between states. The channel is closed when the context ends.
+//
+// Each poll only asks for what was modified since the last, via StartDate and ordering by
+// modification time, so the filters' StartDate, Modified, Ordering and Offset are overridden;
+// the rest apply as usual, and Limit sets the page size (100 when zero). A change modified
+// without moving - a new log, say - produces no event.
+//
+// A poll pages by modification time too, asking for each next page from the newest time on the
+// last rather than by offset, so a change modified while the poll is paging moves later in the
+// listing without pushing another out of it. Rows repeated at a page boundary are recognised.
+//
+// The watch reads the time from the platform's modification stamps, not the local clock, except
+// when it starts without a checkpoint: then it starts from the local time, and a local clock
+// running ahead of the server's can hide the first changes.
+func (c *Client) WatchChangeInstances(
+	ctx context.Context,
+	filters *GetChangeInstancesRequest,
+	opts ...WatchOption,
+) <-chan ChangeInstanceEvent {
+	settings := watchSettings{interval: 30 * time.Second, retention: DefaultWatchRetention}
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	var base GetChangeInstancesRequest
+	if filters != nil {
+		base = *filters
+	}
+	if base.Limit == 0 {
+		base.Limit = 100
+	}
+	base.Modified = time.Time{}
+	base.Ordering = "modified"
+
+	state := &watchState{since: time.Now(), retention: settings.retention}
+	if settings.checkpoint != nil {
+		state.since = settings.checkpoint.Since
+		state.known = settings.checkpoint.Known()
+	}
+
+	events := make(chan ChangeInstanceEvent)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(settings.interval)
+		defer ticker.Stop()
+
+		for {
+			if !c.pollWatch(ctx, base, state, events) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return events
+}
+
+// watchState is what a running watch knows between polls.
+type watchState struct {
+	since     time.Time
+	known     map[int]WatchedChange
+	retention time.Duration
+}
+
+// watchPoll is one poll's view: what the watch knew when it began, and what it has observed.
+type watchPoll struct {
+	started  time.Time
+	since    time.Time
+	known    map[int]WatchedChange
+	observed map[int]WatchedChange
+	recent   []observedChange
+}
+
+// pollWatch runs one poll, sending its events and advancing the watch. It returns false when
+// the context ended while it was sending.
+func (c *Client) pollWatch(
+	ctx context.Context,
+	filters GetChangeInstancesRequest,
+	state *watchState,
+	events chan<- ChangeInstanceEvent,
+) bool {
+	poll := &watchPoll{
+		started:  state.since,
+		since:    state.since,
+		known:    state.known,
+		observed: map[int]WatchedChange{},
+	}
+
+	send := func(event ChangeInstanceEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// Page by keyset: each page starts at the newest modification time on the last. start_date
+	// is sent to the second, though, so the key is that second and the offset steps over the
+	// rows already read from it; a page that ends in the key's own second just adds to it.
+	filters.StartDate, filters.Offset = poll.since.Truncate(time.Second), 0
+	var pollErr error
+	for {
+		page, err := c.listChangeInstances(ctx, &filters, "")
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			pollErr = err
+			break
+		}
+
+		for _, ci := range page.Results {
+			event, ok := poll.observe(ci)
+			if ok && !send(event) {
+				return false
+			}
+		}
+		if page.Next == nil || len(page.Results) == 0 {
+			break
+		}
+		key := page.Results[len(page.Results)-1].Modified.Truncate(time.Second)
+		if !key.After(filters.StartDate) {
+			filters.Offset += len(page.Results)
+			continue
+		}
+		filters.StartDate, filters.Offset = key, 0
+		for _, ci := range page.Results {
+			if !ci.Modified.Before(key) {
+				filters.Offset++
+			}
+		}
+	}
+
+	state.advance(poll)
+	if pollErr != nil {
+		return send(ChangeInstanceEvent{Type: ChangeInstanceWatchError, Err: pollErr, Checkpoint: state.checkpoint()})
+	}
+	return true
+}
+
+// observe records a listed change, returning the event it amounts to, if any. A change the
+// watch has not seen, created at or after the poll's starting watermark, is new.
+func (p *watchPoll) observe(ci ChangeInstance) (ChangeInstanceEvent, bool) {
+	known, seen := p.observed[ci.ID]
+	if !seen {
+		known, seen = p.known[ci.ID]
+	}
+	if seen && !ci.Modified.After(known.Modified) {
+		return ChangeInstanceEvent{}, false
+	}
+
+	change := WatchedChange{State: ci.State, Modified: ci.Modified}
+	p.observed[ci.ID] = change
+	p.recent = append(p.recent, observedChange{id: ci.ID, WatchedChange: change})
+	if ci.Modified.After(p.since) {
+		p.since = ci.Modified
+	}
+
+	event := ChangeInstanceEvent{ChangeInstance: ci, NewState: ci.State}
+	switch {
+	case seen && known.State == ci.State:
+		return ChangeInstanceEvent{}, false
+	case seen:
+		event.Type = ChangeInstanceStateChanged
+		event.OldState = known.State
+	case !ci.Created.Before(p.started):
+		event.Type = ChangeInstanceCreated
+	default:
+		event.Type = ChangeInstanceStateChanged
+	}
+	// The checkpoint shares the poll's starting map and a prefix of what it observed since;
+	// later observations only ever append past that prefix.
+	event.Checkpoint = WatchCheckpoint{Since: p.since, known: p.known, recent: p.recent[:len(p.recent):len(p.recent)]}
+	return event, true
+}
+
+// advance folds a finished poll into what the watch knows - into a new map, since the old one
+// is shared with the checkpoints already handed out - and forgets what it no longer needs:
+// changes in a state nothing leaves once the watch has moved past their last modification,
+// and any change left unmodified for longer than the retention period. One modified at exactly
+// the watermark is kept, since the next poll lists it again.
+func (s *watchState) advance(poll *watchPoll) {
+	s.since = poll.since
+	if len(poll.observed) == 0 && !s.prunable() {
+		return
+	}
+
+	known := make(map[int]WatchedChange, len(s.known)+len(poll.observed))
+	maps.Copy(known, s.known)
+	maps.Copy(known, poll.observed)
+	maps.DeleteFunc(known, func(_ int, change WatchedChange) bool { return s.forgets(change) })
+	s.known = known
+}
+
+// prunable reports whether anything the watch knows is due to be forgotten.
+func (s *watchState) prunable() bool {
+	for _, change := range s.known {
+		if s.forgets(change) {
+			return true
+		}
+	}
+	return false
+}
+
+// forgets reports whether the watch no longer needs to remember a change.
+func (s *watchState) forgets(change WatchedChange) bool {
+	if change.Modified.Before(s.since.Add(-s.retention)) {
+		return true
+	}
+	final := len(changeInstanceTransitions[change.State]) == 0 && change.State.IsKnown()
+	return final && change.Modified.Before(s.since)
+}
+
+// checkpoint is where the watch stands between polls.
+func (s *watchState) checkpoint() WatchCheckpoint {
+	return WatchCheckpoint{Since: s.since, known: s.known}
+}
@@ -0,0 +1,392 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchChange renders a listed change instance for the watch tests.
+func watchChange(id int, state client.ChangeInstanceState, created, modified time.Time) string {
+	return fmt.Sprintf(`{"id":%d,"state":"%s","created":"%s","modified":"%s"}`,
+		id, state, created.Format(time.RFC3339Nano), modified.Format(time.RFC3339Nano))
+}
+
+// page is one listing page of rendered change instances.
+func page(changes ...string) []string { return changes }
+
+// scriptedPolls answers each listing with the next page of the script, repeating the last once
+// it runs out, and records the queries.
+func scriptedPolls(pages ...[]string) (httpmock.Responder, func() []url.Values) {
+	var mu sync.Mutex
+	var queries []url.Values
+	responder := func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		queries = append(queries, req.URL.Query())
+
+		results := pages[min(len(queries), len(pages))-1]
+		body := fmt.Sprintf(`{"count":%d,"next":null,"previous":null,"results":[%s]}`,
+			len(results), strings.Join(results, ","))
+		return httpmock.NewStringResponse(200, body), nil
+	}
+	return responder, func() []url.Values {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]url.Values(nil), queries...)
+	}
+}
+
+// nextEvent reads one event or fails the test after a second.
+func nextEvent(t *testing.T, events <-chan client.ChangeInstanceEvent) client.ChangeInstanceEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event arrived")
+		return client.ChangeInstanceEvent{}
+	}
+}
+
+func TestWatchChangeInstances(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	t1, t2 := start.Add(time.Minute), start.Add(2*time.Minute)
+
+	responder, queries := scriptedPolls(
+		page(watchChange(1, client.ChangeInstancePENDING, t1, t1)),
+		// the same change again, unmoved, at the inclusive watermark
+		page(watchChange(1, client.ChangeInstancePENDING, t1, t1)),
+		page(watchChange(1, client.ChangeInstanceAPPROVED, t1, t2)),
+	)
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", responder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nc := newPackTestClient(t)
+	events := nc.WatchChangeInstances(ctx, &client.GetChangeInstancesRequest{ServiceName: "vm"},
+		client.WithPollInterval(5*time.Millisecond), client.WithCheckpoint(client.WatchCheckpoint{Since: start}))
+
+	created := nextEvent(t, events)
+	assert.Equal(t, client.ChangeInstanceCreated, created.Type)
+	assert.Equal(t, 1, created.ChangeInstance.ID)
+	assert.Equal(t, client.ChangeInstancePENDING, created.NewState)
+	assert.Empty(t, created.OldState)
+	assert.Equal(t, t1, created.Checkpoint.Since)
+
+	changed := nextEvent(t, events)
+	assert.Equal(t, client.ChangeInstanceStateChanged, changed.Type)
+	assert.Equal(t, client.ChangeInstancePENDING, changed.OldState)
+	assert.Equal(t, client.ChangeInstanceAPPROVED, changed.NewState)
+	assert.Equal(t, t2, changed.Checkpoint.Since)
+
+	cancel()
+	for range events {
+		// drain until the watch closes the channel
+	}
+
+	first := queries()[0]
+	assert.Equal(t, "vm", first.Get("service_name"))
+	assert.Equal(t, "modified", first.Get("ordering"))
+	assert.Equal(t, "100", first.Get("limit"))
+	assert.Equal(t, start.Format(time.RFC3339), first.Get("start_date"))
+	assert.Equal(t, t1.Format(time.RFC3339), queries()[1].Get("start_date"), "the watermark advances")
+}
+
+func TestWatchChangeInstancesResume(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	since := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	earlier, later := since.Add(-time.Hour), since.Add(time.Minute)
+
+	responder, _ := scriptedPolls(page(
+		watchChange(1, client.ChangeInstanceCOMPLETED, earlier, later),
+		watchChange(2, client.ChangeInstanceAPPROVED, earlier, later),
+	))
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", responder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the checkpoint a previous process persisted, as it would come back from storage
+	var checkpoint client.WatchCheckpoint
+	require.NoError(t, json.Unmarshal(
+		[]byte(`{"since":"2026-10-18T09:00:00Z","known":{"1":{"state":"APPROVED","modified":"2026-10-18T08:30:00Z"}}}`),
+		&checkpoint))
+
+	nc := newPackTestClient(t)
+	events := nc.WatchChangeInstances(ctx, nil, client.WithPollInterval(time.Hour), client.WithCheckpoint(checkpoint))
+
+	resumed := nextEvent(t, events)
+	assert.Equal(t, client.ChangeInstanceStateChanged, resumed.Type)
+	assert.Equal(t, client.ChangeInstanceAPPROVED, resumed.OldState)
+	assert.Equal(t, client.ChangeInstanceCOMPLETED, resumed.NewState)
+
+	unseen := nextEvent(t, events)
+	assert.Equal(t, 2, unseen.ChangeInstance.ID)
+	assert.Equal(t, client.ChangeInstanceStateChanged, unseen.Type, "raised before the checkpoint, so not new")
+	assert.Empty(t, unseen.OldState, "the watch never saw its earlier state")
+
+	assert.Equal(t, map[int]client.WatchedChange{
+		1: {State: client.ChangeInstanceCOMPLETED, Modified: later},
+		2: {State: client.ChangeInstanceAPPROVED, Modified: later},
+	}, unseen.Checkpoint.Known())
+}
+
+func TestWatchChangeInstancesReportsPollErrors(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", httpmock.NewStringResponder(503, `unavailable`))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nc := newPackTestClient(t)
+	events := nc.WatchChangeInstances(ctx, nil, client.WithPollInterval(time.Hour))
+
+	event := nextEvent(t, events)
+	assert.Equal(t, client.ChangeInstanceWatchError, event.Type)
+	require.ErrorIs(t, event.Err, client.ErrServerUnavailable)
+}
+
+// watchListing serves a live listing the way the platform does: changes modified at or after
+// start_date, oldest modification first (then by id), paged by limit and offset. Its changes can be edited
+// between requests.
+type watchListing struct {
+	mu      sync.Mutex
+	changes map[int]client.ChangeInstance
+	// afterRequest, when set, runs after each request is answered, with the request's number.
+	afterRequest func(n int, changes map[int]client.ChangeInstance)
+	requests     int
+}
+
+func (l *watchListing) respond(req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	query := req.URL.Query()
+	start, _ := time.Parse(time.RFC3339, query.Get("start_date"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+
+	var listed []client.ChangeInstance
+	for _, ci := range l.changes {
+		if !ci.Modified.Before(start) {
+			listed = append(listed, ci)
+		}
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		if !listed[i].Modified.Equal(listed[j].Modified) {
+			return listed[i].Modified.Before(listed[j].Modified)
+		}
+		return listed[i].ID < listed[j].ID
+	})
+
+	var next *string
+	results := listed[min(offset, len(listed)):]
+	if len(results) > limit {
+		results = results[:limit]
+		more := "more"
+		next = &more
+	}
+	rendered := make([]string, len(results))
+	for i, ci := range results {
+		rendered[i] = watchChange(ci.ID, ci.State, ci.Created, ci.Modified)
+	}
+	body, _ := json.Marshal(map[string]any{
+		"count": len(listed), "next": next, "results": json.RawMessage("[" + strings.Join(rendered, ",") + "]"),
+	})
+
+	l.requests++
+	if l.afterRequest != nil {
+		l.afterRequest(l.requests, l.changes)
+	}
+	return httpmock.NewBytesResponse(200, body), nil
+}
+
+func TestWatchChangeInstancesPagesByModificationTime(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	listing := &watchListing{changes: map[int]client.ChangeInstance{}}
+	for id := 1; id <= 4; id++ {
+		listing.changes[id] = client.ChangeInstance{
+			ID: id, State: client.ChangeInstancePENDING, Created: at(id), Modified: at(id),
+		}
+	}
+	// Change 1, on the first page, is approved while the watch is reading the second: it moves
+	// to the end of the listing and every change after it moves up one place.
+	listing.afterRequest = func(n int, changes map[int]client.ChangeInstance) {
+		if n == 1 {
+			changes[1] = client.ChangeInstance{
+				ID: 1, State: client.ChangeInstanceAPPROVED, Created: at(1), Modified: at(5),
+			}
+		}
+	}
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", listing.respond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nc := newPackTestClient(t)
+	events := nc.WatchChangeInstances(ctx, &client.GetChangeInstancesRequest{Limit: 2},
+		client.WithPollInterval(time.Hour), client.WithCheckpoint(client.WatchCheckpoint{Since: start}))
+
+	var seen []string
+	for range 5 {
+		event := nextEvent(t, events)
+		seen = append(seen, fmt.Sprintf("%d %s %s", event.ChangeInstance.ID, event.Type, event.NewState))
+	}
+	assert.Equal(t, []string{
+		"1 created PENDING",
+		"2 created PENDING",
+		"3 created PENDING", // skipped by offset paging, which would ask for the third row next
+		"4 created PENDING",
+		"1 state_changed APPROVED",
+	}, seen)
+}
+
+func TestWatchChangeInstancesPagesThroughOneInstant(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	listing := &watchListing{changes: map[int]client.ChangeInstance{}}
+	for id := 1; id <= 5; id++ {
+		// a bulk update stamps every change with the same time
+		listing.changes[id] = client.ChangeInstance{
+			ID: id, State: client.ChangeInstancePENDING, Created: start, Modified: start.Add(time.Minute),
+		}
+	}
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", listing.respond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nc := newPackTestClient(t)
+	events := nc.WatchChangeInstances(ctx, &client.GetChangeInstancesRequest{Limit: 2},
+		client.WithPollInterval(time.Hour), client.WithCheckpoint(client.WatchCheckpoint{Since: start}))
+
+	ids := map[int]bool{}
+	for range 5 {
+		ids[nextEvent(t, events).ChangeInstance.ID] = true
+	}
+	assert.Len(t, ids, 5, "every change is reported once, though no page moves the watermark on")
+}
+
+// TestWatchChangeInstancesPagesThroughOneSecond has a burst of changes modified within one second,
+// which start_date, sent to the second, cannot tell apart: each row must still be read once.
+func TestWatchChangeInstancesPagesThroughOneSecond(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	burst := start.Add(time.Minute)
+	listing := &watchListing{changes: map[int]client.ChangeInstance{}}
+	for id := 1; id <= 9; id++ {
+		modified := burst.Add(time.Duration(id) * 100 * time.Millisecond)
+		listing.changes[id] = client.ChangeInstance{
+			ID: id, State: client.ChangeInstancePENDING, Created: start, Modified: modified,
+		}
+	}
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", listing.respond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nc := newPackTestClient(t)
+	events := nc.WatchChangeInstances(ctx, &client.GetChangeInstancesRequest{Limit: 2},
+		client.WithPollInterval(time.Hour), client.WithCheckpoint(client.WatchCheckpoint{Since: start}))
+
+	var ids []int
+	for range 9 {
+		ids = append(ids, nextEvent(t, events).ChangeInstance.ID)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, ids)
+	listing.mu.Lock()
+	defer listing.mu.Unlock()
+	assert.Equal(t, 5, listing.requests, "one request per page, none restarting at the top of the second")
+}
+
+func TestWatchChangeInstancesIgnoresANonPositiveInterval(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	responder, _ := scriptedPolls(page(watchChange(1, client.ChangeInstancePENDING, start, start)))
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", responder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nc := newPackTestClient(t)
+	events := nc.WatchChangeInstances(ctx, nil,
+		client.WithPollInterval(0), client.WithPollInterval(-time.Second),
+		client.WithCheckpoint(client.WatchCheckpoint{Since: start}))
+
+	assert.Equal(t, client.ChangeInstanceCreated, nextEvent(t, events).Type)
+}
+
+func TestWatchChangeInstancesForgetsOldChanges(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	since := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	t1, t2 := since.Add(time.Minute), since.Add(2*time.Minute)
+	responder, _ := scriptedPolls(
+		page(watchChange(1, client.ChangeInstancePENDING, t1, t1)),
+		page(watchChange(1, client.ChangeInstanceAPPROVED, t1, t2)),
+	)
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", responder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var checkpoint client.WatchCheckpoint
+	require.NoError(t, json.Unmarshal([]byte(`{"since":"2026-10-18T09:00:00Z","known":{`+
+		`"5":{"state":"PENDING","modified":"2026-10-18T07:00:00Z"},`+
+		`"6":{"state":"COMPLETED","modified":"2026-10-18T07:30:00Z"},`+
+		`"7":{"state":"ERROR","modified":"2026-10-18T08:30:00Z"},`+
+		`"8":{"state":"CLOSED","modified":"2026-10-18T08:30:00Z"}}}`), &checkpoint))
+
+	nc := newPackTestClient(t)
+	events := nc.WatchChangeInstances(ctx, nil, client.WithPollInterval(5*time.Millisecond),
+		client.WithRetention(time.Hour), client.WithCheckpoint(checkpoint))
+
+	nextEvent(t, events)
+	approved := nextEvent(t, events)
+	assert.Equal(t, client.ChangeInstanceAPPROVED, approved.NewState)
+	assert.Equal(t, map[int]client.WatchedChange{
+		1: {State: client.ChangeInstanceAPPROVED, Modified: t2},
+		// 5 and 6 went unmodified for longer than the retention, whatever their state, and 8
+		// is in a state nothing leaves
+		7: {State: client.ChangeInstanceERROR, Modified: since.Add(-30 * time.Minute)},
+	}, approved.Checkpoint.Known())
+
+	stored, err := json.Marshal(approved.Checkpoint)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"since":"2026-10-18T09:02:00Z","known":{`+
+		`"1":{"state":"APPROVED","modified":"2026-10-18T09:02:00Z"},`+
+		`"7":{"state":"ERROR","modified":"2026-10-18T08:30:00Z"}}}`, string(stored))
+}
@@ -1,10 +1,12 @@
 package client
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -57,6 +59,11 @@ type Client struct {
 	// Logger, when set, receives one line per request. Leave nil for silence; a library
 	// should not write to its consumer's log stream uninvited.
 	Logger Logger
+
+	// orderingMu guards deployedItemOrdering, what FindDeployedItemForServiceItem has learned
+	// about whether this server sorts deployed items by version when asked to.
+	orderingMu           sync.Mutex
+	deployedItemOrdering orderingSupport
 }
 
 // Logger is the minimal logging surface the client needs. It is satisfied by *log.Logger
@@ -139,3 +146,29 @@ func (r *RefID) UnmarshalJSON(data []byte) error {
 	*r = RefID(object.ID)
 	return nil
 }
+
+// decodeEnum decodes one of the package's string enums without ever failing on the value.
+//
+// The platform adds states and types faster than clients are released, and an SDK that refused
+// a whole listing because one item carried a state it had not heard of would turn every new
+// server feature into an outage. So a string is kept verbatim whether or not it is known (check
+// IsKnown to tell), and null and absence become "".
+//
+// Anything that is not a string - a number, a boolean, an object - is rejected rather than kept
+// as its text: the enums encode as strings, so 3 would come back out as "3", and a value that
+// does not survive the round trip is better refused than silently changed.
+func decodeEnum(data []byte) (string, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return "", nil
+	}
+	if trimmed[0] != '"' {
+		return "", fmt.Errorf("expected a string, got %s", trimmed)
+	}
+
+	var value string
+	if err := json.Unmarshal(trimmed, &value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
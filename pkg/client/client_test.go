@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -41,3 +42,71 @@ func TestClient(t *testing.T) {
 		assert.Equal(t, "base URL must start with http:// or https://", err.Error())
 	})
 }
+
+// TestEnumDecoding pins the unknown-tolerant decoding shared by the package's enums. A state the
+// platform adds after a release must survive a decode and encode untouched, not fail the listing
+// it arrived in.
+func TestEnumDecoding(t *testing.T) {
+	const payload = `{
+		"id": 53,
+		"state": "AWAITING_CAB",
+		"change_type": "REPLACE",
+		"service_item": {"id": 31, "runtime_state": "DRAINING", "change_state": "CHANGES_PENDING"}
+	}`
+
+	t.Run("keeps values the package does not know about", func(t *testing.T) {
+		var ci client.ChangeInstance
+		require.NoError(t, json.Unmarshal([]byte(payload), &ci))
+
+		assert.Equal(t, client.ChangeInstanceState("AWAITING_CAB"), ci.State)
+		assert.False(t, ci.State.IsKnown())
+		assert.False(t, ci.State.IsTerminal())
+		assert.Equal(t, client.ChangeType("REPLACE"), ci.ChangeType)
+		assert.False(t, ci.ChangeType.IsKnown())
+		assert.Equal(t, client.RuntimeState("DRAINING"), ci.ServiceItem.RuntimeState)
+		assert.False(t, ci.ServiceItem.RuntimeState.IsKnown())
+		assert.Equal(t, client.ChangeStateChangesPending, ci.ServiceItem.ChangeState)
+		assert.True(t, ci.ServiceItem.ChangeState.IsKnown())
+
+		encoded, err := json.Marshal(ci)
+		require.NoError(t, err)
+		assert.Contains(t, string(encoded), `"state":"AWAITING_CAB"`)
+		assert.Contains(t, string(encoded), `"change_type":"REPLACE"`)
+		assert.Contains(t, string(encoded), `"runtime_state":"DRAINING"`)
+	})
+
+	t.Run("reads null as empty", func(t *testing.T) {
+		var pipeline client.PackPipeline
+		require.NoError(t, json.Unmarshal([]byte(`{"state": null}`), &pipeline))
+		assert.Equal(t, client.PackPipelineState(""), pipeline.State)
+	})
+
+	t.Run("rejects anything but a string, which would not round-trip", func(t *testing.T) {
+		var ci client.ChangeInstance
+		err := json.Unmarshal([]byte(`{"state": {"name": "PENDING"}}`), &ci)
+		require.ErrorContains(t, err, "failed to decode change instance state")
+
+		var data client.PackData
+		err = json.Unmarshal([]byte(`{"action_type": 3}`), &data)
+		require.EqualError(t, err, "failed to decode pack action type: expected a string, got 3")
+	})
+
+	t.Run("classifies the terminal states", func(t *testing.T) {
+		for state, terminal := range map[client.ChangeInstanceState]bool{
+			client.ChangeInstancePENDING:   false,
+			client.ChangeInstanceAPPROVED:  false,
+			client.ChangeInstanceCOMPLETED: true,
+			client.ChangeInstanceREJECTED:  true,
+			client.ChangeInstanceCLOSED:    true,
+			client.ChangeInstanceERROR:     true,
+		} {
+			assert.True(t, state.IsKnown(), state)
+			assert.Equal(t, terminal, state.IsTerminal(), state)
+		}
+
+		assert.True(t, client.PackPipelineOK.IsTerminal())
+		assert.True(t, client.PackPipelineFailed.IsTerminal())
+		assert.False(t, client.PackPipelineWaitingForResponse.IsTerminal())
+		assert.True(t, client.PackActionChangeInstanceValidator.IsKnown())
+	})
+}
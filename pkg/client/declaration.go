@@ -0,0 +1,458 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidDeclaration is the sentinel for a declaration that does not decode into, or does not
+// validate as, the type a caller asked for. The error carrying it is a *DeclarationError.
+var ErrInvalidDeclaration = errors.New("netorca: invalid declaration")
+
+// errUnknownField is the cause recorded against a field strict decoding did not expect.
+var errUnknownField = errors.New("unknown field")
+
+// DeclarationError reports a declaration that failed to decode or validate. Its cause is, or
+// joins, one or more *FieldError values locating each problem; reach them with errors.As.
+type DeclarationError struct {
+	// Source names the declaration, e.g. "new declaration of change instance 53".
+	Source string
+	// Err is the cause.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *DeclarationError) Error() string {
+	return fmt.Sprintf("netorca: %s: %v", e.Source, e.Err)
+}
+
+// Unwrap returns both ErrInvalidDeclaration and the cause, so errors.Is matches the sentinel and
+// errors.As reaches the field errors.
+func (e *DeclarationError) Unwrap() []error {
+	return []error{ErrInvalidDeclaration, e.Err}
+}
+
+// FieldError ties an error to one place in a declaration, written as a JSON path such as
+// "$.interfaces[2].vlan". Decoding produces it for type mismatches and unknown fields; a
+// declaration type's Validate method can return it too (joined with errors.Join for several) to
+// have its findings reported the same way.
+type FieldError struct {
+	// Path locates the problem. "$" is the declaration itself.
+	Path string
+	// Err is the problem.
+	Err error
+}
+
+// NewFieldError builds a *FieldError from a path and a formatted message.
+func NewFieldError(path, format string, args ...any) *FieldError {
+	return &FieldError{Path: path, Err: fmt.Errorf(format, args...)}
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the problem.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeOption adjusts how a declaration is decoded.
+type DecodeOption func(*decodeSettings)
+
+// decodeSettings collects the options passed to a single decode.
+type decodeSettings struct {
+	strict         bool
+	skipValidation bool
+}
+
+// Strict rejects fields the target type does not declare, reporting each by path, rather than
+// ignoring them as encoding/json does. It catches a consumer's typo ("cpus" for "cpu") that would
+// otherwise decode silently to a zero value.
+func Strict() DecodeOption {
+	return func(settings *decodeSettings) {
+		settings.strict = true
+	}
+}
+
+// SkipValidation decodes without calling the target type's Validate method.
+func SkipValidation() DecodeOption {
+	return func(settings *decodeSettings) {
+		settings.skipValidation = true
+	}
+}
+
+// DecodeDeclaration decodes a change instance's new and old declarations into T.
+//
+// Either result is nil when the change has no such declaration: a CREATE has no old one, and a
+// DELETE may carry no new one. If *T (or T) has a Validate() error method it is run on each
+// decoded value, unless SkipValidation is passed. Failures are *DeclarationError values naming
+// which declaration failed, wrapping ErrInvalidDeclaration.
+func DecodeDeclaration[T any](ci *ChangeInstance, opts ...DecodeOption) (newDecl, oldDecl *T, err error) {
+	settings := newDecodeSettings(opts)
+
+	newDecl, err = decodeDeclaration[T](
+		ci.NewDeclaration.Declaration, fmt.Sprintf("new declaration of change instance %d", ci.ID), settings,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ci.OldDeclaration != nil {
+		oldDecl, err = decodeDeclaration[T](
+			ci.OldDeclaration.Declaration, fmt.Sprintf("old declaration of change instance %d", ci.ID), settings,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return newDecl, oldDecl, nil
+}
+
+// ServiceItemDeclaration decodes a service item's current declaration into T, with the same
+// options and validation as DecodeDeclaration. It returns nil for an item with no declaration.
+func ServiceItemDeclaration[T any](item *ServiceItem, opts ...DecodeOption) (*T, error) {
+	return decodeDeclaration[T](
+		item.Declaration, fmt.Sprintf("declaration of service item %d", item.ID), newDecodeSettings(opts),
+	)
+}
+
+// PackDataDeclaration decodes the service item declaration snapshot stored with a pack stage's
+// output into T, with the same options and validation as DecodeDeclaration. It returns nil when
+// the record carries no snapshot.
+func PackDataDeclaration[T any](data *PackData, opts ...DecodeOption) (*T, error) {
+	return decodeDeclaration[T](
+		data.SIDeclaration, fmt.Sprintf("service item declaration in pack data %d", data.ID), newDecodeSettings(opts),
+	)
+}
+
+// newDecodeSettings applies the options.
+func newDecodeSettings(opts []DecodeOption) decodeSettings {
+	var settings decodeSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+	return settings
+}
+
+// decodeDeclaration decodes one raw declaration, returning nil for an absent one.
+func decodeDeclaration[T any](raw json.RawMessage, source string, settings decodeSettings) (*T, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil, nil
+	}
+
+	value := new(T)
+	if err := decodeJSONPaths(trimmed, value, settings); err != nil {
+		return nil, &DeclarationError{Source: source, Err: err}
+	}
+	if !settings.skipValidation {
+		if err := validateDecoded(value); err != nil {
+			return nil, &DeclarationError{Source: source, Err: err}
+		}
+	}
+	return value, nil
+}
+
+// decodeJSONPaths decodes raw into target, translating decoding failures into *FieldError values
+// and, in strict mode, reporting every field target's type does not declare.
+func decodeJSONPaths(raw []byte, target any, settings decodeSettings) error {
+	if settings.strict {
+		var tree any
+		if err := json.Unmarshal(raw, &tree); err != nil {
+			return fieldErrorFrom(err)
+		}
+		if unknown := unknownFields(tree, reflect.TypeOf(target), "$"); len(unknown) > 0 {
+			return errors.Join(unknown...)
+		}
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return typeFieldError(raw, reflect.TypeOf(target), typeErr)
+		}
+		return fieldErrorFrom(err)
+	}
+	return nil
+}
+
+// validateDecoded runs the Validate method of *T or T, if either has one. An error that does not
+// already locate itself is attributed to the declaration as a whole.
+func validateDecoded(value any) error {
+	validator, ok := value.(interface{ Validate() error })
+	if !ok {
+		validator, ok = reflect.ValueOf(value).Elem().Interface().(interface{ Validate() error })
+	}
+	if !ok {
+		return nil
+	}
+
+	err := validator.Validate()
+	if err == nil {
+		return nil
+	}
+	var fieldErr *FieldError
+	if errors.As(err, &fieldErr) {
+		return err
+	}
+	return &FieldError{Path: "$", Err: err}
+}
+
+// fieldErrorFrom locates an encoding/json error by path where it carries enough to do so.
+func fieldErrorFrom(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return typeFieldError(nil, nil, typeErr)
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &FieldError{Path: "$", Err: fmt.Errorf("malformed JSON at byte %d: %w", syntaxErr.Offset, err)}
+	}
+	return &FieldError{Path: "$", Err: err}
+}
+
+// typeFieldError locates a type mismatch decoding raw into target.
+func typeFieldError(raw []byte, target reflect.Type, typeErr *json.UnmarshalTypeError) *FieldError {
+	return &FieldError{
+		Path: typeErrorPath(raw, target, typeErr),
+		Err:  fmt.Errorf("cannot use JSON %s as %s", typeErr.Value, typeErr.Type),
+	}
+}
+
+// typeErrorPath turns the dotted field path encoding/json reports, "interfaces.2.vlan", into
+// the form the rest of this package uses: "$.interfaces[2].vlan".
+//
+// The dotted form cannot be read on its own: "2" is an array index under a slice but a key
+// under a map[string]T, written $.labels["2"], and the encoding/json of older Go releases leaves
+// array indices and map keys out altogether. So the path is walked along the target type and
+// the JSON that failed to decode, taking an index or key from the field path where there is one
+// and otherwise finding the element that holds the mismatched value. Without the JSON to search, an index
+// that cannot be known is written [*].
+func typeErrorPath(raw []byte, target reflect.Type, typeErr *json.UnmarshalTypeError) string {
+	var segments []string
+	if typeErr.Field != "" {
+		segments = strings.Split(typeErr.Field, ".")
+	}
+	kind, _, _ := strings.Cut(typeErr.Value, " ")
+
+	var tree any
+	if raw != nil && json.Unmarshal(raw, &tree) == nil {
+		if path, ok := locateTypeError(tree, target, segments, "$", typeErr.Type, kind); ok {
+			return path
+		}
+	}
+	path, _ := locateTypeError(nil, target, segments, "$", typeErr.Type, "")
+	return path
+}
+
+// locateTypeError follows segments down tree and t, returning the path to the value where the
+// walk ends and whether that value is of the JSON kind that failed to decode. A nil tree walks
+// the type alone, writing unknown indices as [*]; a nil type walks the segments alone.
+func locateTypeError(
+	tree any,
+	t reflect.Type,
+	segments []string,
+	path string,
+	errType reflect.Type,
+	kind string,
+) (string, bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t != nil && t != errType && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		elements, _ := tree.([]any)
+		if len(segments) > 0 && arrayIndexToken.MatchString(segments[0]) {
+			var element any
+			if index, err := strconv.Atoi(segments[0]); err == nil && index < len(elements) {
+				element = elements[index]
+			}
+			return locateTypeError(element, t.Elem(), segments[1:], path+"["+segments[0]+"]", errType, kind)
+		}
+		for index, element := range elements {
+			found, ok := locateTypeError(element, t.Elem(), segments, path+"["+strconv.Itoa(index)+"]", errType, kind)
+			if ok {
+				return found, true
+			}
+		}
+		return locateTypeError(nil, t.Elem(), segments, path+"[*]", errType, kind)
+	}
+
+	if len(segments) == 0 {
+		object, searchable := tree.(map[string]any)
+		if !searchable || t == nil || t == errType || t.Kind() != reflect.Map {
+			return path, tree != nil && jsonKind(tree) == kind
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if found, ok := locateTypeError(object[key], t.Elem(), nil, childPath(path, key), errType, kind); ok {
+				return found, true
+			}
+		}
+		return path, false
+	}
+	var child any
+	if object, ok := tree.(map[string]any); ok {
+		child = object[segments[0]]
+	}
+	var next reflect.Type
+	if t != nil {
+		switch t.Kind() {
+		case reflect.Map:
+			next = t.Elem()
+		case reflect.Struct:
+			next, _ = lookupJSONField(jsonFields(t), segments[0])
+		}
+	}
+	return locateTypeError(child, next, segments[1:], childPath(path, segments[0]), errType, kind)
+}
+
+// jsonKind names a decoded JSON value's kind as encoding/json's errors do.
+func jsonKind(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	rawMessageType      = reflect.TypeFor[json.RawMessage]()
+	// plainKey matches object keys that can be written as ".key" in a path.
+	plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// arrayIndexToken matches an array index as JSON paths and pointers write it: no sign and no
+	// leading zero.
+	arrayIndexToken = regexp.MustCompile(`^(0|[1-9][0-9]*)$`)
+)
+
+// unknownFields walks a decoded JSON tree alongside the Go type it is meant for, returning a
+// *FieldError for every object key the type has nowhere to put. Types that decode themselves,
+// and interface or raw message fields, accept anything and end the walk.
+func unknownFields(tree any, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == rawMessageType || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+
+	var unknown []error
+	switch node := tree.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for _, key := range keys {
+				fieldType, ok := lookupJSONField(fields, key)
+				if !ok {
+					unknown = append(unknown, &FieldError{Path: childPath(path, key), Err: errUnknownField})
+					continue
+				}
+				unknown = append(unknown, unknownFields(node[key], fieldType, childPath(path, key))...)
+			}
+		case reflect.Map:
+			for _, key := range keys {
+				unknown = append(unknown, unknownFields(node[key], t.Elem(), childPath(path, key))...)
+			}
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, element := range node {
+				unknown = append(unknown, unknownFields(element, t.Elem(), path+"["+strconv.Itoa(i)+"]")...)
+			}
+		}
+	}
+	return unknown
+}
+
+// jsonFields returns the JSON names a struct type decodes, mapped to their field types, following
+// encoding/json's rules for tags, ignored fields and embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	var embedded []reflect.Type
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				embedded = append(embedded, fieldType)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+
+	// Fields of embedded structs are promoted, but the outer struct's own fields win.
+	for _, inner := range embedded {
+		for name, fieldType := range jsonFields(inner) {
+			if _, taken := fields[name]; !taken {
+				fields[name] = fieldType
+			}
+		}
+	}
+	return fields
+}
+
+// lookupJSONField finds the field a key decodes into: an exact match first, then the
+// case-insensitive match encoding/json falls back to.
+func lookupJSONField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if fieldType, ok := fields[key]; ok {
+		return fieldType, true
+	}
+	for name, fieldType := range fields {
+		if strings.EqualFold(name, key) {
+			return fieldType, true
+		}
+	}
+	return nil, false
+}
+
+// childPath appends an object key to a JSON path, quoting keys that are not plain identifiers.
+func childPath(path, key string) string {
+	if plainKey.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
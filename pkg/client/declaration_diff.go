@@ -0,0 +1,530 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maskedValue replaces a masked value in a diff. maskedChangedValue replaces the new side of one
+// that changed, so an approver still learns that a secret moved without seeing either version.
+const (
+	maskedValue        = "***"
+	maskedChangedValue = "*** (changed)"
+)
+
+// ANSI escapes used by JSONDiff.Colored.
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// DiffOptions tunes DiffDeclarations and DiffDocuments. The zero value (or nil) masks nothing and
+// shows three lines of context.
+type DiffOptions struct {
+	// MaskPaths are RFC 6901 JSON pointers whose values are hidden in every form of the diff, such
+	// as "/credentials/password". A "*" token matches any object key or array index, as in
+	// "/users/*/token", and masking a container hides everything beneath it.
+	MaskPaths []string
+	// Context is how many unchanged lines the text diff shows around each change. Defaults to 3;
+	// set it to NoDiffContext for the changed lines alone.
+	Context int
+}
+
+// NoDiffContext is the DiffOptions.Context that shows no unchanged lines at all. Zero cannot
+// say so, since it is the field's unset value and so means the default.
+const NoDiffContext = -1
+
+// Validate checks the mask paths are JSON pointers and the context is NoDiffContext or more.
+func (o *DiffOptions) Validate() error {
+	for _, path := range o.MaskPaths {
+		if _, err := splitPointer(path); err != nil {
+			return fmt.Errorf("invalid mask path: %w", err)
+		}
+	}
+	if o.Context < NoDiffContext {
+		return fmt.Errorf("diff context cannot be negative, other than NoDiffContext")
+	}
+	return nil
+}
+
+// JSONDiff is the difference between two JSON documents, as a JSON Patch and as text. Key order
+// is ignored throughout: both documents are rendered with sorted keys before the text is diffed.
+type JSONDiff struct {
+	// OldLabel and NewLabel name the two sides in the text diff's header.
+	OldLabel string
+	NewLabel string
+	// Patch turns the old document into the new one. Masked values appear masked here too.
+	Patch JSONPatch
+
+	oldLines []string
+	newLines []string
+	context  int
+}
+
+// DiffDeclarations compares a change instance's old declaration with its new one - what an
+// approver of a MODIFY wants to see. A change with no old declaration (a CREATE) diffs against an
+// empty one, so everything shows as added; one with no new declaration shows everything removed.
+func DiffDeclarations(ci *ChangeInstance, opts *DiffOptions) (*JSONDiff, error) {
+	oldLabel := "old declaration (none)"
+	var oldRaw json.RawMessage
+	if ci.OldDeclaration != nil {
+		oldLabel = fmt.Sprintf("old declaration (v%d)", ci.OldDeclaration.Version)
+		oldRaw = ci.OldDeclaration.Declaration
+	}
+	newLabel := fmt.Sprintf("new declaration (v%d)", ci.NewDeclaration.Version)
+
+	diff, err := DiffDocuments(oldLabel, oldRaw, newLabel, ci.NewDeclaration.Declaration, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff the declarations of change instance %d: %w", ci.ID, err)
+	}
+	return diff, nil
+}
+
+// DiffDocuments compares any two JSON documents with the same masking and rendering as
+// DiffDeclarations. A missing (empty or null) side is treated as an empty object in the patch
+// and as no lines at all in the text.
+func DiffDocuments(oldLabel string, oldRaw json.RawMessage, newLabel string, newRaw json.RawMessage,
+	opts *DiffOptions,
+) (*JSONDiff, error) {
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	oldTree, err := decodeJSONTree(oldRaw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", oldLabel, err)
+	}
+	newTree, err := decodeJSONTree(newRaw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", newLabel, err)
+	}
+	oldTree, newTree = maskJSONTrees(oldTree, newTree, opts.MaskPaths)
+
+	patchOld, patchNew := oldTree, newTree
+	if patchOld == nil {
+		patchOld = map[string]any{}
+	}
+	if patchNew == nil {
+		patchNew = map[string]any{}
+	}
+	var patch JSONPatch
+	if err := diffJSONTrees("", patchOld, patchNew, &patch); err != nil {
+		return nil, err
+	}
+
+	oldLines, err := documentLines(oldTree)
+	if err != nil {
+		return nil, err
+	}
+	newLines, err := documentLines(newTree)
+	if err != nil {
+		return nil, err
+	}
+
+	context := opts.Context
+	switch context {
+	case 0:
+		context = 3
+	case NoDiffContext:
+		context = 0
+	}
+	return &JSONDiff{
+		OldLabel: oldLabel,
+		NewLabel: newLabel,
+		Patch:    patch,
+		oldLines: oldLines,
+		newLines: newLines,
+		context:  context,
+	}, nil
+}
+
+// HasChanges reports whether the documents differ.
+func (d *JSONDiff) HasChanges() bool {
+	return len(d.Patch) > 0
+}
+
+// Unified renders the diff in unified format, as diff -u and git diff do. It is empty when the
+// documents are the same.
+func (d *JSONDiff) Unified() string {
+	return d.render(false)
+}
+
+// Colored renders the unified diff with ANSI colours for a terminal: removals red, additions
+// green and hunk headers cyan.
+func (d *JSONDiff) Colored() string {
+	return d.render(true)
+}
+
+// Summary describes the patch in one line, short enough to embed in a change log: the number of
+// changes and the first few paths they touch, e.g.
+// "3 changes: replaced /cpu, added /interfaces/1, removed /labels/env".
+func (d *JSONDiff) Summary() string {
+	const shown = 5
+
+	if len(d.Patch) == 0 {
+		return "no changes"
+	}
+	verbs := map[string]string{PatchAdd: "added", PatchRemove: "removed", PatchReplace: "replaced"}
+
+	parts := make([]string, 0, shown)
+	for _, op := range d.Patch[:min(shown, len(d.Patch))] {
+		path := op.Path
+		if path == "" {
+			path = "/"
+		}
+		parts = append(parts, verbs[op.Op]+" "+path)
+	}
+
+	noun := "changes"
+	if len(d.Patch) == 1 {
+		noun = "change"
+	}
+	summary := fmt.Sprintf("%d %s: %s", len(d.Patch), noun, strings.Join(parts, ", "))
+	if len(d.Patch) > shown {
+		summary += fmt.Sprintf(" and %d more", len(d.Patch)-shown)
+	}
+	return summary
+}
+
+// render builds the unified diff text.
+func (d *JSONDiff) render(color bool) string {
+	if !d.HasChanges() {
+		return ""
+	}
+	paint := func(code, text string) string {
+		if !color {
+			return text
+		}
+		return code + text + ansiReset
+	}
+
+	var out strings.Builder
+	out.WriteString(paint(ansiBold, "--- "+d.OldLabel) + "\n")
+	out.WriteString(paint(ansiBold, "+++ "+d.NewLabel) + "\n")
+
+	edits := diffLines(d.oldLines, d.newLines)
+	for _, hunk := range unifiedHunks(edits, d.context) {
+		out.WriteString(paint(ansiCyan, hunk.header()) + "\n")
+		for _, edit := range edits[hunk.start:hunk.end] {
+			line := string(edit.kind) + edit.text
+			switch edit.kind {
+			case '-':
+				line = paint(ansiRed, line)
+			case '+':
+				line = paint(ansiGreen, line)
+			}
+			out.WriteString(line + "\n")
+		}
+	}
+	return out.String()
+}
+
+// documentLines renders a decoded document with sorted keys and two-space indents, one line per
+// element, so a line diff of two renderings ignores key order. A missing document has no lines.
+func documentLines(tree any) ([]string, error) {
+	if tree == nil {
+		return nil, nil
+	}
+	rendered, err := json.MarshalIndent(tree, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the document: %w", err)
+	}
+	return strings.Split(string(rendered), "\n"), nil
+}
+
+// lineEdit is one line of an edit script: kept (' '), removed ('-') or added ('+').
+type lineEdit struct {
+	kind byte
+	text string
+	// oldLine and newLine are the 1-based line numbers on each side; 0 where the line is absent.
+	oldLine int
+	newLine int
+}
+
+// diffLines computes a shortest edit script between two sequences of lines, via the longest
+// common subsequence of the part between their common prefix and suffix. Declarations are small
+// enough that the quadratic table is not a concern once the unchanged ends are trimmed.
+func diffLines(a, b []string) []lineEdit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:].
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]lineEdit, 0, len(a)+len(b))
+	oldLine, newLine := 0, 0
+	keep := func(text string) {
+		oldLine++
+		newLine++
+		edits = append(edits, lineEdit{kind: ' ', text: text, oldLine: oldLine, newLine: newLine})
+	}
+	remove := func(text string) {
+		oldLine++
+		edits = append(edits, lineEdit{kind: '-', text: text, oldLine: oldLine})
+	}
+	add := func(text string) {
+		newLine++
+		edits = append(edits, lineEdit{kind: '+', text: text, newLine: newLine})
+	}
+
+	for _, text := range a[:prefix] {
+		keep(text)
+	}
+	i, j := 0, 0
+	for i < len(midA) && j < len(midB) {
+		switch {
+		case midA[i] == midB[j]:
+			keep(midA[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			remove(midA[i])
+			i++
+		default:
+			add(midB[j])
+			j++
+		}
+	}
+	for ; i < len(midA); i++ {
+		remove(midA[i])
+	}
+	for ; j < len(midB); j++ {
+		add(midB[j])
+	}
+	for _, text := range a[len(a)-suffix:] {
+		keep(text)
+	}
+	return edits
+}
+
+// diffHunk is a run of edits shown together, as the half-open range [start, end) of the script.
+type diffHunk struct {
+	start, end int
+	oldStart   int
+	oldCount   int
+	newStart   int
+	newCount   int
+}
+
+// header renders the hunk's "@@ -l,s +l,s @@" line.
+func (h diffHunk) header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.oldStart, h.oldCount), hunkRange(h.newStart, h.newCount))
+}
+
+// hunkRange renders one side of a hunk header, in the form diff -u uses.
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return strconv.Itoa(start) + "," + strconv.Itoa(count)
+}
+
+// unifiedHunks groups the changed lines of an edit script into hunks with the given context,
+// merging hunks whose context would touch or overlap.
+func unifiedHunks(edits []lineEdit, context int) []diffHunk {
+	var hunks []diffHunk
+	for i := 0; i < len(edits); i++ {
+		if edits[i].kind == ' ' {
+			continue
+		}
+		start := max(0, i-context)
+		end := i + 1
+		// Extend while another change falls within reach of the trailing context.
+		for j := end; j < len(edits) && j < end+2*context+1; j++ {
+			if edits[j].kind != ' ' {
+				end = j + 1
+			}
+		}
+		end = min(len(edits), end+context)
+
+		hunk := diffHunk{start: start, end: end}
+		for _, edit := range edits[start:end] {
+			if edit.kind != '+' {
+				if hunk.oldCount == 0 {
+					hunk.oldStart = edit.oldLine
+				}
+				hunk.oldCount++
+			}
+			if edit.kind != '-' {
+				if hunk.newCount == 0 {
+					hunk.newStart = edit.newLine
+				}
+				hunk.newCount++
+			}
+		}
+		// An empty side is numbered by the line it follows, as diff -u does.
+		if hunk.oldCount == 0 {
+			hunk.oldStart = linesBefore(edits, start, true)
+		}
+		if hunk.newCount == 0 {
+			hunk.newStart = linesBefore(edits, start, false)
+		}
+		hunks = append(hunks, hunk)
+		i = end - 1
+	}
+	return hunks
+}
+
+// linesBefore counts the old (or new) lines in the edit script before the given position.
+func linesBefore(edits []lineEdit, position int, old bool) int {
+	count := 0
+	for _, edit := range edits[:position] {
+		if (old && edit.kind != '+') || (!old && edit.kind != '-') {
+			count++
+		}
+	}
+	return count
+}
+
+// maskJSONTrees hides the values at the mask paths in both documents. A value present on both
+// sides that differs is masked distinctly on the new side, so the diff still records the change.
+func maskJSONTrees(oldTree, newTree any, maskPaths []string) (any, any) {
+	for _, maskPath := range maskPaths {
+		pattern, _ := splitPointer(maskPath) // validated by DiffOptions.Validate
+
+		seen := map[string]bool{}
+		var targets [][]string
+		for _, tree := range []any{oldTree, newTree} {
+			for _, target := range expandPointerPattern(tree, pattern, nil) {
+				key := strings.Join(target, "/")
+				if !seen[key] {
+					seen[key] = true
+					targets = append(targets, target)
+				}
+			}
+		}
+
+		for _, target := range targets {
+			oldValue, inOld := lookupJSONTree(oldTree, target)
+			newValue, inNew := lookupJSONTree(newTree, target)
+			if inOld {
+				oldTree = setJSONTree(oldTree, target, maskedValue)
+			}
+			if inNew {
+				masked := maskedValue
+				if inOld && !jsonTreesEqual(oldValue, newValue) {
+					masked = maskedChangedValue
+				}
+				newTree = setJSONTree(newTree, target, masked)
+			}
+		}
+	}
+	return oldTree, newTree
+}
+
+// expandPointerPattern returns the concrete token paths in tree matching a pattern whose "*"
+// tokens match any key or index.
+func expandPointerPattern(tree any, pattern, prefix []string) [][]string {
+	if len(pattern) == 0 {
+		return [][]string{append([]string(nil), prefix...)}
+	}
+	token, rest := pattern[0], pattern[1:]
+
+	var children []string
+	switch node := tree.(type) {
+	case map[string]any:
+		if token == "*" {
+			for key := range node {
+				children = append(children, key)
+			}
+		} else if _, ok := node[token]; ok {
+			children = append(children, token)
+		}
+	case []any:
+		if token == "*" {
+			for i := range node {
+				children = append(children, strconv.Itoa(i))
+			}
+		} else if _, ok := elementIndex(token, len(node)); ok {
+			children = append(children, token)
+		}
+	}
+
+	var matches [][]string
+	for _, child := range children {
+		value, _ := lookupJSONTree(tree, []string{child})
+		matches = append(matches, expandPointerPattern(value, rest, append(prefix, child))...)
+	}
+	return matches
+}
+
+// lookupJSONTree returns the value at a token path, and whether it exists.
+func lookupJSONTree(tree any, tokens []string) (any, bool) {
+	for _, token := range tokens {
+		switch node := tree.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			tree = value
+		case []any:
+			i, ok := elementIndex(token, len(node))
+			if !ok {
+				return nil, false
+			}
+			tree = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return tree, true
+}
+
+// setJSONTree replaces the existing value at a token path and returns the (possibly new) root.
+func setJSONTree(tree any, tokens []string, value any) any {
+	if len(tokens) == 0 {
+		return value
+	}
+	parent, ok := lookupJSONTree(tree, tokens[:len(tokens)-1])
+	if !ok {
+		return tree
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+	case []any:
+		if i, ok := elementIndex(last, len(node)); ok {
+			node[i] = value
+		}
+	}
+	return tree
+}
+
+// elementIndex parses a pointer token as an index into an array of the given length. Only the
+// plain decimal form counts: "01" and "+1" name no element, as in a JSON patch.
+func elementIndex(token string, length int) (int, bool) {
+	if !arrayIndexToken.MatchString(token) {
+		return 0, false
+	}
+	i, err := strconv.Atoi(token)
+	return i, err == nil && i < length
+}
@@ -0,0 +1,153 @@
+package client_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// modifyChange is a MODIFY raising the CPU count, relabelling the item and rotating its password.
+func modifyChange() *client.ChangeInstance {
+	return &client.ChangeInstance{
+		ID: 53,
+		OldDeclaration: &client.Declaration{
+			Version:     2,
+			Declaration: json.RawMessage(`{"name":"web-01","cpu":2,"labels":{"env":"dev"},"password":"hunter2"}`),
+		},
+		NewDeclaration: client.Declaration{
+			Version:     3,
+			Declaration: json.RawMessage(`{"password":"correct horse","labels":{"env":"prod"},"name":"web-01","cpu":4}`),
+		},
+	}
+}
+
+func TestDiffDeclarations(t *testing.T) {
+	diff, err := client.DiffDeclarations(modifyChange(), nil)
+	require.NoError(t, err)
+
+	require.True(t, diff.HasChanges())
+	assert.Equal(t, client.JSONPatch{
+		{Op: client.PatchReplace, Path: "/cpu", Value: json.RawMessage(`4`)},
+		{Op: client.PatchReplace, Path: "/labels/env", Value: json.RawMessage(`"prod"`)},
+		{Op: client.PatchReplace, Path: "/password", Value: json.RawMessage(`"correct horse"`)},
+	}, diff.Patch)
+
+	assert.Equal(t, `--- old declaration (v2)
++++ new declaration (v3)
+@@ -1,8 +1,8 @@
+ {
+-  "cpu": 2,
++  "cpu": 4,
+   "labels": {
+-    "env": "dev"
++    "env": "prod"
+   },
+   "name": "web-01",
+-  "password": "hunter2"
++  "password": "correct horse"
+ }
+`, diff.Unified())
+
+	assert.Equal(t, "3 changes: replaced /cpu, replaced /labels/env, replaced /password", diff.Summary())
+}
+
+func TestDiffDeclarationsMasking(t *testing.T) {
+	diff, err := client.DiffDeclarations(modifyChange(), &client.DiffOptions{MaskPaths: []string{"/password"}})
+	require.NoError(t, err)
+
+	assert.NotContains(t, diff.Unified(), "hunter2")
+	assert.NotContains(t, diff.Unified(), "correct horse")
+	assert.Contains(t, diff.Unified(), `-  "password": "***"`)
+	assert.Contains(t, diff.Unified(), `+  "password": "*** (changed)"`)
+	assert.Contains(t, diff.Patch, client.PatchOperation{
+		Op: client.PatchReplace, Path: "/password", Value: json.RawMessage(`"*** (changed)"`),
+	})
+
+	t.Run("an unchanged masked value drops out of the diff", func(t *testing.T) {
+		ci := &client.ChangeInstance{
+			OldDeclaration: &client.Declaration{Declaration: json.RawMessage(`{"users":[{"token":"a"}],"n":1}`)},
+			NewDeclaration: client.Declaration{Declaration: json.RawMessage(`{"users":[{"token":"a"}],"n":2}`)},
+		}
+		diff, err := client.DiffDeclarations(ci, &client.DiffOptions{MaskPaths: []string{"/users/*/token"}})
+		require.NoError(t, err)
+		assert.Equal(t, "1 change: replaced /n", diff.Summary())
+		assert.Contains(t, diff.Unified(), `"token": "***"`)
+	})
+
+	t.Run("only a plain index names an element", func(t *testing.T) {
+		ci := &client.ChangeInstance{
+			OldDeclaration: &client.Declaration{Declaration: json.RawMessage(`{"users":[{"token":"a"}]}`)},
+			NewDeclaration: client.Declaration{Declaration: json.RawMessage(`{"users":[{"token":"b"}]}`)},
+		}
+		for _, mask := range []string{"/users/00/token", "/users/+0/token"} {
+			diff, err := client.DiffDeclarations(ci, &client.DiffOptions{MaskPaths: []string{mask}})
+			require.NoError(t, err)
+			assert.Contains(t, diff.Unified(), `"token": "b"`, mask)
+		}
+	})
+
+	_, err = client.DiffDeclarations(modifyChange(), &client.DiffOptions{MaskPaths: []string{"password"}})
+	require.Error(t, err)
+}
+
+func TestDiffDeclarationsRendering(t *testing.T) {
+	t.Run("a CREATE shows everything added", func(t *testing.T) {
+		ci := &client.ChangeInstance{
+			NewDeclaration: client.Declaration{Version: 1, Declaration: json.RawMessage(`{"name":"web-01"}`)},
+		}
+		diff, err := client.DiffDeclarations(ci, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "--- old declaration (none)\n+++ new declaration (v1)\n@@ -0,0 +1,3 @@\n"+
+			"+{\n+  \"name\": \"web-01\"\n+}\n", diff.Unified())
+		assert.Equal(t, "1 change: added /name", diff.Summary())
+	})
+
+	t.Run("distant changes get separate hunks", func(t *testing.T) {
+		keys := make([]string, 0, 20)
+		for _, key := range "abcdefghijklmnopqrst" {
+			keys = append(keys, `"`+string(key)+`":0`)
+		}
+		oldDoc := "{" + strings.Join(keys, ",") + "}"
+		newDoc := strings.Replace(strings.Replace(oldDoc, `"a":0`, `"a":1`, 1), `"t":0`, `"t":1`, 1)
+
+		diff, err := client.DiffDocuments("old", json.RawMessage(oldDoc), "new", json.RawMessage(newDoc),
+			&client.DiffOptions{Context: 1})
+		require.NoError(t, err)
+		assert.Equal(t, "--- old\n+++ new\n"+
+			"@@ -1,3 +1,3 @@\n {\n-  \"a\": 0,\n+  \"a\": 1,\n   \"b\": 0,\n"+
+			"@@ -20,3 +20,3 @@\n   \"s\": 0,\n-  \"t\": 0\n+  \"t\": 1\n }\n", diff.Unified())
+	})
+
+	t.Run("no context at all", func(t *testing.T) {
+		diff, err := client.DiffDeclarations(modifyChange(), &client.DiffOptions{Context: client.NoDiffContext})
+		require.NoError(t, err)
+		assert.Equal(t, "--- old declaration (v2)\n+++ new declaration (v3)\n"+
+			"@@ -2 +2 @@\n-  \"cpu\": 2,\n+  \"cpu\": 4,\n"+
+			"@@ -4 +4 @@\n-    \"env\": \"dev\"\n+    \"env\": \"prod\"\n"+
+			"@@ -7 +7 @@\n-  \"password\": \"hunter2\"\n+  \"password\": \"correct horse\"\n", diff.Unified())
+
+		_, err = client.DiffDeclarations(modifyChange(), &client.DiffOptions{Context: -2})
+		require.Error(t, err)
+	})
+
+	t.Run("colours and the no-change case", func(t *testing.T) {
+		diff, err := client.DiffDeclarations(modifyChange(), nil)
+		require.NoError(t, err)
+		assert.Contains(t, diff.Colored(), "\x1b[31m-  \"cpu\": 2,\x1b[0m")
+		assert.Contains(t, diff.Colored(), "\x1b[32m+  \"cpu\": 4,\x1b[0m")
+
+		same := &client.ChangeInstance{
+			OldDeclaration: &client.Declaration{Declaration: json.RawMessage(`{"a":1,"b":2}`)},
+			NewDeclaration: client.Declaration{Declaration: json.RawMessage(`{"b":2,"a":1}`)},
+		}
+		diff, err = client.DiffDeclarations(same, nil)
+		require.NoError(t, err)
+		assert.False(t, diff.HasChanges())
+		assert.Empty(t, diff.Unified())
+		assert.Equal(t, "no changes", diff.Summary())
+	})
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DeclarationSearch is a typed declaration search, rendered into the declaration,
+// declaration_contains and declaration_regex filters. Start one with DeclarationQuery and hand
+// it to the DeclarationQuery field of GetServiceItemsRequest or GetChangeInstancesRequest:
+//
+//	filters := &client.GetServiceItemsRequest{
+//		DeclarationQuery: client.DeclarationQuery().
+//			Field("vip.port").Equals(443).
+//			Contains("tags", "prod").
+//			Field("name").Matches("^web-"),
+//	}
+//
+// Each filter takes a JSON object mirroring the declaration, with the value to compare at the
+// field's place in it: Field("vip.port").Equals(443) renders declaration={"vip":{"port":443}}.
+// Conditions of one kind are combined (an AND); the three kinds are separate filters, also
+// ANDed by the platform.
+//
+// Mistakes - an empty path, a pattern that does not compile, two conditions on one field - are
+// collected as the query is built and reported by Err, and so by ToQueryParams, which refuses to
+// send a query that is not what the caller meant. The zero value is an empty search, the same
+// as DeclarationQuery returns.
+type DeclarationSearch struct {
+	// Each kind's conditions, keyed by their path's keys joined with NUL. Each map is made by the
+	// first condition of its kind.
+	equals   map[string]any
+	contains map[string]any
+	regex    map[string]any
+	errs     []error
+}
+
+// DeclarationField is one declaration field of a search, waiting for its condition.
+type DeclarationField struct {
+	search *DeclarationSearch
+	path   string
+}
+
+// DeclarationQuery starts an empty declaration search.
+func DeclarationQuery() *DeclarationSearch {
+	return &DeclarationSearch{}
+}
+
+// Field names a declaration field by its dotted path, such as "vip.port". Write a dot that is
+// part of a key as `\.`.
+func (s *DeclarationSearch) Field(path string) *DeclarationField {
+	return &DeclarationField{search: s, path: path}
+}
+
+// Equals matches declarations whose field holds exactly value, which may be any JSON-encodable
+// value: a number, a string, a bool, or a whole object or list.
+func (f *DeclarationField) Equals(value any) *DeclarationSearch {
+	return f.search.Equals(f.path, value)
+}
+
+// Contains matches declarations whose field contains substring.
+func (f *DeclarationField) Contains(substring string) *DeclarationSearch {
+	return f.search.Contains(f.path, substring)
+}
+
+// Matches matches declarations whose field matches the regular expression pattern.
+func (f *DeclarationField) Matches(pattern string) *DeclarationSearch {
+	return f.search.Matches(f.path, pattern)
+}
+
+// Equals is Field(path).Equals(value).
+func (s *DeclarationSearch) Equals(path string, value any) *DeclarationSearch {
+	if _, err := json.Marshal(value); err != nil {
+		s.errs = append(s.errs, NewFieldError(jsonPathOf(path), "value cannot be encoded as JSON: %v", err))
+		return s
+	}
+	s.set(&s.equals, path, value)
+	return s
+}
+
+// Contains is Field(path).Contains(substring).
+func (s *DeclarationSearch) Contains(path, substring string) *DeclarationSearch {
+	if substring == "" {
+		s.errs = append(s.errs, NewFieldError(jsonPathOf(path), "contains needs a non-empty substring"))
+		return s
+	}
+	s.set(&s.contains, path, substring)
+	return s
+}
+
+// Matches is Field(path).Matches(pattern).
+//
+// The pattern is compiled with Go's regexp package before anything is sent, to catch the
+// unbalanced brackets and stray quantifiers that otherwise come back as an opaque 400 or, worse,
+// as no results. The platform's regex dialect accepts a little more than Go's - lookarounds and
+// backreferences - so a pattern relying on those has to go through DeclarationRegex by hand.
+func (s *DeclarationSearch) Matches(path, pattern string) *DeclarationSearch {
+	if _, err := regexp.Compile(pattern); err != nil {
+		s.errs = append(s.errs, NewFieldError(jsonPathOf(path), "invalid regex: %v", err))
+		return s
+	}
+	s.set(&s.regex, path, pattern)
+	return s
+}
+
+// Err reports every mistake made building the search, joined, or nil.
+func (s *DeclarationSearch) Err() error {
+	return errors.Join(s.errs...)
+}
+
+// Render returns the JSON each filter takes, keyed by filter name: "declaration",
+// "declaration_contains" and "declaration_regex". Filters with no conditions are left out.
+func (s *DeclarationSearch) Render() (map[string]string, error) {
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("invalid declaration query: %w", err)
+	}
+
+	rendered := map[string]string{}
+	for name, conditions := range map[string]map[string]any{
+		"declaration":          s.equals,
+		"declaration_contains": s.contains,
+		"declaration_regex":    s.regex,
+	} {
+		if len(conditions) == 0 {
+			continue
+		}
+		// Every value was checked as it was added, so the tree always encodes.
+		encoded, err := json.Marshal(conditionTree(conditions))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s filter: %w", name, err)
+		}
+		rendered[name] = string(encoded)
+	}
+	return rendered, nil
+}
+
+// set records a condition at path. A path that runs into a condition already set - the same
+// field twice, or a field inside one already compared whole - is recorded as an error rather
+// than silently overwritten.
+func (s *DeclarationSearch) set(conditions *map[string]any, path string, value any) {
+	keys, err := splitDeclarationPath(path)
+	if err != nil {
+		s.errs = append(s.errs, NewFieldError(jsonPathOf(path), "%v", err))
+		return
+	}
+
+	// Keys are joined with a byte no declaration key contains, so one path being inside
+	// another is a plain prefix test.
+	joined := strings.Join(keys, "\x00")
+	for existing := range *conditions {
+		if existing == joined ||
+			strings.HasPrefix(existing, joined+"\x00") || strings.HasPrefix(joined, existing+"\x00") {
+			s.errs = append(s.errs, NewFieldError(jsonPathOf(path), "overlaps another condition of the same kind"))
+			return
+		}
+	}
+	if *conditions == nil {
+		*conditions = map[string]any{}
+	}
+	(*conditions)[joined] = value
+}
+
+// conditionTree nests a kind's conditions into the object shape the filter takes.
+func conditionTree(conditions map[string]any) map[string]any {
+	tree := map[string]any{}
+	for joined, value := range conditions {
+		keys := strings.Split(joined, "\x00")
+		node := tree
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := node[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[key] = child
+			}
+			node = child
+		}
+		node[keys[len(keys)-1]] = value
+	}
+	return tree
+}
+
+// splitDeclarationPath splits a dotted path into keys, honouring `\.` as a literal dot.
+func splitDeclarationPath(path string) ([]string, error) {
+	var keys []string
+	var key strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			key.WriteByte('.')
+			i++
+		case path[i] == '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(path[i])
+		}
+	}
+	keys = append(keys, key.String())
+
+	for _, k := range keys {
+		if k == "" {
+			return nil, errors.New("empty field name in path")
+		}
+	}
+	return keys, nil
+}
+
+// jsonPathOf writes a dotted path in the "$.vip.port" form FieldError uses.
+func jsonPathOf(path string) string {
+	return "$." + path
+}
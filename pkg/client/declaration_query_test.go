@@ -0,0 +1,135 @@
+package client_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeclarationQueryRender(t *testing.T) {
+	rendered, err := client.DeclarationQuery().
+		Field("vip.port").Equals(443).
+		Field("vip.protocol").Equals("tcp").
+		Contains("tags", "prod").
+		Field(`dns\.zone`).Matches(`^corp\.`).
+		Render()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"declaration":          `{"vip":{"port":443,"protocol":"tcp"}}`,
+		"declaration_contains": `{"tags":"prod"}`,
+		"declaration_regex":    `{"dns.zone":"^corp\\."}`,
+	}, rendered)
+}
+
+func TestDeclarationQueryZeroValue(t *testing.T) {
+	search := &client.DeclarationSearch{}
+	rendered, err := search.Field("vip.port").Equals(443).Contains("tags", "prod").Render()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"declaration":          `{"vip":{"port":443}}`,
+		"declaration_contains": `{"tags":"prod"}`,
+	}, rendered)
+}
+
+func TestDeclarationQueryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *client.DeclarationSearch
+		path  string
+		msg   string
+	}{
+		{
+			name:  "invalid regex",
+			query: client.DeclarationQuery().Field("name").Matches("web-("),
+			path:  "$.name",
+			msg:   "invalid regex: error parsing regexp: missing closing ): `web-(`",
+		},
+		{
+			name:  "empty key",
+			query: client.DeclarationQuery().Field("vip..port").Equals(443),
+			path:  "$.vip..port",
+			msg:   "empty field name in path",
+		},
+		{
+			name:  "same field twice",
+			query: client.DeclarationQuery().Equals("port", 443).Equals("port", 80),
+			path:  "$.port",
+			msg:   "overlaps another condition of the same kind",
+		},
+		{
+			name:  "field inside one compared whole",
+			query: client.DeclarationQuery().Equals("vip", map[string]any{"port": 443}).Equals("vip.port", 80),
+			path:  "$.vip.port",
+			msg:   "overlaps another condition of the same kind",
+		},
+		{
+			name:  "empty substring",
+			query: client.DeclarationQuery().Contains("tags", ""),
+			path:  "$.tags",
+			msg:   "contains needs a non-empty substring",
+		},
+		{
+			name:  "unencodable value",
+			query: client.DeclarationQuery().Equals("port", func() {}),
+			path:  "$.port",
+			msg:   "value cannot be encoded as JSON: json: unsupported type: func()",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fieldErr *client.FieldError
+			require.ErrorAs(t, tt.query.Err(), &fieldErr)
+			assert.Equal(t, tt.path, fieldErr.Path)
+			assert.EqualError(t, fieldErr.Err, tt.msg)
+
+			_, err := tt.query.Render()
+			require.ErrorContains(t, err, "invalid declaration query")
+		})
+	}
+
+	// Conditions of different kinds may share a field.
+	require.NoError(t, client.DeclarationQuery().Equals("name", "web").Contains("name", "we").Err())
+}
+
+func TestDeclarationQueryFilters(t *testing.T) {
+	query := client.DeclarationQuery().Field("vip.port").Equals(443).Contains("tags", "prod")
+
+	serviceItems, err := (&client.GetServiceItemsRequest{DeclarationQuery: query}).ToQueryParams()
+	require.NoError(t, err)
+	changeInstances, err := (&client.GetChangeInstancesRequest{DeclarationQuery: query}).ToQueryParams()
+	require.NoError(t, err)
+
+	for _, encoded := range []string{serviceItems, changeInstances} {
+		values, err := url.ParseQuery(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"declaration":          {`{"vip":{"port":443}}`},
+			"declaration_contains": {`{"tags":"prod"}`},
+		}, values)
+	}
+
+	_, err = (&client.GetChangeInstancesRequest{
+		DeclarationQuery:  query,
+		DeclarationFilter: map[string]any{"name": "web"},
+	}).ToQueryParams()
+	require.EqualError(t, err,
+		"the declaration filter is set twice: use the declaration query or the field, not both")
+
+	// A regex set by hand alongside a query without one is fine.
+	encoded, err := (&client.GetServiceItemsRequest{
+		DeclarationQuery: query,
+		DeclarationRegex: `{"name":"^web-(?=prod)"}`,
+	}).ToQueryParams()
+	require.NoError(t, err)
+	assert.Contains(t, encoded, "declaration_regex=")
+
+	_, err = (&client.GetServiceItemsRequest{
+		DeclarationQuery: client.DeclarationQuery().Field("name").Matches("("),
+	}).ToQueryParams()
+	require.ErrorContains(t, err, "$.name: invalid regex")
+}
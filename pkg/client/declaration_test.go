@@ -0,0 +1,161 @@
+package client_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type vmInterface struct {
+	Name string `json:"name"`
+	VLAN int    `json:"vlan"`
+}
+
+type vmDeclaration struct {
+	Name       string            `json:"name"`
+	CPU        int               `json:"cpu"`
+	Interfaces []vmInterface     `json:"interfaces"`
+	Labels     map[string]string `json:"labels"`
+	Extra      json.RawMessage   `json:"extra"`
+}
+
+// Validate reports the rules a JSON schema cannot express, located by path.
+func (d *vmDeclaration) Validate() error {
+	var errs []error
+	if d.CPU <= 0 {
+		errs = append(errs, client.NewFieldError("$.cpu", "must be positive, got %d", d.CPU))
+	}
+	for i, iface := range d.Interfaces {
+		if iface.VLAN > 4094 {
+			errs = append(errs, client.NewFieldError(fmt.Sprintf("$.interfaces[%d].vlan", i), "out of range"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func TestDecodeDeclaration(t *testing.T) {
+	ci := &client.ChangeInstance{
+		ID:             53,
+		NewDeclaration: client.Declaration{Declaration: json.RawMessage(`{"name":"web-01","cpu":4}`)},
+		OldDeclaration: &client.Declaration{Declaration: json.RawMessage(`{"name":"web-01","cpu":2}`)},
+	}
+
+	newDecl, oldDecl, err := client.DecodeDeclaration[vmDeclaration](ci)
+	require.NoError(t, err)
+	assert.Equal(t, 4, newDecl.CPU)
+	assert.Equal(t, 2, oldDecl.CPU)
+
+	t.Run("a CREATE has no old declaration", func(t *testing.T) {
+		create := &client.ChangeInstance{ID: 54, NewDeclaration: ci.NewDeclaration}
+		newDecl, oldDecl, err := client.DecodeDeclaration[vmDeclaration](create)
+		require.NoError(t, err)
+		assert.Equal(t, "web-01", newDecl.Name)
+		assert.Nil(t, oldDecl)
+	})
+
+	t.Run("a null new declaration decodes to nil", func(t *testing.T) {
+		deleted := &client.ChangeInstance{
+			ID:             55,
+			NewDeclaration: client.Declaration{Declaration: json.RawMessage(`null`)},
+			OldDeclaration: ci.OldDeclaration,
+		}
+		newDecl, oldDecl, err := client.DecodeDeclaration[vmDeclaration](deleted)
+		require.NoError(t, err)
+		assert.Nil(t, newDecl)
+		assert.Equal(t, 2, oldDecl.CPU)
+	})
+}
+
+func TestDecodeDeclarationErrors(t *testing.T) {
+	item := func(declaration string) *client.ServiceItem {
+		return &client.ServiceItem{ID: 389, Declaration: json.RawMessage(declaration)}
+	}
+
+	t.Run("type mismatch is located by path", func(t *testing.T) {
+		_, err := client.ServiceItemDeclaration[vmDeclaration](item(`{"name":"web-01","cpu":"four"}`))
+		require.ErrorIs(t, err, client.ErrInvalidDeclaration)
+
+		var fieldErr *client.FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, "$.cpu", fieldErr.Path)
+		assert.Contains(t, err.Error(), "declaration of service item 389: $.cpu: cannot use JSON string as int")
+	})
+
+	t.Run("a type mismatch in an array element is located by index", func(t *testing.T) {
+		const mismatch = `{"name":"web-01","cpu":1,"interfaces":[{"vlan":1},{"vlan":2},{"vlan":"x"}]}`
+
+		for _, opts := range [][]client.DecodeOption{nil, {client.Strict()}} {
+			_, err := client.ServiceItemDeclaration[vmDeclaration](item(mismatch), opts...)
+
+			var fieldErr *client.FieldError
+			require.ErrorAs(t, err, &fieldErr)
+			assert.Equal(t, "$.interfaces[2].vlan", fieldErr.Path)
+			assert.ErrorContains(t, err, "$.interfaces[2].vlan: cannot use JSON string as int")
+		}
+	})
+
+	t.Run("a numeric map key stays a key", func(t *testing.T) {
+		const mismatch = `{"name":"web-01","cpu":1,"labels":{"team":"web","2":5}}`
+
+		_, err := client.ServiceItemDeclaration[vmDeclaration](item(mismatch))
+
+		var fieldErr *client.FieldError
+		require.ErrorAs(t, err, &fieldErr)
+		assert.Equal(t, `$.labels["2"]`, fieldErr.Path)
+	})
+
+	t.Run("unknown fields pass unless strict", func(t *testing.T) {
+		const typo = `{"name":"web-01","cpus":4,"cpu":1,"interfaces":[{"name":"eth0","vlan":10,"mtu":9000}],` +
+			`"labels":{"team":"web"},"extra":{"anything":true}}`
+
+		decl, err := client.ServiceItemDeclaration[vmDeclaration](item(typo))
+		require.NoError(t, err)
+		assert.Equal(t, 1, decl.CPU)
+
+		_, err = client.ServiceItemDeclaration[vmDeclaration](item(typo), client.Strict())
+		require.ErrorIs(t, err, client.ErrInvalidDeclaration)
+		assert.EqualError(t, err, "netorca: declaration of service item 389: $.cpus: unknown field\n"+
+			"$.interfaces[0].mtu: unknown field")
+	})
+
+	t.Run("strict mode follows encoding/json's case folding", func(t *testing.T) {
+		decl, err := client.ServiceItemDeclaration[vmDeclaration](item(`{"Name":"web-01","CPU":2}`), client.Strict())
+		require.NoError(t, err)
+		assert.Equal(t, 2, decl.CPU)
+	})
+
+	t.Run("validation errors keep their paths", func(t *testing.T) {
+		const invalid = `{"name":"web-01","cpu":0,"interfaces":[{"name":"eth0","vlan":5000}]}`
+
+		_, err := client.ServiceItemDeclaration[vmDeclaration](item(invalid))
+		require.ErrorIs(t, err, client.ErrInvalidDeclaration)
+		assert.EqualError(t, err, "netorca: declaration of service item 389: $.cpu: must be positive, got 0\n"+
+			"$.interfaces[0].vlan: out of range")
+
+		decl, err := client.ServiceItemDeclaration[vmDeclaration](item(invalid), client.SkipValidation())
+		require.NoError(t, err)
+		assert.Equal(t, 0, decl.CPU)
+	})
+}
+
+func TestPackDataDeclaration(t *testing.T) {
+	data := &client.PackData{ID: 12, SIDeclaration: json.RawMessage(`{"name":"web-01","cpu":2}`)}
+
+	decl, err := client.PackDataDeclaration[vmDeclaration](data)
+	require.NoError(t, err)
+	assert.Equal(t, "web-01", decl.Name)
+
+	// a map target works too, with no Validate method to run
+	generic, err := client.PackDataDeclaration[map[string]any](data)
+	require.NoError(t, err)
+	assert.Equal(t, "web-01", (*generic)["name"])
+
+	empty, err := client.PackDataDeclaration[vmDeclaration](&client.PackData{ID: 13})
+	require.NoError(t, err)
+	assert.Nil(t, empty)
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -317,20 +318,165 @@ func (c *Client) GetDeployedItem(ctx context.Context, pov POV, id int) (*Deploye
 // "Current" means the highest version, which is what the platform itself resolves a service
 // item's deployed item to. It returns an error wrapping ErrNotFound when the service item has
 // never had one, which a provider should read as drift rather than as a failure.
+//
+// A long-lived item's history costs a request per hundred versions to scan, so the lookup first
+// asks the server for the highest version alone, with ordering=-version. It only trusts that
+// answer once a sample page has shown the server really sorts by version - the first lookup on
+// a Client makes that check, and its outcome is remembered for the Client's lifetime. A server
+// that ignores or rejects the ordering gets the full scan, which is always correct; so does a
+// lookup whose probe fails for any other reason, though that is not remembered.
 func (c *Client) FindDeployedItemForServiceItem(
 	ctx context.Context,
 	pov POV,
 	serviceItemID int,
 ) (*DeployedItem, error) {
-	// The current record is the highest version, and a service item's history can run past one
-	// page - 32 versions against a page size of 20 has been seen. Taking the max over a single
-	// page returns a stale version the moment the history outgrows a page, so this pages through
-	// every result and takes the true max. Because it scans everything, the answer does not
-	// depend on the server honouring any particular ordering.
-	const pageSize = 100
+	current, trusted, err := c.findOrderedDeployedItem(ctx, pov, serviceItemID)
+	if err == nil && !trusted {
+		current, err = c.findScannedDeployedItem(ctx, pov, serviceItemID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to look up the deployed item for service item %d: %w", serviceItemID, err,
+		)
+	}
+
+	if current == nil {
+		return nil, fmt.Errorf(
+			"%w: no deployed item for service item %d", ErrNotFound, serviceItemID,
+		)
+	}
+	return current, nil
+}
+
+// orderingSupport is what a Client knows about a server's handling of ordering=-version.
+type orderingSupport int
+
+const (
+	orderingUnknown orderingSupport = iota
+	orderingHonoured
+	orderingIgnored
+)
+
+// deployedItemOrderingSample is the size of the page that checks a server sorts by version.
+const deployedItemOrderingSample = 20
+
+// findOrderedDeployedItem asks the server for the highest version directly. It reports whether
+// its answer can be trusted; when it cannot, the caller should scan instead. A trusted nil item
+// means the service item has no deployed item at all.
+func (c *Client) findOrderedDeployedItem(
+	ctx context.Context,
+	pov POV,
+	serviceItemID int,
+) (*DeployedItem, bool, error) {
+	c.orderingMu.Lock()
+	support := c.deployedItemOrdering
+	c.orderingMu.Unlock()
+	if support == orderingIgnored {
+		return nil, false, nil
+	}
+
+	// Until the server has proved itself, ask for a sample page rather than a single item: one
+	// element cannot show whether anything was sorted.
+	limit := 1
+	if support == orderingUnknown {
+		limit = deployedItemOrderingSample
+	}
+	response, err := c.ListDeployedItems(ctx, &ListDeployedItemsRequest{
+		POV:           pov,
+		ServiceItemID: []int{serviceItemID},
+		Limit:         limit,
+		Ordering:      "-version",
+	})
+	switch {
+	case orderingRejected(err):
+		// The API rejects an ordering it does not recognise rather than ignoring it.
+		c.setDeployedItemOrdering(orderingIgnored)
+		return nil, false, nil
+	case err != nil && ctx.Err() != nil:
+		return nil, false, err
+	case err != nil:
+		// Any other failure - a 400 about something else, a 5xx, a dropped connection - says
+		// nothing about the ordering, so it is not remembered; this lookup scans instead, and the
+		// scan reports the failure if it persists.
+		return nil, false, nil
+	case response.Count == 0 && len(response.Results) == 0:
+		return nil, true, nil
+	}
+
+	// A page that is not the size the limit and the count call for is a server ignoring the
+	// limit, or a history changing under the request. Either way the page proves nothing.
+	if len(response.Results) != min(limit, response.Count) {
+		if len(response.Results) > limit {
+			c.setDeployedItemOrdering(orderingIgnored)
+		}
+		return nil, false, nil
+	}
+	if response.Count == 1 {
+		return &response.Results[0], true, nil
+	}
+
+	if support == orderingUnknown {
+		for index := 1; index < len(response.Results); index++ {
+			if response.Results[index].Version >= response.Results[index-1].Version {
+				c.setDeployedItemOrdering(orderingIgnored)
+				return nil, false, nil
+			}
+		}
+		c.setDeployedItemOrdering(orderingHonoured)
+	}
+	return &response.Results[0], true, nil
+}
+
+// orderingRejected reports whether err is a 400 whose explanation is about the ordering
+// parameter, as opposed to any other problem with the request.
+func orderingRejected(err error) bool {
+	var apiErr *APIError
+	return errors.Is(err, ErrBadRequest) && errors.As(err, &apiErr) &&
+		strings.Contains(strings.ToLower(apiErr.Body), "ordering")
+}
+
+// setDeployedItemOrdering records what a lookup learned about the server's ordering.
+func (c *Client) setDeployedItemOrdering(support orderingSupport) {
+	c.orderingMu.Lock()
+	c.deployedItemOrdering = support
+	c.orderingMu.Unlock()
+}
+
+// findScannedDeployedItem reads the whole history and takes the highest version, returning nil
+// when there is none.
+func (c *Client) findScannedDeployedItem(
+	ctx context.Context,
+	pov POV,
+	serviceItemID int,
+) (*DeployedItem, error) {
+	history, err := c.listDeployedItemHistory(ctx, pov, serviceItemID)
+	if err != nil {
+		return nil, err
+	}
 
 	var current *DeployedItem
-	seen := 0
+	for index := range history {
+		if current == nil || history[index].Version > current.Version {
+			current = &history[index]
+		}
+	}
+	return current, nil
+}
+
+// listDeployedItemHistory returns every version of a service item's deployed item, in whatever
+// order the server sent them.
+func (c *Client) listDeployedItemHistory(
+	ctx context.Context,
+	pov POV,
+	serviceItemID int,
+) ([]DeployedItem, error) {
+	// A service item's history can run past one page - 32 versions against a page size of 20
+	// has been seen. Taking the max over a single page returns a stale version the moment the
+	// history outgrows a page, so this pages through every result. Because it reads everything,
+	// its callers' answers do not depend on the server honouring any particular ordering.
+	const pageSize = 100
+
+	var history []DeployedItem
 	for offset := 0; ; offset += pageSize {
 		response, err := c.ListDeployedItems(ctx, &ListDeployedItemsRequest{
 			POV:           pov,
@@ -339,32 +485,16 @@ func (c *Client) FindDeployedItemForServiceItem(
 			Offset:        offset,
 		})
 		if err != nil {
-			return nil, fmt.Errorf(
-				"failed to look up the deployed item for service item %d: %w", serviceItemID, err,
-			)
-		}
-
-		for index := range response.Results {
-			if current == nil || response.Results[index].Version > current.Version {
-				candidate := response.Results[index]
-				current = &candidate
-			}
+			return nil, err
 		}
+		history = append(history, response.Results...)
 
 		// Stop on a short page or once every counted item has been read. Guarding on Count as
 		// well as page length means a server that ignores the limit cannot spin this loop.
-		seen += len(response.Results)
-		if len(response.Results) < pageSize || seen >= response.Count {
-			break
+		if len(response.Results) < pageSize || len(history) >= response.Count {
+			return history, nil
 		}
 	}
-
-	if current == nil {
-		return nil, fmt.Errorf(
-			"%w: no deployed item for service item %d", ErrNotFound, serviceItemID,
-		)
-	}
-	return current, nil
 }
 
 // CreateDeployedItem records a deployment against exactly one parent, returning the item the
@@ -427,6 +557,17 @@ func (c *Client) UpdateDeployedItem(
 		return nil, fmt.Errorf("failed to read deployed item %d before updating it: %w", id, err)
 	}
 
+	return c.rewriteDeployedItem(ctx, pov, existing, data)
+}
+
+// rewriteDeployedItem writes data over an item the caller has just read, echoing its parent
+// back as the API requires.
+func (c *Client) rewriteDeployedItem(
+	ctx context.Context,
+	pov POV,
+	existing *DeployedItem,
+	data json.RawMessage,
+) (*DeployedItem, error) {
 	// Prefer the service item: the platform sets it on every item it accepts, including the
 	// ones created against a change instance, so it is the parent that is always there.
 	payload := deployedItemBody{Data: deployedItemData(data)}
@@ -437,11 +578,11 @@ func (c *Client) UpdateDeployedItem(
 		payload.ChangeInstance = existing.ChangeInstance
 	default:
 		return nil, fmt.Errorf(
-			"deployed item %d has no parent to write back, so the API will not accept an update", id,
+			"deployed item %d has no parent to write back, so the API will not accept an update", existing.ID,
 		)
 	}
 
-	endpoint := fmt.Sprintf("orcabase/%s/deployed_items/%d/", pov.orDefault(), id)
+	endpoint := fmt.Sprintf("orcabase/%s/deployed_items/%d/", pov.orDefault(), existing.ID)
 
 	var response DeployedItem
 	if err := c.doRequest(ctx, "PATCH", endpoint, payload, &response); err != nil {
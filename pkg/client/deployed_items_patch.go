@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// deployedItemPatchAttempts bounds how many times PatchDeployedItem re-reads and re-applies its
+// patch after losing a race with another writer.
+const deployedItemPatchAttempts = 5
+
+// PatchDeployedItem changes part of a service item's current deployed item - one field of a
+// large record, say - without the caller reading, decoding and writing back the whole thing.
+// The patch is a MergePatch or a JSONPatch:
+//
+//	item, err := nc.PatchDeployedItem(ctx, pov, 389, client.MergePatch(`{"pool":{"members":4}}`))
+//	item, err := nc.PatchDeployedItem(ctx, pov, 389, client.JSONPatch{
+//		{Op: client.PatchReplace, Path: "/pool/members", Value: json.RawMessage(`4`)},
+//	})
+//
+// The patch is applied to the current version's data and written back over that version in
+// place, like UpdateDeployedItem. The API has no conditional write, so the current version is
+// read again just before writing: if another writer has cut a new version or rewritten this one
+// since - its Version or Modified has moved - the patch is applied afresh to what they wrote.
+// After a few lost races it gives up with an error wrapping ErrConcurrentModification. The race
+// is narrowed, not closed: a write landing between the second read and this one's is lost.
+//
+// A patch that changes nothing is not written, and the current item is returned as read. It
+// returns an error wrapping ErrNotFound when the service item has no deployed item to patch.
+func (c *Client) PatchDeployedItem(
+	ctx context.Context,
+	pov POV,
+	serviceItemID int,
+	patch DocumentPatch,
+) (*DeployedItem, error) {
+	if patch == nil {
+		return nil, errors.New("a deployed item patch is required")
+	}
+
+	current, err := c.FindDeployedItemForServiceItem(ctx, pov, serviceItemID)
+	if err != nil {
+		return nil, err
+	}
+	for range deployedItemPatchAttempts {
+		patched, err := patch.Apply(current.Data)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to patch deployed item version %d of service item %d: %w", current.Version, serviceItemID, err,
+			)
+		}
+		changes, err := DiffJSON(current.Data, patched)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare deployed item data: %w", err)
+		}
+		if len(changes) == 0 {
+			return current, nil
+		}
+
+		latest, err := c.FindDeployedItemForServiceItem(ctx, pov, serviceItemID)
+		if err != nil {
+			return nil, err
+		}
+		if latest.ID != current.ID || latest.Version != current.Version || !latest.Modified.Equal(current.Modified) {
+			current = latest
+			continue
+		}
+		return c.rewriteDeployedItem(ctx, pov, latest, patched)
+	}
+	return nil, fmt.Errorf(
+		"%w: the deployed item of service item %d changed on each of %d attempts to patch it",
+		ErrConcurrentModification, serviceItemID, deployedItemPatchAttempts,
+	)
+}
@@ -0,0 +1,112 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deployedItemListing serves service item 389's current deployed item as the writers left it at
+// each read, one listing per call; the last repeats once they run out.
+func deployedItemListing(versions ...string) httpmock.Responder {
+	calls := 0
+	return func(*http.Request) (*http.Response, error) {
+		version := versions[min(calls, len(versions)-1)]
+		calls++
+		return httpmock.NewStringResponse(200, `{"count":1,"results":[`+version+`]}`), nil
+	}
+}
+
+// modifiedDeployedItem renders version 3 of the deployed item as last written at the given minute.
+func modifiedDeployedItem(minute int, data string) string {
+	return fmt.Sprintf(`{"id":7412,"version":3,"data":%s,"service_item":"%s","modified":"2026-07-19T11:%02d:00Z"}`,
+		data, serviceItemLink, minute)
+}
+
+func TestPatchDeployedItem(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	item := modifiedDeployedItem(1, `{"vip":"10.0.0.7","pool":{"members":2}}`)
+	httpmock.RegisterResponder("GET", deployedItemsRoot+"/", deployedItemListing(item))
+	var capturedBody string
+	httpmock.RegisterResponder("PATCH", deployedItemDetail, captureBodyResponder(&capturedBody, 200, item))
+	nc := newDeployedItemTestClient(t)
+
+	_, err := nc.PatchDeployedItem(context.Background(), client.POVServiceOwner, 389,
+		client.MergePatch(`{"pool":{"members":4}}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":{"vip":"10.0.0.7","pool":{"members":4}},"service_item":"`+serviceItemLink+`"}`,
+		capturedBody)
+	assert.Equal(t, 3, httpmock.GetTotalCallCount(), "read, re-read, write")
+}
+
+func TestPatchDeployedItemRetriesAfterAConcurrentWrite(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	// Another writer changes the vip between the first read and the re-read before writing.
+	httpmock.RegisterResponder("GET", deployedItemsRoot+"/", deployedItemListing(
+		modifiedDeployedItem(1, `{"vip":"10.0.0.7","pool":{"members":2}}`),
+		modifiedDeployedItem(2, `{"vip":"10.0.0.9","pool":{"members":2}}`),
+	))
+	var capturedBody string
+	httpmock.RegisterResponder("PATCH", deployedItemDetail, captureBodyResponder(&capturedBody, 200, oneDeployedItem))
+	nc := newDeployedItemTestClient(t)
+
+	_, err := nc.PatchDeployedItem(context.Background(), client.POVServiceOwner, 389, client.JSONPatch{
+		{Op: client.PatchReplace, Path: "/pool/members", Value: json.RawMessage(`4`)},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":{"vip":"10.0.0.9","pool":{"members":4}},"service_item":"`+serviceItemLink+`"}`,
+		capturedBody, "the patch is applied to the other writer's data, not over it")
+	assert.Equal(t, 4, httpmock.GetTotalCallCount())
+}
+
+func TestPatchDeployedItemGivesUp(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var versions []string
+	for minute := range 10 {
+		versions = append(versions, modifiedDeployedItem(minute, `{"pool":{"members":2}}`))
+	}
+	httpmock.RegisterResponder("GET", deployedItemsRoot+"/", deployedItemListing(versions...))
+	nc := newDeployedItemTestClient(t)
+
+	_, err := nc.PatchDeployedItem(context.Background(), client.POVServiceOwner, 389,
+		client.MergePatch(`{"pool":{"members":4}}`))
+	require.ErrorIs(t, err, client.ErrConcurrentModification)
+	assert.ErrorContains(t, err, "changed on each of 5 attempts")
+	assert.Equal(t, 6, httpmock.GetTotalCallCount(), "nothing is written")
+}
+
+func TestPatchDeployedItemWithoutAChange(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", deployedItemsRoot+"/", deployedItemListing(oneDeployedItem))
+	nc := newDeployedItemTestClient(t)
+	ctx := context.Background()
+
+	item, err := nc.PatchDeployedItem(ctx, client.POVServiceOwner, 389, client.MergePatch(`{"partition":"demo"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 7412, item.ID)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount(), "a patch that changes nothing is not written")
+
+	_, err = nc.PatchDeployedItem(ctx, client.POVServiceOwner, 389, client.JSONPatch{
+		{Op: client.PatchTest, Path: "/vip", Value: json.RawMessage(`"10.0.0.8"`)},
+	})
+	require.EqualError(t, err, `failed to patch deployed item version 3 of service item 389: `+
+		`JSON patch operation 1 (test "/vip"): the value at "/vip" is not "10.0.0.8"`)
+
+	_, err = nc.PatchDeployedItem(ctx, client.POVServiceOwner, 389, nil)
+	require.EqualError(t, err, "a deployed item patch is required")
+}
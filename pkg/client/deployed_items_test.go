@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -379,6 +381,128 @@ func TestFindDeployedItemForServiceItem(t *testing.T) { //nolint:funlen
 	})
 }
 
+func TestFindDeployedItemForServiceItemOrdering(t *testing.T) { //nolint:funlen
+	// history serves versions 1 to 50 of service item 389's deployed item, sorted as asked when
+	// sorts is set and in creation order otherwise, honouring the limit either way.
+	history := func(sorts bool, queries *[]string) httpmock.Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			*queries = append(*queries, req.URL.RawQuery)
+			var versions []string
+			for v := 1; v <= 50; v++ {
+				versions = append(versions, fmt.Sprintf(`{"id":%d,"version":%d}`, 7000+v, v))
+			}
+			if sorts && req.URL.Query().Get("ordering") == "-version" {
+				slices.Reverse(versions)
+			}
+			if limit, _ := strconv.Atoi(req.URL.Query().Get("limit")); limit > 0 && limit < len(versions) {
+				versions = versions[:limit]
+			}
+			return httpmock.NewStringResponse(200, `{"count":50,"results":[`+joinJSON(versions)+`]}`), nil
+		}
+	}
+
+	t.Run("asks for the highest version once the server has shown it sorts", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var queries []string
+		httpmock.RegisterResponder("GET", deployedItemsRoot+"/", history(true, &queries))
+		nc := newDeployedItemTestClient(t)
+
+		for range 2 {
+			item, err := nc.FindDeployedItemForServiceItem(context.Background(), client.POVServiceOwner, 389)
+			require.NoError(t, err)
+			assert.Equal(t, 50, item.Version)
+		}
+		require.Len(t, queries, 2, "one request per lookup")
+		assert.Contains(t, queries[0], "limit=20", "the first lookup checks a sample page")
+		assert.Contains(t, queries[1], "limit=1")
+		assert.Contains(t, queries[1], "ordering=-version")
+
+		queries = nil
+		_, err := newDeployedItemTestClient(t).FindDeployedItemForServiceItem(
+			context.Background(), client.POVServiceOwner, 389)
+		require.NoError(t, err)
+		assert.Contains(t, queries[0], "limit=20", "what a client learns is its own")
+	})
+
+	t.Run("scans when the server ignores the ordering", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var queries []string
+		httpmock.RegisterResponder("GET", deployedItemsRoot+"/", history(false, &queries))
+		nc := newDeployedItemTestClient(t)
+
+		for range 2 {
+			item, err := nc.FindDeployedItemForServiceItem(context.Background(), client.POVServiceOwner, 389)
+			require.NoError(t, err)
+			assert.Equal(t, 50, item.Version)
+		}
+		require.Len(t, queries, 3, "the probe, then a scan per lookup")
+		assert.NotContains(t, queries[2], "ordering", "the probe is not repeated")
+	})
+
+	t.Run("scans when the server rejects the ordering", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var queries []string
+		scan := history(false, &queries)
+		httpmock.RegisterResponder("GET", deployedItemsRoot+"/", func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Has("ordering") {
+				queries = append(queries, req.URL.RawQuery)
+				return httpmock.NewStringResponse(400, `{"ordering":["Invalid ordering."]}`), nil
+			}
+			return scan(req)
+		})
+		nc := newDeployedItemTestClient(t)
+
+		for range 2 {
+			item, err := nc.FindDeployedItemForServiceItem(context.Background(), client.POVServiceOwner, 389)
+			require.NoError(t, err)
+			assert.Equal(t, 50, item.Version)
+		}
+		assert.Len(t, queries, 3)
+	})
+
+	t.Run("scans without giving up on the ordering when the probe fails otherwise", func(t *testing.T) {
+		for name, failure := range map[string]httpmock.Responder{
+			"another 400":  httpmock.NewStringResponder(400, `{"service_item":["Select a valid choice."]}`),
+			"server error": httpmock.NewStringResponder(503, "unavailable"),
+		} {
+			t.Run(name, func(t *testing.T) {
+				httpmock.Activate()
+				defer httpmock.DeactivateAndReset()
+
+				var queries []string
+				probes := 0
+				scan := history(true, &queries)
+				httpmock.RegisterResponder("GET", deployedItemsRoot+"/", func(req *http.Request) (*http.Response, error) {
+					if req.URL.Query().Has("ordering") {
+						if probes++; probes == 1 {
+							queries = append(queries, req.URL.RawQuery)
+							return failure(req)
+						}
+					}
+					return scan(req)
+				})
+				nc := newDeployedItemTestClient(t)
+
+				for range 2 {
+					item, err := nc.FindDeployedItemForServiceItem(context.Background(), client.POVServiceOwner, 389)
+					require.NoError(t, err)
+					assert.Equal(t, 50, item.Version)
+				}
+				require.Len(t, queries, 3, "the failed probe, a scan, then a fresh probe")
+				assert.NotContains(t, queries[1], "ordering")
+				assert.Contains(t, queries[2], "ordering=-version", "the failure is not remembered")
+				assert.Contains(t, queries[2], "limit=20")
+			})
+		}
+	})
+}
+
 func TestCreateDeployedItem(t *testing.T) { //nolint:funlen
 	t.Run("sends the service item as a hyperlink, not an id", func(t *testing.T) {
 		httpmock.Activate()
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// TypedDeployedItem is a deployed item with its freeform data decoded into the service owner's
+// own type. The embedded DeployedItem keeps the record as the platform sent it, raw data
+// included (as DeployedItem.Data); Data here shadows it with the decoded value.
+type TypedDeployedItem[T any] struct {
+	DeployedItem
+	// Data is the decoded data. It is T's zero value for an item recorded with no data.
+	Data T
+}
+
+// DecodeDeployedItem decodes a deployed item's data into T, with the same options and
+// validation as DecodeDeclaration: Strict reports fields T does not declare, and a Validate
+// method on T is run unless SkipValidation is passed. Failures are *DeclarationError values
+// naming the deployed item, wrapping ErrInvalidDeclaration.
+func DecodeDeployedItem[T any](item *DeployedItem, opts ...DecodeOption) (*TypedDeployedItem[T], error) {
+	value, err := decodeDeclaration[T](
+		item.Data, fmt.Sprintf("data of deployed item %d", item.ID), newDecodeSettings(opts),
+	)
+	if err != nil {
+		return nil, err
+	}
+	typed := &TypedDeployedItem[T]{DeployedItem: *item}
+	if value != nil {
+		typed.Data = *value
+	}
+	return typed, nil
+}
+
+// GetDeployedItemAs is GetDeployedItem with the data decoded into T.
+func GetDeployedItemAs[T any](
+	ctx context.Context,
+	c *Client,
+	pov POV,
+	id int,
+	opts ...DecodeOption,
+) (*TypedDeployedItem[T], error) {
+	item, err := c.GetDeployedItem(ctx, pov, id)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeDeployedItem[T](item, opts...)
+}
+
+// FindDeployedItemForServiceItemAs is FindDeployedItemForServiceItem with the data decoded into
+// T. As there, a service item that has never had a deployed item is an error wrapping
+// ErrNotFound.
+func FindDeployedItemForServiceItemAs[T any](
+	ctx context.Context,
+	c *Client,
+	pov POV,
+	serviceItemID int,
+	opts ...DecodeOption,
+) (*TypedDeployedItem[T], error) {
+	item, err := c.FindDeployedItemForServiceItem(ctx, pov, serviceItemID)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeDeployedItem[T](item, opts...)
+}
+
+// CreateDeployedItemFrom is CreateDeployedItem with the data encoded from a value of T. The
+// parent comes from body, whose own Data must be left empty; the stored item is decoded back,
+// so the result reflects what the platform kept.
+func CreateDeployedItemFrom[T any](
+	ctx context.Context,
+	c *Client,
+	pov POV,
+	body *DeployedItemWrite,
+	data T,
+) (*TypedDeployedItem[T], error) {
+	if body == nil {
+		return nil, errors.New("a deployed item body is required")
+	}
+	if len(body.Data) > 0 {
+		return nil, errors.New("the deployed item data is set twice: leave body.Data empty")
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the deployed item: %w", err)
+	}
+
+	write := *body
+	write.Data = raw
+	item, err := c.CreateDeployedItem(ctx, pov, &write)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeDeployedItem[T](item, SkipValidation())
+}
@@ -0,0 +1,127 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadBalancer is a service owner's own schema for the deployed item data in oneDeployedItem.
+type loadBalancer struct {
+	VIP       string `json:"vip"`
+	Partition string `json:"partition"`
+}
+
+func (lb *loadBalancer) Validate() error {
+	if lb.VIP == "" {
+		return client.NewFieldError("$.vip", "is required")
+	}
+	return nil
+}
+
+func TestGetDeployedItemAs(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", deployedItemDetail, httpmock.NewStringResponder(200, oneDeployedItem))
+	nc := newDeployedItemTestClient(t)
+
+	item, err := client.GetDeployedItemAs[loadBalancer](context.Background(), nc, client.POVServiceOwner, 7412)
+	require.NoError(t, err)
+	assert.Equal(t, loadBalancer{VIP: "10.0.0.7", Partition: "demo"}, item.Data)
+	assert.Equal(t, 3, item.Version)
+	assert.JSONEq(t, `{"vip": "10.0.0.7", "partition": "demo"}`, string(item.DeployedItem.Data))
+
+	type vipOnly struct {
+		VIP string `json:"vip"`
+	}
+	_, err = client.GetDeployedItemAs[vipOnly](context.Background(), nc, client.POVServiceOwner, 7412, client.Strict())
+	require.ErrorIs(t, err, client.ErrInvalidDeclaration)
+	assert.EqualError(t, err, "netorca: data of deployed item 7412: $.partition: unknown field")
+}
+
+func TestDecodeDeployedItem(t *testing.T) {
+	empty, err := client.DecodeDeployedItem[loadBalancer](&client.DeployedItem{ID: 1, Data: json.RawMessage(`{}`)},
+		client.SkipValidation())
+	require.NoError(t, err)
+	assert.Equal(t, loadBalancer{}, empty.Data)
+
+	_, err = client.DecodeDeployedItem[loadBalancer](&client.DeployedItem{ID: 1, Data: json.RawMessage(`{}`)})
+	var fieldErr *client.FieldError
+	require.True(t, errors.As(err, &fieldErr), "Validate runs on the decoded data")
+	assert.Equal(t, "$.vip", fieldErr.Path)
+
+	none, err := client.DecodeDeployedItem[loadBalancer](&client.DeployedItem{ID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, loadBalancer{}, none.Data, "no data at all decodes to the zero value")
+}
+
+func TestFindDeployedItemForServiceItemAs(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", deployedItemsRoot+"/", httpmock.NewStringResponder(200,
+		`{"count":2,"results":[{"id":7000,"version":1,"data":{"vip":"10.0.0.1"}},`+
+			`{"id":7412,"version":3,"data":{"vip":"10.0.0.7"}}]}`))
+	nc := newDeployedItemTestClient(t)
+
+	item, err := client.FindDeployedItemForServiceItemAs[loadBalancer](
+		context.Background(), nc, client.POVServiceOwner, 389)
+	require.NoError(t, err)
+	assert.Equal(t, 7412, item.ID)
+	assert.Equal(t, "10.0.0.7", item.Data.VIP)
+}
+
+func TestCreateDeployedItemFrom(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var capturedBody string
+	httpmock.RegisterResponder("POST", deployedItemsRoot+"/",
+		captureBodyResponder(&capturedBody, 201, oneDeployedItem))
+	nc := newDeployedItemTestClient(t)
+
+	item, err := client.CreateDeployedItemFrom(context.Background(), nc, client.POVServiceOwner,
+		&client.DeployedItemWrite{ServiceItemID: 389}, loadBalancer{VIP: "10.0.0.7", Partition: "demo"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":{"vip":"10.0.0.7","partition":"demo"},"service_item":"`+serviceItemLink+`"}`,
+		capturedBody)
+	assert.Equal(t, "demo", item.Data.Partition)
+
+	_, err = client.CreateDeployedItemFrom(context.Background(), nc, client.POVServiceOwner,
+		&client.DeployedItemWrite{ServiceItemID: 389, Data: json.RawMessage(`{}`)}, loadBalancer{})
+	require.EqualError(t, err, "the deployed item data is set twice: leave body.Data empty")
+	assert.Equal(t, 1, httpmock.GetTotalCallCount())
+}
+
+func TestWithDeployedItem(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var capturedBody string
+	httpmock.RegisterResponder("PATCH", changeInstancesRoot+"/53/",
+		captureBodyResponder(&capturedBody, 200, `{"id":53,"state":"COMPLETED"}`))
+	nc := newPackTestClient(t)
+	ctx := context.Background()
+
+	_, err := nc.CompleteChangeInstanceWithContext(ctx, client.POVServiceOwner, 53, "built", nil,
+		client.WithDeployedItem(loadBalancer{VIP: "10.0.0.7"}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"state":"COMPLETED","log":"built","deployed_item":{"vip":"10.0.0.7","partition":""}}`,
+		capturedBody)
+
+	_, err = nc.CompleteChangeInstanceWithContext(ctx, client.POVServiceOwner, 53, "built",
+		json.RawMessage(`{}`), client.WithDeployedItem(loadBalancer{}))
+	require.ErrorContains(t, err, "the deployed item is set twice")
+
+	_, err = nc.CompleteChangeInstanceWithContext(ctx, client.POVServiceOwner, 53, "built", nil,
+		client.WithDeployedItem(func() {}))
+	require.ErrorContains(t, err, "failed to encode the deployed item")
+	assert.Equal(t, 1, httpmock.GetTotalCallCount(), "neither mistake reaches the server")
+}
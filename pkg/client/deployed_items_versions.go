@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ListDeployedItemVersions returns the whole history of a service item's deployed item, oldest
+// version first, so the last element is the current one FindDeployedItemForServiceItem would
+// return. It pages through every version rather than trusting the server's ordering, and
+// returns an empty slice, not an error, for a service item that has never had a deployed item.
+func (c *Client) ListDeployedItemVersions(
+	ctx context.Context,
+	pov POV,
+	serviceItemID int,
+) ([]DeployedItem, error) {
+	history, err := c.listDeployedItemHistory(ctx, pov, serviceItemID)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to list the deployed item versions of service item %d: %w", serviceItemID, err,
+		)
+	}
+
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Version < history[j].Version })
+	return history, nil
+}
+
+// DiffDeployedItemVersions returns the RFC 6902 JSON Patch that turns one version's data into
+// another's - what changed on the ground between two deployments. Pass the older version as
+// from; the patch is empty when the two recorded the same thing, however it was formatted.
+// The versions need not belong to the same service item, though they usually will.
+func DiffDeployedItemVersions(from, to *DeployedItem) (JSONPatch, error) {
+	if from == nil || to == nil {
+		return nil, errors.New("two deployed items are required to diff")
+	}
+
+	patch, err := DiffJSON(from.Data, to.Data)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to diff deployed item version %d against version %d: %w", from.Version, to.Version, err,
+		)
+	}
+	return patch, nil
+}
+
+// RollbackDeployedItem re-records an earlier version's data against the service item as a new
+// version, and returns the item the platform stored. History is never rewritten: rolling back
+// from version 5 to version 3 creates version 6 with version 3's data, so the record of what
+// was deployed in between survives.
+//
+// This leans on the platform's "no change detected" rule. A rollback to data the current
+// version already holds - rolling back to the current version, or repeating a rollback - is
+// answered with the existing current item rather than a new version, so it is safe to retry.
+// Compare the returned Version with the one you had to tell the two apart.
+//
+// It returns an error wrapping ErrNotFound when the service item has no such version.
+func (c *Client) RollbackDeployedItem(
+	ctx context.Context,
+	pov POV,
+	serviceItemID int,
+	version int,
+) (*DeployedItem, error) {
+	if version <= 0 {
+		return nil, fmt.Errorf("deployed item versions count from 1, so there is no version %d", version)
+	}
+
+	history, err := c.ListDeployedItemVersions(ctx, pov, serviceItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *DeployedItem
+	for index := range history {
+		if history[index].Version == version {
+			target = &history[index]
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf(
+			"%w: service item %d has no deployed item version %d", ErrNotFound, serviceItemID, version,
+		)
+	}
+
+	item, err := c.CreateDeployedItem(ctx, pov, &DeployedItemWrite{
+		ServiceItemID: serviceItemID,
+		Data:          target.Data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to roll service item %d back to deployed item version %d: %w", serviceItemID, version, err,
+		)
+	}
+	return item, nil
+}
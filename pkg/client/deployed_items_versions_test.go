@@ -0,0 +1,122 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deployedItemVersion renders one version of service item 389's deployed item.
+func deployedItemVersion(id, version int, data string) string {
+	return fmt.Sprintf(`{"id":%d,"version":%d,"data":%s,"service_item":"%s"}`, id, version, data, serviceItemLink)
+}
+
+// registerDeployedItemHistory serves three versions of service item 389's deployed item, out of order.
+func registerDeployedItemHistory() {
+	httpmock.RegisterResponder("GET", deployedItemsRoot+"/", httpmock.NewStringResponder(200,
+		`{"count":3,"results":[`+joinJSON([]string{
+			deployedItemVersion(7412, 3, `{"vip":"10.0.0.9","partition":"demo"}`),
+			deployedItemVersion(7000, 1, `{"vip":"10.0.0.7"}`),
+			deployedItemVersion(7200, 2, `{"vip":"10.0.0.7","partition":"demo"}`),
+		})+`]}`))
+}
+
+func TestListDeployedItemVersions(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerDeployedItemHistory()
+	nc := newDeployedItemTestClient(t)
+
+	history, err := nc.ListDeployedItemVersions(context.Background(), client.POVServiceOwner, 389)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	for i, item := range history {
+		assert.Equal(t, i+1, item.Version, "oldest first")
+	}
+	assert.Equal(t, 7412, history[2].ID)
+}
+
+func TestListDeployedItemVersionsEmpty(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", deployedItemsRoot+"/",
+		httpmock.NewStringResponder(200, `{"count":0,"results":[]}`))
+	nc := newDeployedItemTestClient(t)
+
+	history, err := nc.ListDeployedItemVersions(context.Background(), client.POVServiceOwner, 389)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestDiffDeployedItemVersions(t *testing.T) {
+	v1 := &client.DeployedItem{Version: 1, Data: json.RawMessage(`{"vip":"10.0.0.7"}`)}
+	v2 := &client.DeployedItem{Version: 2, Data: json.RawMessage(`{"partition":"demo", "vip":"10.0.0.9"}`)}
+
+	patch, err := client.DiffDeployedItemVersions(v1, v2)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(patch)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op":"add","path":"/partition","value":"demo"},
+		{"op":"replace","path":"/vip","value":"10.0.0.9"}
+	]`, string(encoded))
+
+	same := &client.DeployedItem{Version: 3, Data: json.RawMessage(`{ "vip": "10.0.0.7" }`)}
+	patch, err = client.DiffDeployedItemVersions(v1, same)
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+
+	broken := &client.DeployedItem{Version: 4, Data: json.RawMessage(`{`)}
+	_, err = client.DiffDeployedItemVersions(v1, broken)
+	assert.ErrorContains(t, err, "failed to diff deployed item version 1 against version 4")
+
+	_, err = client.DiffDeployedItemVersions(nil, v1)
+	assert.EqualError(t, err, "two deployed items are required to diff")
+}
+
+func TestRollbackDeployedItem(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerDeployedItemHistory()
+	var capturedBody string
+	httpmock.RegisterResponder("POST", deployedItemsRoot+"/", captureBodyResponder(&capturedBody, 201,
+		deployedItemVersion(7500, 4, `{"vip":"10.0.0.7","partition":"demo"}`)))
+	nc := newDeployedItemTestClient(t)
+
+	item, err := nc.RollbackDeployedItem(context.Background(), client.POVServiceOwner, 389, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 4, item.Version, "a rollback records a new version")
+	assert.JSONEq(t, `{"data":{"vip":"10.0.0.7","partition":"demo"},"service_item":"`+serviceItemLink+`"}`,
+		capturedBody)
+}
+
+func TestRollbackDeployedItemErrors(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	registerDeployedItemHistory()
+	httpmock.RegisterResponder("POST", deployedItemsRoot+"/", httpmock.NewStringResponder(400,
+		`{"detail":"bad data"}`))
+	nc := newDeployedItemTestClient(t)
+	ctx := context.Background()
+
+	_, err := nc.RollbackDeployedItem(ctx, client.POVServiceOwner, 389, 0)
+	require.EqualError(t, err, "deployed item versions count from 1, so there is no version 0")
+	assert.Equal(t, 0, httpmock.GetTotalCallCount())
+
+	_, err = nc.RollbackDeployedItem(ctx, client.POVServiceOwner, 389, 7)
+	require.ErrorIs(t, err, client.ErrNotFound)
+	assert.ErrorContains(t, err, "service item 389 has no deployed item version 7")
+
+	_, err = nc.RollbackDeployedItem(ctx, client.POVServiceOwner, 389, 1)
+	require.ErrorContains(t, err, "failed to roll service item 389 back to deployed item version 1")
+}
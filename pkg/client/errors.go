@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Sentinel errors for the HTTP status codes callers routinely branch on.
@@ -35,6 +38,149 @@ var (
 // It unwraps to ErrNotFound, so errors.Is(err, ErrNotFound) also matches.
 var ErrPackDataNotFound = fmt.Errorf("%w: pack data not found", ErrNotFound)
 
+// ErrInvalidTransition is the sentinel for a change instance transition the client refused to
+// send because the state machine does not allow it. The error carrying it is an
+// *InvalidTransitionError, which names the states that would have been accepted.
+var ErrInvalidTransition = errors.New("netorca: invalid change instance transition")
+
+// ErrBulkAborted marks the transitions of a bulk update that were never sent: those left when
+// a stop-on-error run hit its first failure, or every one when a preview found a problem.
+var ErrBulkAborted = errors.New("netorca: bulk update aborted")
+
+// ErrTransitionDeferred is the sentinel for a transition a gate held back - typically one that
+// falls outside a change window. The error carrying it is a *DeferredTransitionError.
+var ErrTransitionDeferred = errors.New("netorca: transition deferred")
+
+// ErrDependencyCycle is the sentinel for change instances that depend on each other in a loop,
+// so no processing order exists. The error carrying it is a *DependencyCycleError.
+var ErrDependencyCycle = errors.New("netorca: dependency cycle")
+
+// ErrChangeConflict is the sentinel for a change instance that may not move yet because an older
+// change on the same service item is unresolved. The error carrying it is a *ChangeConflictError.
+var ErrChangeConflict = errors.New("netorca: change conflict")
+
+// ErrConcurrentModification is the sentinel for a read-modify-write that kept losing the race:
+// the record changed between every read and the write that was to follow it.
+var ErrConcurrentModification = errors.New("netorca: concurrent modification")
+
+// InvalidTransitionError reports a transition rejected client-side, before any request was made.
+// Use errors.As to reach the allowed targets, or errors.Is against ErrInvalidTransition.
+type InvalidTransitionError struct {
+	// ID is the change instance that was to move.
+	ID int
+	// From is the state the change instance was in.
+	From ChangeInstanceState
+	// To is the state the caller asked for.
+	To ChangeInstanceState
+	// Allowed lists the states the change instance could have moved to instead. Empty when
+	// it is in a state nothing leaves.
+	Allowed []ChangeInstanceState
+	// Reason explains a refusal the table alone does not, such as a service that forbids
+	// manual approval. Empty otherwise.
+	Reason string
+}
+
+// Error implements the error interface, naming the allowed targets so the message alone is
+// enough to fix the call.
+func (e *InvalidTransitionError) Error() string {
+	allowed := "none"
+	if len(e.Allowed) > 0 {
+		names := make([]string, 0, len(e.Allowed))
+		for _, state := range e.Allowed {
+			names = append(names, string(state))
+		}
+		allowed = strings.Join(names, ", ")
+	}
+
+	message := fmt.Sprintf(
+		"netorca: change instance %d cannot move from %s to %s (allowed: %s)", e.ID, e.From, e.To, allowed,
+	)
+	if e.Reason != "" {
+		message += ": " + e.Reason
+	}
+	return message
+}
+
+// Unwrap returns ErrInvalidTransition, so errors.Is matches without a type assertion.
+func (e *InvalidTransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// DeferredTransitionError reports a transition held back by a TransitionGate. Nothing moved, but
+// the reason was written to the change's log, so the consumer can see why their change is
+// waiting. Use errors.As to reach the reason, or errors.Is against ErrTransitionDeferred.
+type DeferredTransitionError struct {
+	// ID is the change instance that was to move.
+	ID int
+	// To is the state it was to move to.
+	To ChangeInstanceState
+	// Reason explains the deferral, for the change's log.
+	Reason string
+	// Until is when the transition may next go ahead. Zero when the gate cannot say.
+	Until time.Time
+}
+
+// Error implements the error interface.
+func (e *DeferredTransitionError) Error() string {
+	return fmt.Sprintf("netorca: change instance %d: move to %s deferred: %s", e.ID, e.To, e.Reason)
+}
+
+// Unwrap returns ErrTransitionDeferred, so errors.Is matches without a type assertion.
+func (e *DeferredTransitionError) Unwrap() error {
+	return ErrTransitionDeferred
+}
+
+// DependencyCycleError reports a loop in a dependency graph. Use errors.As to reach the change
+// instances involved, or errors.Is against ErrDependencyCycle.
+type DependencyCycleError struct {
+	// Cycle lists the change instance ids around the loop, each one processed before the next,
+	// with the first repeated at the end.
+	Cycle []int
+}
+
+// Error implements the error interface, spelling the loop out so it can be broken by hand.
+func (e *DependencyCycleError) Error() string {
+	ids := make([]string, 0, len(e.Cycle))
+	for _, id := range e.Cycle {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	return "netorca: change instances depend on each other in a cycle: " + strings.Join(ids, " -> ")
+}
+
+// Unwrap returns ErrDependencyCycle, so errors.Is matches without a type assertion.
+func (e *DependencyCycleError) Unwrap() error {
+	return ErrDependencyCycle
+}
+
+// ChangeConflictError reports a transition refused because older change instances on the same
+// service item are still open: applying the newer change first would apply them out of order.
+// Use errors.As to reach the blocking changes, or errors.Is against ErrChangeConflict.
+type ChangeConflictError struct {
+	// ID is the change instance that was to move.
+	ID int
+	// To is the state it was to move to.
+	To ChangeInstanceState
+	// ServiceItemID is the service item the changes share.
+	ServiceItemID int
+	// Blocking lists the older unresolved change instance ids, oldest first.
+	Blocking []int
+}
+
+// Error implements the error interface.
+func (e *ChangeConflictError) Error() string {
+	ids := make([]string, len(e.Blocking))
+	for i, id := range e.Blocking {
+		ids[i] = strconv.Itoa(id)
+	}
+	return fmt.Sprintf("netorca: change instance %d: cannot move to %s before older change instances on service item %d"+
+		" are resolved: %s", e.ID, e.To, e.ServiceItemID, strings.Join(ids, ", "))
+}
+
+// Unwrap returns ErrChangeConflict, so errors.Is matches without a type assertion.
+func (e *ChangeConflictError) Unwrap() error {
+	return ErrChangeConflict
+}
+
 // APIError is returned for any non-2xx response. It carries the request that failed and the
 // server's own explanation, which for a NetOrca 400 is the validation payload callers need to
 // see. Use errors.As to reach the status code, or errors.Is against the sentinels above.
@@ -0,0 +1,470 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The operations of an RFC 6902 JSON Patch.
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+	PatchMove    = "move"
+	PatchCopy    = "copy"
+	PatchTest    = "test"
+)
+
+// PatchOperation is one operation of an RFC 6902 JSON Patch.
+type PatchOperation struct {
+	// Op is one of the Patch* constants.
+	Op string `json:"op"`
+	// Path is the RFC 6901 JSON pointer the operation targets; "" is the whole document.
+	Path string `json:"path"`
+	// From is the source pointer of a move or copy.
+	From string `json:"from,omitempty"`
+	// Value is the value added, substituted or tested. A JSON null is kept as "null", so it is
+	// still sent.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is an RFC 6902 JSON Patch: operations applied in order.
+type JSONPatch []PatchOperation
+
+// DiffJSON returns a JSON Patch that turns the from document into the to document. Object key
+// order is ignored, as are differences in how a number is written ("1.0" against "1"). Array
+// elements are compared by position, which keeps the patch simple to read: an element inserted
+// at the front shows as every later element being replaced.
+func DiffJSON(from, to json.RawMessage) (JSONPatch, error) {
+	fromTree, err := decodeJSONTree(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the original document: %w", err)
+	}
+	toTree, err := decodeJSONTree(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the target document: %w", err)
+	}
+
+	var patch JSONPatch
+	if err := diffJSONTrees("", fromTree, toTree, &patch); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// DocumentPatch is a change to a JSON document: a JSONPatch or a MergePatch.
+type DocumentPatch interface {
+	// Apply returns the document with the patch applied. The input is left untouched.
+	Apply(doc json.RawMessage) (json.RawMessage, error)
+}
+
+// Apply applies the operations to doc in order and returns the result, failing on the first
+// operation that cannot be applied - a "test" that does not hold included - so a patch applies
+// entirely or not at all. An empty document is treated as null.
+func (p JSONPatch) Apply(doc json.RawMessage) (json.RawMessage, error) {
+	tree, err := decodeJSONTree(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the document to patch: %w", err)
+	}
+	for i, op := range p {
+		if tree, err = applyPatchOperation(tree, op); err != nil {
+			return nil, fmt.Errorf("JSON patch operation %d (%s %q): %w", i+1, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(tree)
+}
+
+// MergePatch is an RFC 7396 JSON merge patch: a document shaped like the target, whose members
+// replace the target's, with null removing a member. It cannot reach into arrays - an array in
+// the patch replaces the target's whole - which is what JSONPatch is for.
+type MergePatch json.RawMessage
+
+// Apply merges the patch into doc and returns the result.
+func (p MergePatch) Apply(doc json.RawMessage) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(p)) == 0 {
+		return nil, fmt.Errorf("a merge patch cannot be empty")
+	}
+	patch, err := decodeJSONTree(json.RawMessage(p))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the merge patch: %w", err)
+	}
+	tree, err := decodeJSONTree(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the document to patch: %w", err)
+	}
+	return json.Marshal(mergeJSONTrees(tree, patch))
+}
+
+// mergeJSONTrees merges patch into target as RFC 7396 describes.
+func mergeJSONTrees(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeJSONTrees(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// applyPatchOperation applies one operation to a decoded document. Objects and arrays are
+// edited in place, so the returned tree shares them with the one passed in.
+func applyPatchOperation(tree any, op PatchOperation) (any, error) {
+	path, err := splitPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case PatchAdd, PatchReplace, PatchTest:
+		if len(op.Value) == 0 {
+			return nil, fmt.Errorf("a value is required")
+		}
+		value, err := decodeJSONTree(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode the value: %w", err)
+		}
+		switch op.Op {
+		case PatchAdd:
+			return addAtPointer(tree, path, value)
+		case PatchReplace:
+			if tree, _, err = removeAtPointer(tree, path); err != nil {
+				return nil, err
+			}
+			return addAtPointer(tree, path, value)
+		default:
+			current, ok := lookupJSONTree(tree, path)
+			if !ok {
+				return nil, fmt.Errorf("nothing at %q", op.Path)
+			}
+			if !jsonTreesEqual(current, value) {
+				return nil, fmt.Errorf("the value at %q is not %s", op.Path, op.Value)
+			}
+			return tree, nil
+		}
+	case PatchRemove:
+		tree, _, err = removeAtPointer(tree, path)
+		return tree, err
+	case PatchMove, PatchCopy:
+		from, err := splitPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if op.Op == PatchMove {
+			if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move %q into one of its own children", op.From)
+			}
+			if tree, value, err = removeAtPointer(tree, from); err != nil {
+				return nil, err
+			}
+		} else {
+			source, ok := lookupJSONTree(tree, from)
+			if !ok {
+				return nil, fmt.Errorf("nothing at %q", op.From)
+			}
+			value = copyJSONTree(source)
+		}
+		return addAtPointer(tree, path, value)
+	default:
+		return nil, fmt.Errorf("unknown operation")
+	}
+}
+
+// addAtPointer adds value where the tokens point: a member of an object is set, an element of
+// an array is inserted before the one at that index, or appended for "-".
+func addAtPointer(tree any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return editAtPointer(tree, tokens, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			container[token] = value
+			return container, nil
+		case []any:
+			index, err := arrayIndex(token, len(container), true)
+			if err != nil {
+				return nil, err
+			}
+			return slices.Insert(container, index, value), nil
+		default:
+			return nil, fmt.Errorf("cannot add %q to a scalar", token)
+		}
+	})
+}
+
+// removeAtPointer removes the value the tokens point to, and returns it. Removing the whole
+// document leaves null.
+func removeAtPointer(tree any, tokens []string) (any, any, error) {
+	if len(tokens) == 0 {
+		return nil, tree, nil
+	}
+	var removed any
+	tree, err := editAtPointer(tree, tokens, func(container any, token string) (any, error) {
+		switch container := container.(type) {
+		case map[string]any:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("no member %q", token)
+			}
+			removed = value
+			delete(container, token)
+			return container, nil
+		case []any:
+			index, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = container[index]
+			return slices.Delete(container, index, index+1), nil
+		default:
+			return nil, fmt.Errorf("cannot remove %q from a scalar", token)
+		}
+	})
+	return tree, removed, err
+}
+
+// editAtPointer walks to the container holding the tokens' last one and replaces it with what
+// edit returns, writing it back into its own parent - which an array that grew or shrank needs.
+func editAtPointer(tree any, tokens []string, edit func(container any, token string) (any, error)) (any, error) {
+	if len(tokens) == 1 {
+		return edit(tree, tokens[0])
+	}
+	switch container := tree.(type) {
+	case map[string]any:
+		child, ok := container[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("no member %q", tokens[0])
+		}
+		updated, err := editAtPointer(child, tokens[1:], edit)
+		if err != nil {
+			return nil, err
+		}
+		container[tokens[0]] = updated
+		return container, nil
+	case []any:
+		index, err := arrayIndex(tokens[0], len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := editAtPointer(container[index], tokens[1:], edit)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = updated
+		return container, nil
+	default:
+		return nil, fmt.Errorf("cannot look up %q in a scalar", tokens[0])
+	}
+}
+
+// arrayIndex parses an array reference token. "-", the position past the last element, is only
+// valid where an element is being added, as is an index equal to the length.
+func arrayIndex(token string, length int, adding bool) (int, error) {
+	if token == "-" && adding {
+		return length, nil
+	}
+	if !arrayIndexToken.MatchString(token) {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	if index > length || (index == length && !adding) {
+		return 0, fmt.Errorf("index %d is out of range for an array of %d", index, length)
+	}
+	return index, nil
+}
+
+// copyJSONTree returns a deep copy of a decoded document, so a copied value and its source do
+// not share objects or arrays.
+func copyJSONTree(tree any) any {
+	switch tree := tree.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(tree))
+		for key, value := range tree {
+			copied[key] = copyJSONTree(value)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(tree))
+		for i, value := range tree {
+			copied[i] = copyJSONTree(value)
+		}
+		return copied
+	default:
+		return tree
+	}
+}
+
+// decodeJSONTree decodes a document into maps, slices and json.Number values. An empty document
+// decodes to nil, like an explicit null.
+func decodeJSONTree(raw json.RawMessage) (any, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var tree any
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// diffJSONTrees appends to patch the operations turning from into to at the given pointer.
+func diffJSONTrees(pointer string, from, to any, patch *JSONPatch) error {
+	fromObject, fromIsObject := from.(map[string]any)
+	toObject, toIsObject := to.(map[string]any)
+	if fromIsObject && toIsObject {
+		keys := make([]string, 0, len(fromObject)+len(toObject))
+		for key := range fromObject {
+			keys = append(keys, key)
+		}
+		for key := range toObject {
+			if _, shared := fromObject[key]; !shared {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			child := pointer + "/" + escapePointerToken(key)
+			fromValue, inFrom := fromObject[key]
+			toValue, inTo := toObject[key]
+			switch {
+			case !inTo:
+				*patch = append(*patch, PatchOperation{Op: PatchRemove, Path: child})
+			case !inFrom:
+				if err := appendValueOperation(patch, PatchAdd, child, toValue); err != nil {
+					return err
+				}
+			default:
+				if err := diffJSONTrees(child, fromValue, toValue, patch); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	fromArray, fromIsArray := from.([]any)
+	toArray, toIsArray := to.([]any)
+	if fromIsArray && toIsArray {
+		shared := min(len(fromArray), len(toArray))
+		for i := range shared {
+			if err := diffJSONTrees(pointer+"/"+strconv.Itoa(i), fromArray[i], toArray[i], patch); err != nil {
+				return err
+			}
+		}
+		for i := shared; i < len(toArray); i++ {
+			if err := appendValueOperation(patch, PatchAdd, pointer+"/"+strconv.Itoa(i), toArray[i]); err != nil {
+				return err
+			}
+		}
+		// Remove from the end, so each index is still valid when its operation is applied.
+		for i := len(fromArray) - 1; i >= shared; i-- {
+			*patch = append(*patch, PatchOperation{Op: PatchRemove, Path: pointer + "/" + strconv.Itoa(i)})
+		}
+		return nil
+	}
+
+	if jsonTreesEqual(from, to) {
+		return nil
+	}
+	return appendValueOperation(patch, PatchReplace, pointer, to)
+}
+
+// appendValueOperation appends an operation carrying a value.
+func appendValueOperation(patch *JSONPatch, op, pointer string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode the value at %q: %w", pointer, err)
+	}
+	*patch = append(*patch, PatchOperation{Op: op, Path: pointer, Value: raw})
+	return nil
+}
+
+// jsonTreesEqual compares two decoded documents, treating numbers by value.
+func jsonTreesEqual(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !jsonTreesEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonTreesEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if a == b {
+			return true
+		}
+		af, aErr := a.Float64()
+		bf, bErr := b.Float64()
+		return aErr == nil && bErr == nil && af == bf
+	default:
+		return a == b
+	}
+}
+
+// escapePointerToken escapes one reference token of an RFC 6901 JSON pointer.
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// unescapePointerToken reverses escapePointerToken.
+func unescapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// splitPointer splits an RFC 6901 JSON pointer into its unescaped reference tokens. The empty
+// pointer, which names the whole document, has none.
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer %q must be empty or start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = unescapePointerToken(token)
+	}
+	return tokens, nil
+}
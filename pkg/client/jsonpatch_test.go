@@ -0,0 +1,192 @@
+package client_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want string
+	}{
+		{
+			name: "key order and number spelling are ignored",
+			from: `{"b":1.0,"a":{"y":[1,2],"x":"s"}}`,
+			to:   `{"a":{"x":"s","y":[1,2]},"b":1}`,
+			want: `null`,
+		},
+		{
+			name: "objects",
+			from: `{"cpu":2,"labels":{"env":"dev"},"name":"web"}`,
+			to:   `{"cpu":4,"disk":{"size":20},"name":"web"}`,
+			want: `[
+				{"op":"replace","path":"/cpu","value":4},
+				{"op":"add","path":"/disk","value":{"size":20}},
+				{"op":"remove","path":"/labels"}
+			]`,
+		},
+		{
+			name: "arrays grow at the end and shrink from the end",
+			from: `{"grow":[1],"shrink":[1,2,3]}`,
+			to:   `{"grow":[1,2,3],"shrink":[9]}`,
+			want: `[
+				{"op":"add","path":"/grow/1","value":2},
+				{"op":"add","path":"/grow/2","value":3},
+				{"op":"replace","path":"/shrink/0","value":9},
+				{"op":"remove","path":"/shrink/2"},
+				{"op":"remove","path":"/shrink/1"}
+			]`,
+		},
+		{
+			name: "pointer tokens are escaped and nulls are kept",
+			from: `{"a/b":1,"m~n":null}`,
+			to:   `{"a/b":null,"m~n":true}`,
+			want: `[
+				{"op":"replace","path":"/a~1b","value":null},
+				{"op":"replace","path":"/m~0n","value":true}
+			]`,
+		},
+		{
+			name: "a type change replaces the whole value",
+			from: `{"ports":[80]}`,
+			to:   `{"ports":{"http":80}}`,
+			want: `[{"op":"replace","path":"/ports","value":{"http":80}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := client.DiffJSON(json.RawMessage(tt.from), json.RawMessage(tt.to))
+			require.NoError(t, err)
+
+			encoded, err := json.Marshal(patch)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(encoded))
+		})
+	}
+
+	_, err := client.DiffJSON(json.RawMessage(`{`), json.RawMessage(`{}`))
+	require.Error(t, err)
+}
+
+func TestJSONPatchApply(t *testing.T) {
+	doc := `{"name":"web","pool":{"members":["a","b"]},"labels":{"env":"dev"}}`
+	tests := []struct {
+		name    string
+		patch   string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "add, replace and remove",
+			patch: `[
+				{"op":"add","path":"/cpu","value":4},
+				{"op":"replace","path":"/name","value":"web-1"},
+				{"op":"remove","path":"/labels/env"}
+			]`,
+			want: `{"cpu":4,"name":"web-1","pool":{"members":["a","b"]},"labels":{}}`,
+		},
+		{
+			name: "array insert, append and remove",
+			patch: `[
+				{"op":"add","path":"/pool/members/0","value":"z"},
+				{"op":"add","path":"/pool/members/-","value":"c"},
+				{"op":"remove","path":"/pool/members/1"}
+			]`,
+			want: `{"name":"web","pool":{"members":["z","b","c"]},"labels":{"env":"dev"}}`,
+		},
+		{
+			name: "move, copy and a passing test",
+			patch: `[
+				{"op":"test","path":"/pool/members/1","value":"b"},
+				{"op":"copy","from":"/labels","path":"/tags"},
+				{"op":"move","from":"/name","path":"/labels/name"}
+			]`,
+			want: `{"pool":{"members":["a","b"]},"labels":{"env":"dev","name":"web"},"tags":{"env":"dev"}}`,
+		},
+		{
+			name:  "the whole document",
+			patch: `[{"op":"replace","path":"","value":{"fresh":true}}]`,
+			want:  `{"fresh":true}`,
+		},
+		{
+			name:    "a failing test stops the patch",
+			patch:   `[{"op":"remove","path":"/name"},{"op":"test","path":"/pool/members/0","value":"b"}]`,
+			wantErr: `JSON patch operation 2 (test "/pool/members/0"): the value at "/pool/members/0" is not "b"`,
+		},
+		{
+			name:    "a missing member",
+			patch:   `[{"op":"replace","path":"/nope/deeper","value":1}]`,
+			wantErr: `JSON patch operation 1 (replace "/nope/deeper"): no member "nope"`,
+		},
+		{
+			name:    "an index past the end",
+			patch:   `[{"op":"add","path":"/pool/members/3","value":"x"}]`,
+			wantErr: `JSON patch operation 1 (add "/pool/members/3"): index 3 is out of range for an array of 2`,
+		},
+		{
+			name:    "a signed index",
+			patch:   `[{"op":"replace","path":"/pool/members/+1","value":"x"}]`,
+			wantErr: `JSON patch operation 1 (replace "/pool/members/+1"): "+1" is not an array index`,
+		},
+		{
+			name:    "a negative zero index",
+			patch:   `[{"op":"remove","path":"/pool/members/-0"}]`,
+			wantErr: `JSON patch operation 1 (remove "/pool/members/-0"): "-0" is not an array index`,
+		},
+		{
+			name:    "a move into its own child",
+			patch:   `[{"op":"move","from":"/pool","path":"/pool/inner"}]`,
+			wantErr: `JSON patch operation 1 (move "/pool/inner"): cannot move "/pool" into one of its own children`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patch client.JSONPatch
+			require.NoError(t, json.Unmarshal([]byte(tt.patch), &patch))
+
+			got, err := patch.Apply(json.RawMessage(doc))
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestJSONPatchApplyRoundTrip(t *testing.T) {
+	from := json.RawMessage(`{"a":[1,2,3],"b":{"c":"d"},"e":null}`)
+	to := json.RawMessage(`{"a":[1,5],"b":{"c":"x","f":[true]},"g":1}`)
+
+	patch, err := client.DiffJSON(from, to)
+	require.NoError(t, err)
+	got, err := patch.Apply(from)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(to), string(got), "applying a diff reproduces its target")
+	assert.JSONEq(t, `{"a":[1,2,3],"b":{"c":"d"},"e":null}`, string(from), "the input is untouched")
+}
+
+func TestMergePatchApply(t *testing.T) {
+	doc := json.RawMessage(`{"name":"web","pool":{"members":["a","b"],"lb":"rr"},"labels":{"env":"dev"}}`)
+
+	got, err := client.MergePatch(`{"pool":{"lb":"least-conn","members":["c"]},"labels":null,"cpu":4}`).Apply(doc)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name":"web","pool":{"members":["c"],"lb":"least-conn"},"cpu":4}`, string(got))
+
+	got, err = client.MergePatch(`{"pool":{"lb":null}}`).Apply(nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"pool":{}}`, string(got), "an empty document is merged into as an object")
+
+	_, err = client.MergePatch(``).Apply(doc)
+	require.EqualError(t, err, "a merge patch cannot be empty")
+}
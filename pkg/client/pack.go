@@ -33,6 +33,27 @@ const (
 	PackActionChangeInstanceValidator PackActionType = "change_instance_validator"
 )
 
+// IsKnown reports whether the action type is one this package models.
+func (a PackActionType) IsKnown() bool {
+	switch a {
+	case PackActionConfig, PackActionVerify, PackActionExecution,
+		PackActionOptimiser, PackActionChangeInstanceValidator:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON decodes the action type, keeping values this package does not know about so a
+// processor kind added to the platform later does not break a listing.
+func (a *PackActionType) UnmarshalJSON(data []byte) error {
+	value, err := decodeEnum(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode pack action type: %w", err)
+	}
+	*a = PackActionType(value)
+	return nil
+}
+
 // IsPipelineStage reports whether the action is one of the three stages that produce pack
 // data. Pack data cannot be read or pushed for the optimiser or validator processors.
 func (a PackActionType) IsPipelineStage() bool {
@@ -86,7 +107,7 @@ type PackData struct {
 	// Modified is the timestamp when the pack data was last modified.
 	Modified time.Time `json:"modified"`
 	// ActionType is the pipeline stage this data belongs to (config, verify or execution).
-	ActionType string `json:"action_type"`
+	ActionType PackActionType `json:"action_type"`
 	// Data is the AI-generated JSON payload for this stage - the field callers read.
 	Data json.RawMessage `json:"data"`
 	// ObjectID is the id of the scoped object (the service item) this data applies to.
@@ -125,20 +146,45 @@ func packDataPath(pov POV, scope PackScope, objectID int, action PackActionType)
 
 // GetPackConfig returns the latest "config" stage data for the given service item.
 // It returns ErrPackDataNotFound if the config stage has not produced data yet.
+//
+// Deprecated: Use GetPackConfigWithContext, which takes a context and a POV.
 func (c *Client) GetPackConfig(serviceItemID int) (*PackData, error) {
-	return c.GetPackData(context.Background(), POVServiceOwner, PackScopeServiceItem, serviceItemID, PackActionConfig)
+	return c.GetPackConfigWithContext(context.Background(), POVServiceOwner, serviceItemID)
 }
 
 // GetPackVerify returns the latest "verify" stage data for the given service item.
 // It returns ErrPackDataNotFound if the verify stage has not produced data yet.
+//
+// Deprecated: Use GetPackVerifyWithContext, which takes a context and a POV.
 func (c *Client) GetPackVerify(serviceItemID int) (*PackData, error) {
-	return c.GetPackData(context.Background(), POVServiceOwner, PackScopeServiceItem, serviceItemID, PackActionVerify)
+	return c.GetPackVerifyWithContext(context.Background(), POVServiceOwner, serviceItemID)
 }
 
 // GetPackExecution returns the latest "execution" stage data for the given service item.
 // It returns ErrPackDataNotFound if the execution stage has not produced data yet.
+//
+// Deprecated: Use GetPackExecutionWithContext, which takes a context and a POV.
 func (c *Client) GetPackExecution(serviceItemID int) (*PackData, error) {
-	return c.GetPackData(context.Background(), POVServiceOwner, PackScopeServiceItem, serviceItemID, PackActionExecution)
+	return c.GetPackExecutionWithContext(context.Background(), POVServiceOwner, serviceItemID)
+}
+
+// GetPackConfigWithContext returns the latest "config" stage data for a service item. It is
+// GetPackData with the service item scope and the config stage filled in, and likewise returns
+// ErrPackDataNotFound while the stage has produced nothing.
+func (c *Client) GetPackConfigWithContext(ctx context.Context, pov POV, serviceItemID int) (*PackData, error) {
+	return c.GetPackData(ctx, pov, PackScopeServiceItem, serviceItemID, PackActionConfig)
+}
+
+// GetPackVerifyWithContext returns the latest "verify" stage data for a service item, or
+// ErrPackDataNotFound while the stage has produced nothing.
+func (c *Client) GetPackVerifyWithContext(ctx context.Context, pov POV, serviceItemID int) (*PackData, error) {
+	return c.GetPackData(ctx, pov, PackScopeServiceItem, serviceItemID, PackActionVerify)
+}
+
+// GetPackExecutionWithContext returns the latest "execution" stage data for a service item, or
+// ErrPackDataNotFound while the stage has produced nothing.
+func (c *Client) GetPackExecutionWithContext(ctx context.Context, pov POV, serviceItemID int) (*PackData, error) {
+	return c.GetPackData(ctx, pov, PackScopeServiceItem, serviceItemID, PackActionExecution)
 }
 
 // GetPackData returns the latest data for one stage of a scoped object's pack pipeline.
@@ -315,10 +361,22 @@ func (c *Client) TriggerPack(
 // config stage. The optional serviceownerComment is passed to the AI processor as feedback (for
 // example, why a previous verify result was rejected); pass "" to send none. On success it returns
 // the confirmation message from the API (e.g. "AI Processor has been retriggered").
+//
+// Deprecated: Use RetriggerPackWithContext, which takes a context and a POV.
 func (c *Client) RetriggerPack(serviceItemID int, serviceownerComment string) (string, error) {
-	return c.RetriggerPackScoped(
-		context.Background(), POVServiceOwner, PackScopeServiceItem, serviceItemID, serviceownerComment,
-	)
+	return c.RetriggerPackWithContext(context.Background(), POVServiceOwner, serviceItemID, serviceownerComment)
+}
+
+// RetriggerPackWithContext re-runs a service item's pack pipeline from the config stage. It is
+// RetriggerPackScoped with the service item scope filled in; reach for that one to retrigger a
+// service-scoped pipeline.
+func (c *Client) RetriggerPackWithContext(
+	ctx context.Context,
+	pov POV,
+	serviceItemID int,
+	comment string,
+) (string, error) {
+	return c.RetriggerPackScoped(ctx, pov, PackScopeServiceItem, serviceItemID, comment)
 }
 
 // RetriggerPackScoped re-runs a scoped object's pack pipeline, always restarting from the config
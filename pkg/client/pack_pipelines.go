@@ -22,6 +22,31 @@ const (
 	PackPipelineWaitingForResponse PackPipelineState = "WAITING_FOR_RESPONSE"
 )
 
+// IsKnown reports whether the state is one this package models.
+func (s PackPipelineState) IsKnown() bool {
+	switch s {
+	case PackPipelineOK, PackPipelineFailed, PackPipelineScheduled, PackPipelineWaitingForResponse:
+		return true
+	}
+	return false
+}
+
+// IsTerminal reports whether the run has finished, successfully or not. A caller polling a run
+// after triggering it stops here; an unknown state is not terminal.
+func (s PackPipelineState) IsTerminal() bool {
+	return s == PackPipelineOK || s == PackPipelineFailed
+}
+
+// UnmarshalJSON decodes the state, keeping values this package does not know about.
+func (s *PackPipelineState) UnmarshalJSON(data []byte) error {
+	value, err := decodeEnum(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode pack pipeline state: %w", err)
+	}
+	*s = PackPipelineState(value)
+	return nil
+}
+
 // PackPipeline is one recorded run of the pack framework against a scoped object.
 //
 // Every field except Applied is produced by the platform and is read-only; Applied is the
@@ -35,7 +60,7 @@ type PackPipeline struct {
 	// the run you already saw.
 	Version int `json:"version"`
 	// State is the run's lifecycle state; compare against the PackPipeline* constants.
-	State string `json:"state"`
+	State PackPipelineState `json:"state"`
 	// CurrentStage is the stage the run has reached (config, verify or execution).
 	CurrentStage string `json:"current_stage"`
 	// Applied records whether an executor has acted on this run. The only writable field.
@@ -55,7 +55,7 @@ func TestListPackPipelines(t *testing.T) {
 		require.Len(t, resp.Results, 1)
 		assert.Equal(t, 2935, resp.Results[0].ID)
 		assert.False(t, resp.Results[0].Applied)
-		assert.Equal(t, string(client.PackPipelineOK), resp.Results[0].State)
+		assert.Equal(t, client.PackPipelineOK, resp.Results[0].State)
 
 		// The embedded stage data is what the executor deploys, and its scope is read back
 		// off the record rather than assumed.
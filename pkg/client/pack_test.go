@@ -55,7 +55,7 @@ func TestClientGetPackData(t *testing.T) { //nolint:funlen
 		pd, err := nc.GetPackConfig(42)
 		require.NoError(t, err)
 		require.NotNil(t, pd)
-		assert.Equal(t, "config", pd.ActionType)
+		assert.Equal(t, client.PackActionConfig, pd.ActionType)
 		assert.Equal(t, 501, pd.ID)
 		assert.JSONEq(t, `{"policy_name":"app42-waf","rules":[{"name":"block-sqli","action":"block"}]}`, string(pd.Data))
 	})
@@ -72,7 +72,7 @@ func TestClientGetPackData(t *testing.T) { //nolint:funlen
 		pd, err := nc.GetPackVerify(42)
 		require.NoError(t, err)
 		require.NotNil(t, pd)
-		assert.Equal(t, "verify", pd.ActionType)
+		assert.Equal(t, client.PackActionVerify, pd.ActionType)
 
 		// Mirror the real loop: decode Data and act on the verify result.
 		var verify struct {
@@ -95,7 +95,7 @@ func TestClientGetPackData(t *testing.T) { //nolint:funlen
 		pd, err := nc.GetPackExecution(42)
 		require.NoError(t, err)
 		require.NotNil(t, pd)
-		assert.Equal(t, "execution", pd.ActionType)
+		assert.Equal(t, client.PackActionExecution, pd.ActionType)
 		assert.JSONEq(t, `{"status":"deployed","target":"f5-bigip-01"}`, string(pd.Data))
 	})
 
@@ -177,6 +177,64 @@ func TestClientRetriggerPack(t *testing.T) {
 	})
 }
 
+func TestPackHelpersWithContext(t *testing.T) {
+	const consumerPack = packTestBaseURL + "/v1/external/consumer/pack"
+
+	t.Run("the stage getters honour the POV they are given", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		for _, stage := range []string{"config", "verify", "execution"} {
+			httpmock.RegisterResponder("GET", consumerPack+"/data/service_item/42/"+stage+"/",
+				httpmock.NewStringResponder(200, `{"id":1,"action_type":"`+stage+`"}`))
+		}
+
+		nc := newPackTestClient(t)
+		ctx := context.Background()
+
+		config, err := nc.GetPackConfigWithContext(ctx, client.POVConsumer, 42)
+		require.NoError(t, err)
+		assert.Equal(t, client.PackActionConfig, config.ActionType)
+
+		verify, err := nc.GetPackVerifyWithContext(ctx, client.POVConsumer, 42)
+		require.NoError(t, err)
+		assert.Equal(t, client.PackActionVerify, verify.ActionType)
+
+		execution, err := nc.GetPackExecutionWithContext(ctx, client.POVConsumer, 42)
+		require.NoError(t, err)
+		assert.Equal(t, client.PackActionExecution, execution.ActionType)
+	})
+
+	t.Run("a stage with no data yet is still ErrPackDataNotFound", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		httpmock.RegisterResponder("GET", packDataRoot+"/service_item/42/verify/",
+			httpmock.NewStringResponder(404, `{"detail":"Not found."}`))
+
+		nc := newPackTestClient(t)
+		_, err := nc.GetPackVerifyWithContext(context.Background(), "", 42)
+
+		require.ErrorIs(t, err, client.ErrPackDataNotFound)
+	})
+
+	t.Run("RetriggerPackWithContext sends the comment on the POV's route", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+
+		var capturedBody string
+		httpmock.RegisterResponder("POST", consumerPack+"/retrigger/service_item/42/",
+			captureBodyResponder(&capturedBody, 200, `"AI Processor has been retriggered"`))
+
+		nc := newPackTestClient(t)
+		msg, err := nc.RetriggerPackWithContext(context.Background(), client.POVConsumer, 42, "try again")
+
+		require.NoError(t, err)
+		assert.Equal(t, "AI Processor has been retriggered", msg)
+		assert.JSONEq(t, `{"serviceowner_comment":"try again"}`, capturedBody)
+	})
+}
+
 const packDataRoot = packTestBaseURL + "/v1/external/serviceowner/pack/data"
 
 // onePackDataRecord is a config stage payload as the API returns it, scope envelope and all.
@@ -250,7 +308,7 @@ func TestListPackData(t *testing.T) {
 		assert.Equal(t, "limit=5&offset=10&ordering=-created", capturedQuery)
 		assert.Equal(t, 1, resp.Count)
 		require.Len(t, resp.Results, 1)
-		assert.Equal(t, "config", resp.Results[0].ActionType)
+		assert.Equal(t, client.PackActionConfig, resp.Results[0].ActionType)
 	})
 
 	t.Run("sends no query string at all for the zero request", func(t *testing.T) {
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -211,6 +212,72 @@ func (q *queryParams) SetJSON(name string, value any) error {
 	return nil
 }
 
+// SetIntsOrString adds an id list filter that a request models twice: as a typed list, and as
+// the comma-joined string the older request fields take. Either may be used; both at once is
+// ambiguous and reported as an error rather than resolved by guessing.
+func (q *queryParams) SetIntsOrString(name string, values []int, legacy string) error {
+	if len(values) > 0 && legacy != "" {
+		return fmt.Errorf("the %s filter is set twice: use the typed list or the string, not both", name)
+	}
+	q.SetString(name, legacy)
+	q.SetInts(name, values)
+	return nil
+}
+
+// SetStringsOrString is SetIntsOrString for a list of names rather than ids.
+func (q *queryParams) SetStringsOrString(name string, values []string, legacy string) error {
+	if len(values) > 0 && legacy != "" {
+		return fmt.Errorf("the %s filter is set twice: use the typed list or the string, not both", name)
+	}
+	q.SetString(name, legacy)
+	q.SetStrings(name, values)
+	return nil
+}
+
+// SetJSONOrString adds a structured filter that may arrive either as a value to encode or as
+// JSON the caller already wrote by hand. As with the id lists, setting both is an error. A nil
+// pointer or map in the typed field counts as unset, as it would when sent.
+func (q *queryParams) SetJSONOrString(name string, value any, legacy string) error {
+	if !isNilFilter(value) && legacy != "" {
+		return fmt.Errorf("the %s filter is set twice: use the typed value or the string, not both", name)
+	}
+	q.SetString(name, legacy)
+	return q.SetJSON(name, value)
+}
+
+// isNilFilter reports whether a typed filter holds nothing: nil itself, or a nil pointer, map,
+// slice or interface stored in the any.
+func isNilFilter(value any) bool {
+	if value == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// SetDeclarationSearch adds the filters a DeclarationSearch renders. The request's own
+// declaration fields reach the same filters, so this runs after them and reports a filter set
+// both ways as an error.
+func (q *queryParams) SetDeclarationSearch(search *DeclarationSearch) error {
+	if search == nil {
+		return nil
+	}
+	rendered, err := search.Render()
+	if err != nil {
+		return err
+	}
+	for name, value := range rendered {
+		if q.values.Has(name) {
+			return fmt.Errorf("the %s filter is set twice: use the declaration query or the field, not both", name)
+		}
+		q.values.Set(name, value)
+	}
+	return nil
+}
+
 // Encode renders the parameters as a query string prefixed with "?", or "" when empty,
 // so it can be concatenated onto a path unconditionally.
 func (q *queryParams) Encode() string {
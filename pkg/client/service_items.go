@@ -4,15 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
-	"strconv"
+	"strings"
 	"time"
 )
 
 // GetServiceItemsRequest represents the filters for service items in the request.
+//
+// As with GetChangeInstancesRequest, the id and declaration filters exist both as the original
+// string fields and as typed fields beside them (ServiceIDs beside ServiceID). Use either form
+// for a given filter; setting both is reported as an error by ToQueryParams.
 type GetServiceItemsRequest struct {
-	// POV is the point of view for the service item (serviceowner, consumer)
-	POV string `json:"pov"`
+	// POV is the point of view for the service item (serviceowner, consumer). Defaults to
+	// serviceowner.
+	POV POV `json:"pov"`
 
 	// name is the name of the service item
 	Name string `json:"name"`
@@ -23,9 +27,14 @@ type GetServiceItemsRequest struct {
 	ChangeState string `json:"change_state"`
 	// declaration is the declaration of the service item
 	Declaration string `json:"declaration"`
+	// DeclarationFilter is the typed form of Declaration: a map or struct matched against
+	// declaration fields exactly, encoded as compact JSON.
+	DeclarationFilter any `json:"-"`
 
 	// application_id is the ID of the application
 	ApplicationID string `json:"application_id"`
+	// ApplicationIDs is the typed form of ApplicationID.
+	ApplicationIDs []int `json:"-"`
 	// application_name is the exact name of the application. Comma-join several to get an
 	// "in" lookup (an OR), the same convention the id filters above follow.
 	ApplicationName string `json:"application_name"`
@@ -35,20 +44,35 @@ type GetServiceItemsRequest struct {
 	ApplicationNameContains string `json:"application_name_contains"`
 	// consumer_team_id is the ID of the consumer team
 	ConsumerTeamID string `json:"consumer_team_id"`
+	// ConsumerTeamIDs is the typed form of ConsumerTeamID.
+	ConsumerTeamIDs []int `json:"-"`
 
 	// declaration_contains is the declaration contains of the service item
 	DeclarationContains string `json:"declaration_contains"`
+	// DeclarationContainsFilter is the typed form of DeclarationContains.
+	DeclarationContainsFilter any `json:"-"`
 	// declaration_regex is the declaration regex of the service item
 	DeclarationRegex string `json:"declaration_regex"`
+	// DeclarationRegexFilter is the typed form of DeclarationRegex.
+	DeclarationRegexFilter any `json:"-"`
+	// DeclarationQuery is a typed search across all three declaration filters, built with
+	// DeclarationQuery(). A filter it renders must not also be set through the fields above.
+	DeclarationQuery *DeclarationSearch `json:"-"`
 
 	// service_id is the ID of the service
 	ServiceID string `json:"service_id"`
+	// ServiceIDs is the typed form of ServiceID.
+	ServiceIDs []int `json:"-"`
 	// service_name is the name of the service
 	ServiceName string `json:"service_name"`
 	// service_owner_id is the ID of the service owner
 	ServiceOwnerID string `json:"service_owner_id"`
+	// ServiceOwnerIDs is the typed form of ServiceOwnerID.
+	ServiceOwnerIDs []int `json:"-"`
 	// service_owner_team_id is the ID of the service owner team
 	ServiceOwnerTeamID string `json:"service_owner_team_id"`
+	// ServiceOwnerTeamIDs is the typed form of ServiceOwnerTeamID.
+	ServiceOwnerTeamIDs []int `json:"-"`
 
 	// start_date restricts results to items modified at or after this time. Note that the
 	// backend applies both date bounds to modified rather than created, so this asks "changed
@@ -66,71 +90,39 @@ type GetServiceItemsRequest struct {
 }
 
 // ToQueryParams converts the GetServiceItemsRequest to a query string - keys are sorted alphabetically
-// and values are URL encoded.
-//
-//nolint:funlen // one flat branch per filter; splitting it would only hide which filters exist
+// and values are URL encoded. It fails when a filter is set in both its string and its typed form.
 func (f *GetServiceItemsRequest) ToQueryParams() (string, error) {
-	params := url.Values{}
+	params := newQueryParams()
 
-	if f.Name != "" {
-		params.Add("name", f.Name)
-	}
-	if f.RuntimeState != "" {
-		params.Add("runtime_state", f.RuntimeState)
-	}
-	if f.ChangeState != "" {
-		params.Add("change_state", f.ChangeState)
-	}
-	if f.Declaration != "" {
-		params.Add("declaration", f.Declaration)
-	}
-	if f.ApplicationID != "" {
-		params.Add("application_id", f.ApplicationID)
-	}
-	if f.ApplicationName != "" {
-		params.Add("application_name", f.ApplicationName)
-	}
-	if f.ApplicationNameContains != "" {
-		params.Add("application_name_contains", f.ApplicationNameContains)
-	}
-	if f.ConsumerTeamID != "" {
-		params.Add("consumer_team_id", f.ConsumerTeamID)
-	}
-	if f.DeclarationContains != "" {
-		params.Add("declaration_contains", f.DeclarationContains)
-	}
-	if f.DeclarationRegex != "" {
-		params.Add("declaration_regex", f.DeclarationRegex)
-	}
-	if f.ServiceID != "" {
-		params.Add("service_id", f.ServiceID)
-	}
-	if f.ServiceName != "" {
-		params.Add("service_name", f.ServiceName)
-	}
-	if f.ServiceOwnerID != "" {
-		params.Add("service_owner_id", f.ServiceOwnerID)
-	}
-	if f.ServiceOwnerTeamID != "" {
-		params.Add("service_owner_team_id", f.ServiceOwnerTeamID)
-	}
-	if !f.StartDate.IsZero() {
-		params.Add("start_date", f.StartDate.Format(time.RFC3339))
-	}
-	if !f.EndDate.IsZero() {
-		params.Add("end_date", f.EndDate.Format(time.RFC3339))
-	}
-	if f.Limit > 0 {
-		params.Add("limit", strconv.Itoa(f.Limit))
-	}
-	if f.Offset > 0 {
-		params.Add("offset", strconv.Itoa(f.Offset))
-	}
-	if f.Ordering != "" {
-		params.Add("ordering", f.Ordering)
+	for _, err := range []error{
+		params.SetIntsOrString("application_id", f.ApplicationIDs, f.ApplicationID),
+		params.SetIntsOrString("consumer_team_id", f.ConsumerTeamIDs, f.ConsumerTeamID),
+		params.SetIntsOrString("service_id", f.ServiceIDs, f.ServiceID),
+		params.SetIntsOrString("service_owner_id", f.ServiceOwnerIDs, f.ServiceOwnerID),
+		params.SetIntsOrString("service_owner_team_id", f.ServiceOwnerTeamIDs, f.ServiceOwnerTeamID),
+		params.SetJSONOrString("declaration", f.DeclarationFilter, f.Declaration),
+		params.SetJSONOrString("declaration_contains", f.DeclarationContainsFilter, f.DeclarationContains),
+		params.SetJSONOrString("declaration_regex", f.DeclarationRegexFilter, f.DeclarationRegex),
+		params.SetDeclarationSearch(f.DeclarationQuery),
+	} {
+		if err != nil {
+			return "", err
+		}
 	}
 
-	return params.Encode(), nil
+	params.SetString("name", f.Name)
+	params.SetString("runtime_state", f.RuntimeState)
+	params.SetString("change_state", f.ChangeState)
+	params.SetString("application_name", f.ApplicationName)
+	params.SetString("application_name_contains", f.ApplicationNameContains)
+	params.SetString("service_name", f.ServiceName)
+	params.SetTime("start_date", f.StartDate)
+	params.SetTime("end_date", f.EndDate)
+	params.SetInt("limit", f.Limit)
+	params.SetInt("offset", f.Offset)
+	params.SetString("ordering", f.Ordering)
+
+	return strings.TrimPrefix(params.Encode(), "?"), nil
 }
 
 // GetServiceItemsResponse represents the response for service items listing
@@ -141,6 +133,68 @@ type GetServiceItemsResponse struct {
 	Results  []ServiceItem `json:"results"`
 }
 
+// RuntimeState is whether a service item is live. Decoding keeps values this package does not
+// model, so check IsKnown rather than assuming the constants below are exhaustive.
+type RuntimeState string
+
+const (
+	// RuntimeStateInService means the item is live.
+	RuntimeStateInService RuntimeState = "IN_SERVICE"
+	// RuntimeStateOutOfService means the item exists but is not serving.
+	RuntimeStateOutOfService RuntimeState = "OUT_OF_SERVICE"
+)
+
+// IsKnown reports whether the runtime state is one this package models.
+func (s RuntimeState) IsKnown() bool {
+	switch s {
+	case RuntimeStateInService, RuntimeStateOutOfService:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON decodes the runtime state, keeping values this package does not know about.
+func (s *RuntimeState) UnmarshalJSON(data []byte) error {
+	value, err := decodeEnum(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode runtime state: %w", err)
+	}
+	*s = RuntimeState(value)
+	return nil
+}
+
+// ChangeState summarises where a service item's outstanding changes stand - whether the
+// declaration the consumer last submitted has been acted on yet.
+type ChangeState string
+
+const (
+	// ChangeStateOK means no changes are outstanding.
+	ChangeStateOK ChangeState = "OK"
+	// ChangeStateChangesPending means a change is waiting for the service owner's decision.
+	ChangeStateChangesPending ChangeState = "CHANGES_PENDING"
+	// ChangeStateChangesApproved means a change has been approved and awaits fulfilment.
+	ChangeStateChangesApproved ChangeState = "CHANGES_APPROVED"
+)
+
+// IsKnown reports whether the change state is one this package models.
+func (s ChangeState) IsKnown() bool {
+	switch s {
+	case ChangeStateOK, ChangeStateChangesPending, ChangeStateChangesApproved:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON decodes the change state, keeping values this package does not know about.
+func (s *ChangeState) UnmarshalJSON(data []byte) error {
+	value, err := decodeEnum(data)
+	if err != nil {
+		return fmt.Errorf("failed to decode change state: %w", err)
+	}
+	*s = ChangeState(value)
+	return nil
+}
+
 // ServiceItem represents a single service item in the response
 type ServiceItem struct {
 	ID                        int             `json:"id"`
@@ -148,13 +202,13 @@ type ServiceItem struct {
 	Name                      string          `json:"name"`
 	Created                   time.Time       `json:"created"`
 	Modified                  time.Time       `json:"modified"`
-	RuntimeState              string          `json:"runtime_state"`
+	RuntimeState              RuntimeState    `json:"runtime_state"`
 	Service                   Service         `json:"service"`
 	Application               Application     `json:"application"`
 	Related                   *string         `json:"related"`
 	ServiceOwnerTeam          Team            `json:"service_owner_team"`
 	ConsumerTeam              Team            `json:"consumer_team"`
-	ChangeState               string          `json:"change_state"`
+	ChangeState               ChangeState     `json:"change_state"`
 	DeployedItem              json.RawMessage `json:"deployed_item"`
 	Declaration               json.RawMessage `json:"declaration"`
 	HealthcheckStatus         *string         `json:"healthcheck_status"`
@@ -197,8 +251,7 @@ type Team struct {
 // Requires a POV (point of view) to be set in the filters.
 // The filters are used to filter the service items returned by the API.
 //
-// Prefer GetServiceItemsWithContext where a context is available - it lets the caller
-// cancel the request, which matters for anything long-running.
+// Deprecated: Use GetServiceItemsWithContext, which lets the caller cancel the request.
 func (c *Client) GetServiceItems(filters *GetServiceItemsRequest) (*GetServiceItemsResponse, error) {
 	return c.GetServiceItemsWithContext(context.Background(), filters)
 }
@@ -250,7 +303,7 @@ func (c *Client) listServiceItems(
 
 	// The trailing slash is the canonical DRF route; without it the API replies 301 to the
 	// slashed URL, doubling the round trips.
-	endpoint := fmt.Sprintf("orcabase/%s/service_items/%s", POV(filters.POV).orDefault(), action)
+	endpoint := fmt.Sprintf("orcabase/%s/service_items/%s", filters.POV.orDefault(), action)
 	if params != "" {
 		endpoint += "?" + params
 	}
@@ -87,6 +87,54 @@ func TestGetServiceItemsToQueryParams(t *testing.T) {
 		})
 	}
 }
+
+func TestGetServiceItemsTypedFilters(t *testing.T) {
+	t.Run("encodes typed ids and declaration filters", func(t *testing.T) {
+		request := &client.GetServiceItemsRequest{
+			ApplicationIDs:            []int{23, 24},
+			ServiceOwnerTeamIDs:       []int{4},
+			DeclarationFilter:         map[string]any{"environment": "prod"},
+			DeclarationContainsFilter: map[string]any{"tags": "web"},
+		}
+
+		query, err := request.ToQueryParams()
+
+		require.NoError(t, err)
+		assert.Equal(t,
+			"application_id=23%2C24&declaration=%7B%22environment%22%3A%22prod%22%7D"+
+				"&declaration_contains=%7B%22tags%22%3A%22web%22%7D&service_owner_team_id=4",
+			query)
+	})
+
+	t.Run("rejects a filter set in both forms", func(t *testing.T) {
+		request := &client.GetServiceItemsRequest{ServiceID: "4", ServiceIDs: []int{5}}
+
+		_, err := request.ToQueryParams()
+
+		require.ErrorContains(t, err, "service_id filter is set twice")
+	})
+
+	t.Run("a nil typed filter does not clash with the string", func(t *testing.T) {
+		type environment struct {
+			Environment string `json:"environment"`
+		}
+		var unset *environment
+		request := &client.GetServiceItemsRequest{
+			DeclarationFilter:         unset,
+			DeclarationContainsFilter: map[string]any(nil),
+			Declaration:               `{"environment":"prod"}`,
+			DeclarationContains:       `{"tags":"web"}`,
+		}
+
+		query, err := request.ToQueryParams()
+
+		require.NoError(t, err)
+		assert.Equal(t,
+			"declaration=%7B%22environment%22%3A%22prod%22%7D&declaration_contains=%7B%22tags%22%3A%22web%22%7D",
+			query)
+	})
+}
+
 func exampleServiceItem() *client.ServiceItem {
 	// Example response from the API: testdata/200_single_service_item_response.json
 	// created by hand to match the API response and validate marshaling and unmarshaling
@@ -369,7 +417,7 @@ func TestGetDependantServiceItems(t *testing.T) {
 
 		nc := newPackTestClient(t)
 		_, err := nc.GetDependantServiceItems(context.Background(), &client.GetServiceItemsRequest{
-			POV: string(client.POVConsumer),
+			POV: client.POVConsumer,
 		})
 
 		require.NoError(t, err)
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// SubmissionGroup is the change instances raised by one submission - one consumer commit - which
+// belong together: a commit touching ten service items means ten changes that were meant to land
+// as one.
+type SubmissionGroup struct {
+	// Submission is the submission the changes share.
+	Submission Submission
+	// ChangeInstances are its changes in the order they were raised (by id).
+	ChangeInstances []ChangeInstance
+}
+
+// GroupBySubmission groups change instances by submission, ordered by submission id.
+func GroupBySubmission(changes []ChangeInstance) []SubmissionGroup {
+	index := map[int]int{}
+	var groups []SubmissionGroup
+	for _, ci := range changes {
+		i, ok := index[ci.Submission.ID]
+		if !ok {
+			i = len(groups)
+			index[ci.Submission.ID] = i
+			groups = append(groups, SubmissionGroup{Submission: ci.Submission})
+		}
+		groups[i].ChangeInstances = append(groups[i].ChangeInstances, ci)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Submission.ID < groups[j].Submission.ID })
+	for _, group := range groups {
+		sort.Slice(group.ChangeInstances, func(i, j int) bool {
+			return group.ChangeInstances[i].ID < group.ChangeInstances[j].ID
+		})
+	}
+	return groups
+}
+
+// ListSubmissionGroups lists every change instance matching the filters, across all pages, and
+// groups them by submission. Filter on States to get the work waiting in each submission.
+//
+// Only the changes the filters match are grouped: a submission whose siblings are in other
+// states, or belong to another team's service, appears with just the matching ones.
+func (c *Client) ListSubmissionGroups(
+	ctx context.Context,
+	filters *GetChangeInstancesRequest,
+) ([]SubmissionGroup, error) {
+	changes, err := c.listAllChangeInstances(ctx, filters, "")
+	if err != nil {
+		return nil, err
+	}
+	return GroupBySubmission(changes), nil
+}
+
+// SubmissionSummary counts a submission's changes by state, change type and service.
+type SubmissionSummary struct {
+	Submission Submission
+	// Total is the number of changes.
+	Total int
+	// States counts the changes in each state.
+	States map[ChangeInstanceState]int
+	// ChangeTypes counts the changes of each type.
+	ChangeTypes map[ChangeType]int
+	// Services lists the services the changes are for, sorted.
+	Services []string
+	// Settled is set once no change can move any further: each is REJECTED, CLOSED or
+	// COMPLETED (which can still be closed, but has done its work).
+	Settled bool
+}
+
+// Summary summarises the group.
+func (g *SubmissionGroup) Summary() SubmissionSummary {
+	summary := SubmissionSummary{
+		Submission:  g.Submission,
+		Total:       len(g.ChangeInstances),
+		States:      map[ChangeInstanceState]int{},
+		ChangeTypes: map[ChangeType]int{},
+		Settled:     true,
+	}
+	services := map[string]bool{}
+	for _, ci := range g.ChangeInstances {
+		summary.States[ci.State]++
+		summary.ChangeTypes[ci.ChangeType]++
+		if !services[ci.Service.Name] {
+			services[ci.Service.Name] = true
+			summary.Services = append(summary.Services, ci.Service.Name)
+		}
+		switch ci.State {
+		case ChangeInstanceCOMPLETED, ChangeInstanceREJECTED, ChangeInstanceCLOSED:
+		default:
+			summary.Settled = false
+		}
+	}
+	sort.Strings(summary.Services)
+	return summary
+}
+
+// GetSubmissionSummary summarises every change instance of one submission that the POV can see.
+func (c *Client) GetSubmissionSummary(ctx context.Context, pov POV, submissionID int) (*SubmissionSummary, error) {
+	changes, err := c.listAllChangeInstances(ctx, &GetChangeInstancesRequest{
+		POV:           pov,
+		SubmissionIDs: []int{submissionID},
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	group := SubmissionGroup{Submission: Submission{ID: submissionID}, ChangeInstances: changes}
+	if len(changes) > 0 {
+		group.Submission = changes[0].Submission
+	}
+	summary := group.Summary()
+	return &summary, nil
+}
+
+// SubmissionStep is what a SubmissionProcessor wants reported for its change.
+type SubmissionStep struct {
+	// State is the state to move the change to. Leave it empty to leave the change as it is:
+	// nothing is reported for it, and Log and DeployedItem are ignored.
+	State ChangeInstanceState
+	// Log is recorded against the change.
+	Log string
+	// DeployedItem is recorded against the change; nil leaves it untouched.
+	DeployedItem json.RawMessage
+}
+
+// SubmissionProcessor does the work for one change of a submission and says what to report.
+// Returning an error reports the change as ERROR.
+type SubmissionProcessor func(ctx context.Context, ci *ChangeInstance) (SubmissionStep, error)
+
+// SubmissionOptions tunes ProcessSubmission. The zero value (or nil) processes each change
+// independently.
+type SubmissionOptions struct {
+	// AllOrNothing treats the submission as a unit: the first failure stops processing, and
+	// every change in the group - those already processed included - is reported as ERROR with
+	// one shared log naming the change that failed. Nothing succeeds unless everything does.
+	AllOrNothing bool
+	// Concurrency bounds the reporting requests, as BulkOptions.Concurrency does.
+	Concurrency int
+	// TransitionOptions are passed to every report.
+	TransitionOptions []TransitionOption
+}
+
+// SubmissionResult is the outcome of ProcessSubmission.
+type SubmissionResult struct {
+	Submission Submission
+	// FailedID is the change whose processor failed first, or 0 when none did.
+	FailedID int
+	// Err is that processor's error.
+	Err error
+	// Reports are the state updates sent, in group order: one per change in the group, less any
+	// whose processor returned an empty step.
+	Reports BulkResults
+}
+
+// ProcessSubmission runs the processor over a submission's changes one at a time, in the order
+// they were raised, and then reports every outcome in one bulk update.
+//
+// The processor runs before any state is written, so in AllOrNothing mode a failure part way
+// through leaves no sibling reported as a success. Undoing what earlier processors built is
+// the caller's business; the shared ERROR log tells everyone which change sank the submission.
+func (c *Client) ProcessSubmission(
+	ctx context.Context,
+	pov POV,
+	group SubmissionGroup,
+	processor SubmissionProcessor,
+	opts *SubmissionOptions,
+) (*SubmissionResult, error) {
+	if opts == nil {
+		opts = &SubmissionOptions{}
+	}
+	result := &SubmissionResult{Submission: group.Submission}
+
+	transitions := make([]BulkTransition, 0, len(group.ChangeInstances))
+	for i := range group.ChangeInstances {
+		ci := &group.ChangeInstances[i]
+		step, err := processor(ctx, ci)
+		if err != nil && result.Err == nil {
+			result.FailedID, result.Err = ci.ID, err
+		}
+
+		if opts.AllOrNothing && result.Err != nil {
+			break
+		}
+		if err != nil {
+			step = SubmissionStep{State: ChangeInstanceERROR, Log: err.Error(), DeployedItem: step.DeployedItem}
+		}
+		if step.State == "" {
+			continue
+		}
+		transitions = append(transitions, BulkTransition{
+			ID: ci.ID, State: step.State, Log: step.Log, DeployedItem: step.DeployedItem,
+		})
+	}
+
+	if opts.AllOrNothing && result.Err != nil {
+		shared := fmt.Sprintf("submission %d failed as a whole: change instance %d: %v",
+			group.Submission.ID, result.FailedID, result.Err)
+		if group.Submission.CommitID != "" {
+			shared = fmt.Sprintf("submission %d (commit %s) failed as a whole: change instance %d: %v",
+				group.Submission.ID, group.Submission.CommitID, result.FailedID, result.Err)
+		}
+		transitions = transitions[:0]
+		for _, ci := range group.ChangeInstances {
+			transitions = append(transitions, BulkTransition{ID: ci.ID, State: ChangeInstanceERROR, Log: shared})
+		}
+	}
+
+	reports, err := c.BulkUpdateChangeInstanceState(ctx, pov, transitions, &BulkOptions{
+		Concurrency:       opts.Concurrency,
+		TransitionOptions: opts.TransitionOptions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Reports = reports
+	return result, nil
+}
+
+// listAllChangeInstances pages through a change instance listing, 100 at a time unless the
+// filters set another Limit. The action picks the route, as for listChangeInstances.
+func (c *Client) listAllChangeInstances(
+	ctx context.Context,
+	filters *GetChangeInstancesRequest,
+	action string,
+) ([]ChangeInstance, error) {
+	var listing GetChangeInstancesRequest
+	if filters != nil {
+		listing = *filters
+	}
+	if listing.Limit == 0 {
+		listing.Limit = 100
+	}
+
+	var changes []ChangeInstance
+	for {
+		page, err := c.listChangeInstances(ctx, &listing, action)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, page.Results...)
+		if page.Next == nil || len(page.Results) == 0 {
+			return changes, nil
+		}
+		listing.Offset += len(page.Results)
+	}
+}
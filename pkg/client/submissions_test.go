@@ -0,0 +1,199 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// submissionChange builds a change of the given submission.
+func submissionChange(id, submission int, state client.ChangeInstanceState, service string) client.ChangeInstance {
+	return client.ChangeInstance{
+		ID:         id,
+		State:      state,
+		ChangeType: client.ChangeTypeCreate,
+		Service:    client.ChangeInstanceService{Name: service},
+		Submission: client.Submission{ID: submission, CommitID: "c0ffee"},
+	}
+}
+
+// capturePatches records each PATCH body by change instance id, echoing the state back.
+func capturePatches(t *testing.T) func() map[int]map[string]any {
+	t.Helper()
+	var mu sync.Mutex
+	bodies := map[int]map[string]any{}
+
+	httpmock.RegisterRegexpResponder("PATCH", changeInstanceDetail, func(req *http.Request) (*http.Response, error) {
+		id, err := strconv.Atoi(changeInstanceDetail.FindStringSubmatch(req.URL.Path)[1])
+		require.NoError(t, err)
+		raw, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(raw, &body))
+
+		mu.Lock()
+		bodies[id] = body
+		mu.Unlock()
+		return httpmock.NewStringResponse(200, `{"id":`+strconv.Itoa(id)+`,"state":"`+body["state"].(string)+`"}`), nil
+	})
+	return func() map[int]map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies
+	}
+}
+
+func TestGroupBySubmission(t *testing.T) {
+	groups := client.GroupBySubmission([]client.ChangeInstance{
+		submissionChange(12, 5, client.ChangeInstancePENDING, "vm"),
+		submissionChange(3, 9, client.ChangeInstancePENDING, "dns"),
+		submissionChange(10, 5, client.ChangeInstanceAPPROVED, "dns"),
+	})
+
+	require.Len(t, groups, 2)
+	assert.Equal(t, 5, groups[0].Submission.ID)
+	assert.Equal(t, 10, groups[0].ChangeInstances[0].ID, "siblings are in the order they were raised")
+	assert.Equal(t, 12, groups[0].ChangeInstances[1].ID)
+	assert.Equal(t, 9, groups[1].Submission.ID)
+
+	summary := groups[0].Summary()
+	assert.Equal(t, client.SubmissionSummary{
+		Submission: client.Submission{ID: 5, CommitID: "c0ffee"},
+		Total:      2,
+		States: map[client.ChangeInstanceState]int{
+			client.ChangeInstancePENDING: 1, client.ChangeInstanceAPPROVED: 1,
+		},
+		ChangeTypes: map[client.ChangeType]int{client.ChangeTypeCreate: 2},
+		Services:    []string{"dns", "vm"},
+	}, summary)
+}
+
+func TestGetSubmissionSummary(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var query string
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"/", captureQueryResponder(&query, 200,
+		`{"count":2,"next":null,"previous":null,"results":[
+			{"id":1,"state":"COMPLETED","submission":{"id":5,"commit_id":"c0ffee"}},
+			{"id":2,"state":"REJECTED","submission":{"id":5,"commit_id":"c0ffee"}}]}`))
+
+	nc := newPackTestClient(t)
+	summary, err := nc.GetSubmissionSummary(context.Background(), client.POVServiceOwner, 5)
+	require.NoError(t, err)
+
+	assert.Equal(t, "limit=100&submission_id=5", query)
+	assert.Equal(t, "c0ffee", summary.Submission.CommitID)
+	assert.Equal(t, 2, summary.Total)
+	assert.True(t, summary.Settled)
+}
+
+func TestProcessSubmission(t *testing.T) {
+	group := client.SubmissionGroup{
+		Submission: client.Submission{ID: 5, CommitID: "c0ffee"},
+		ChangeInstances: []client.ChangeInstance{
+			submissionChange(1, 5, client.ChangeInstanceAPPROVED, "vm"),
+			submissionChange(2, 5, client.ChangeInstanceAPPROVED, "vm"),
+			submissionChange(3, 5, client.ChangeInstanceAPPROVED, "vm"),
+		},
+	}
+	var processed []int
+	failOnTwo := func(_ context.Context, ci *client.ChangeInstance) (client.SubmissionStep, error) {
+		processed = append(processed, ci.ID)
+		if ci.ID == 2 {
+			return client.SubmissionStep{}, errors.New("quota exceeded")
+		}
+		return client.SubmissionStep{State: client.ChangeInstanceCOMPLETED, Log: "built"}, nil
+	}
+
+	t.Run("independent", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		reported := capturePatches(t)
+		processed = nil
+
+		nc := newPackTestClient(t)
+		result, err := nc.ProcessSubmission(context.Background(), client.POVServiceOwner, group, failOnTwo, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{1, 2, 3}, processed)
+		assert.Equal(t, 2, result.FailedID)
+		require.NoError(t, result.Reports.Err())
+		assert.Equal(t, map[int]map[string]any{
+			1: {"state": "COMPLETED", "log": "built"},
+			2: {"state": "ERROR", "log": "quota exceeded"},
+			3: {"state": "COMPLETED", "log": "built"},
+		}, reported())
+	})
+
+	t.Run("all or nothing", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		reported := capturePatches(t)
+		processed = nil
+
+		nc := newPackTestClient(t)
+		result, err := nc.ProcessSubmission(context.Background(), client.POVServiceOwner, group, failOnTwo,
+			&client.SubmissionOptions{AllOrNothing: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{1, 2}, processed, "processing stops at the first failure")
+		require.EqualError(t, result.Err, "quota exceeded")
+
+		shared := map[string]any{
+			"state": "ERROR",
+			"log":   "submission 5 (commit c0ffee) failed as a whole: change instance 2: quota exceeded",
+		}
+		assert.Equal(t, map[int]map[string]any{1: shared, 2: shared, 3: shared}, reported())
+		assert.Len(t, result.Reports, 3)
+	})
+
+	t.Run("all or nothing with no failure", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		reported := capturePatches(t)
+
+		nc := newPackTestClient(t)
+		result, err := nc.ProcessSubmission(context.Background(), client.POVServiceOwner, group,
+			func(context.Context, *client.ChangeInstance) (client.SubmissionStep, error) {
+				return client.SubmissionStep{State: client.ChangeInstanceCOMPLETED}, nil
+			}, &client.SubmissionOptions{AllOrNothing: true})
+		require.NoError(t, err)
+
+		assert.Zero(t, result.FailedID)
+		assert.Len(t, reported(), 3)
+	})
+
+	t.Run("an empty step leaves the change alone", func(t *testing.T) {
+		httpmock.Activate()
+		defer httpmock.DeactivateAndReset()
+		reported := capturePatches(t)
+
+		nc := newPackTestClient(t)
+		result, err := nc.ProcessSubmission(context.Background(), client.POVServiceOwner, group,
+			func(_ context.Context, ci *client.ChangeInstance) (client.SubmissionStep, error) {
+				if ci.ID == 2 {
+					return client.SubmissionStep{}, nil
+				}
+				return client.SubmissionStep{State: client.ChangeInstanceCOMPLETED, Log: "built"}, nil
+			}, nil)
+		require.NoError(t, err)
+
+		require.NoError(t, result.Reports.Err())
+		assert.Len(t, result.Reports, 2)
+		assert.Equal(t, map[int]map[string]any{
+			1: {"state": "COMPLETED", "log": "built"},
+			3: {"state": "COMPLETED", "log": "built"},
+		}, reported())
+	})
+}
@@ -0,0 +1,567 @@
+// Package policy decides routine change instances by rule, so people only see the ones that
+// need judgement. Rules are written in YAML against the service, change type, consumer team and
+// declaration of a change; the first rule that matches decides it:
+//
+//	default: manual
+//	rules:
+//	  - name: small-dev-vms
+//	    decision: approve
+//	    reason: development machines up to 8 CPUs are pre-approved
+//	    match:
+//	      services: [virtual_machine]
+//	      change_types: [CREATE, MODIFY]
+//	      declaration:
+//	        - path: $.environment
+//	          equals: dev
+//	        - path: $.cpu
+//	          range: {min: 1, max: 8}
+//	  - name: no-public-names
+//	    decision: reject
+//	    reason: names must not start with "public-"
+//	    match:
+//	      declaration:
+//	        - path: $.name
+//	          regex: "^public-"
+//
+// Evaluate reports what a policy would do with a change, Apply does it through the client's
+// approve and reject helpers, and Handler plugs the policy into a worker. DryRun and DryRunHistory
+// check a policy against changes that were already decided by hand before it is trusted with
+// new ones.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/netautomate/netorca-go/pkg/worker"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidPolicy is the sentinel for a policy that cannot be used: malformed YAML, an unknown
+// field, an unknown decision, a regex that does not compile or a condition with nothing to check.
+var ErrInvalidPolicy = errors.New("policy: invalid policy")
+
+// Decision is what a policy does with a change instance.
+type Decision string
+
+const (
+	// DecisionApprove approves the change.
+	DecisionApprove Decision = "approve"
+	// DecisionReject rejects the change.
+	DecisionReject Decision = "reject"
+	// DecisionManual leaves the change for a person.
+	DecisionManual Decision = "manual"
+)
+
+// Policy is an ordered list of rules and the decision taken when none matches. Build one with
+// Parse or Load, which validate it; a Policy assembled in code must be passed through Validate
+// before use.
+type Policy struct {
+	// Default is the decision when no rule matches. Defaults to manual.
+	Default Decision `yaml:"default"`
+	// Rules are tried in order; the first that matches decides.
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule decides the change instances it matches.
+type Rule struct {
+	// Name identifies the rule in logs and reports. Required, and unique within a policy.
+	Name string `yaml:"name"`
+	// Decision is what the rule does with a matching change.
+	Decision Decision `yaml:"decision"`
+	// Reason is written to the change's log alongside the rule name. For a rejection the
+	// consumer reads it, so write it for them.
+	Reason string `yaml:"reason"`
+	// Match selects the changes the rule applies to. An empty match applies to every change.
+	Match Match `yaml:"match"`
+}
+
+// Match selects change instances. Every field that is set must match; a list matches when any
+// of its entries does.
+type Match struct {
+	// Services are service names.
+	Services []string `yaml:"services"`
+	// ChangeTypes are CREATE, MODIFY or DELETE.
+	ChangeTypes []client.ChangeType `yaml:"change_types"`
+	// ConsumerTeams are the names of the teams that raised the change.
+	ConsumerTeams []string `yaml:"consumer_teams"`
+	// Declaration are conditions on the change's declaration, all of which must hold.
+	Declaration []Condition `yaml:"declaration"`
+}
+
+// Condition checks one value of a declaration, located by a JSON path such as "$.vip.port" or
+// "$.interfaces[0].vlan". Every comparison that is set must hold, and at least one must be.
+type Condition struct {
+	// Path locates the value.
+	Path string `yaml:"path"`
+	// Old checks the declaration the change replaces rather than the new one - the only one a
+	// DELETE carries.
+	Old bool `yaml:"old"`
+	// Exists requires the value to be present (true) or absent (false).
+	Exists *bool `yaml:"exists"`
+	// Equals requires the value to equal this one. Objects and lists compare whole. In a policy
+	// file, "equals: null" requires a null; in Go, a nil Equals is unset, as is a nil NotEquals.
+	Equals any `yaml:"equals"`
+	// NotEquals requires the value to be present and differ from this one.
+	NotEquals any `yaml:"not_equals"`
+	// In requires the value to equal one of these. It cannot be empty, since nothing would match.
+	In []any `yaml:"in"`
+	// Regex requires the value to be a string matching this regular expression.
+	Regex string `yaml:"regex"`
+	// Range requires the value to be a number within these inclusive bounds.
+	Range *Range `yaml:"range"`
+
+	path []pathStep
+	re   *regexp.Regexp
+	// hasEquals and hasNotEquals record that the comparison is set, even to null.
+	hasEquals    bool
+	hasNotEquals bool
+}
+
+// Range is an inclusive numeric range. Either bound may be left out.
+type Range struct {
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+}
+
+// Parse reads a policy from YAML and validates it. Unknown fields are an error, since a
+// misspelt condition would otherwise match everything.
+func Parse(data []byte) (*Policy, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	// An empty document is an empty policy, deciding everything by its default.
+	var p Policy
+	if err := decoder.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	if err := markNullComparisons(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// markNullComparisons flags the conditions that set equals or not_equals to null. Decoded, a
+// null is the same nil as a comparison left out, so the document is read again as nodes, which
+// keep it.
+func markNullComparisons(data []byte, p *Policy) error {
+	var document struct {
+		Rules []struct {
+			Match struct {
+				Declaration []map[string]yaml.Node `yaml:"declaration"`
+			} `yaml:"match"`
+		} `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	for i, rule := range document.Rules {
+		for j, condition := range rule.Match.Declaration {
+			if i < len(p.Rules) && j < len(p.Rules[i].Match.Declaration) {
+				_, p.Rules[i].Match.Declaration[j].hasEquals = condition["equals"]
+				_, p.Rules[i].Match.Declaration[j].hasNotEquals = condition["not_equals"]
+			}
+		}
+	}
+	return nil
+}
+
+// Load reads and parses the policy file at path.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	return Parse(data)
+}
+
+// Validate checks the policy and prepares its conditions for evaluation, reporting every
+// problem at once. The error wraps ErrInvalidPolicy.
+func (p *Policy) Validate() error {
+	var errs []error
+	if p.Default == "" {
+		p.Default = DecisionManual
+	}
+	if !validDecision(p.Default) {
+		errs = append(errs, fmt.Errorf("default: unknown decision %q", p.Default))
+	}
+
+	names := map[string]bool{}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		where := fmt.Sprintf("rule %d (%s)", i+1, rule.Name)
+		switch {
+		case rule.Name == "":
+			errs = append(errs, fmt.Errorf("rule %d: name is required", i+1))
+		case names[rule.Name]:
+			errs = append(errs, fmt.Errorf("%s: name is used by an earlier rule", where))
+		}
+		names[rule.Name] = true
+
+		if !validDecision(rule.Decision) {
+			errs = append(errs, fmt.Errorf("%s: unknown decision %q", where, rule.Decision))
+		}
+		for _, changeType := range rule.Match.ChangeTypes {
+			switch changeType {
+			case client.ChangeTypeCreate, client.ChangeTypeModify, client.ChangeTypeDelete:
+			default:
+				errs = append(errs, fmt.Errorf("%s: unknown change type %q", where, changeType))
+			}
+		}
+		for j := range rule.Match.Declaration {
+			if err := rule.Match.Declaration[j].prepare(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: declaration condition %d: %w", where, j+1, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidPolicy, errors.Join(errs...))
+	}
+	return nil
+}
+
+func validDecision(d Decision) bool {
+	return d == DecisionApprove || d == DecisionReject || d == DecisionManual
+}
+
+// prepare parses the condition's path and compiles its regex.
+func (c *Condition) prepare() error {
+	path, err := parsePath(c.Path)
+	if err != nil {
+		return err
+	}
+	c.path = path
+
+	if c.Regex != "" {
+		re, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		c.re = re
+	}
+
+	if c.Range != nil {
+		if c.Range.Min == nil && c.Range.Max == nil {
+			return errors.New("range needs a min, a max or both")
+		}
+		if c.Range.Min != nil && c.Range.Max != nil && *c.Range.Min > *c.Range.Max {
+			return fmt.Errorf("range min %v is above max %v", *c.Range.Min, *c.Range.Max)
+		}
+	}
+
+	if c.In != nil && len(c.In) == 0 {
+		return errors.New("in needs at least one value")
+	}
+
+	c.hasEquals = c.hasEquals || c.Equals != nil
+	c.hasNotEquals = c.hasNotEquals || c.NotEquals != nil
+	if c.Exists == nil && !c.hasEquals && !c.hasNotEquals && c.In == nil && c.Regex == "" && c.Range == nil {
+		return errors.New("nothing to check: set exists, equals, not_equals, in, regex or range")
+	}
+	return nil
+}
+
+// Result is a policy's verdict on one change instance.
+type Result struct {
+	// Decision is what to do with the change.
+	Decision Decision
+	// Rule names the rule that decided. Empty when no rule matched and the default applied.
+	Rule string
+	// Reason is the deciding rule's reason.
+	Reason string
+}
+
+// Log is the text written to the change's log when the result is applied, naming the rule so
+// anyone reading the change can find it in the policy.
+func (r Result) Log() string {
+	if r.Rule == "" {
+		return "policy default: " + string(r.Decision)
+	}
+	if r.Reason == "" {
+		return "policy rule " + r.Rule
+	}
+	return "policy rule " + r.Rule + ": " + r.Reason
+}
+
+// Evaluate decides a change instance: the first matching rule's decision, or the default.
+func (p *Policy) Evaluate(ci *client.ChangeInstance) Result {
+	var newDecl, oldDecl any
+	var decoded bool
+	declarations := func() (any, any) {
+		if !decoded {
+			newDecl = decodeDeclaration(ci.NewDeclaration.Declaration)
+			if ci.OldDeclaration != nil {
+				oldDecl = decodeDeclaration(ci.OldDeclaration.Declaration)
+			}
+			decoded = true
+		}
+		return newDecl, oldDecl
+	}
+
+	for _, rule := range p.Rules {
+		if rule.Match.matches(ci, declarations) {
+			return Result{Decision: rule.Decision, Rule: rule.Name, Reason: rule.Reason}
+		}
+	}
+	decision := p.Default
+	if decision == "" {
+		decision = DecisionManual
+	}
+	return Result{Decision: decision}
+}
+
+// decodeDeclaration decodes a declaration for path lookups, treating one that does not decode
+// as absent so conditions on it simply fail.
+func decodeDeclaration(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var tree any
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil
+	}
+	return tree
+}
+
+func (m *Match) matches(ci *client.ChangeInstance, declarations func() (any, any)) bool {
+	if len(m.Services) > 0 && !contains(m.Services, ci.Service.Name) {
+		return false
+	}
+	if len(m.ChangeTypes) > 0 && !contains(m.ChangeTypes, ci.ChangeType) {
+		return false
+	}
+	if len(m.ConsumerTeams) > 0 && !contains(m.ConsumerTeams, ci.ConsumerTeam.Name) {
+		return false
+	}
+	if len(m.Declaration) == 0 {
+		return true
+	}
+
+	newDecl, oldDecl := declarations()
+	for i := range m.Declaration {
+		condition := &m.Declaration[i]
+		declaration := newDecl
+		if condition.Old {
+			declaration = oldDecl
+		}
+		if !condition.holds(declaration) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// holds reports whether every comparison the condition sets holds for the declaration.
+func (c *Condition) holds(declaration any) bool {
+	if c.path == nil {
+		// Not prepared by Validate. Prepare a copy rather than the condition itself, which
+		// concurrent evaluations share; one that cannot be read holds for nothing.
+		prepared := *c
+		if err := prepared.prepare(); err != nil {
+			return false
+		}
+		return prepared.holds(declaration)
+	}
+	value, found := lookup(declaration, c.path)
+
+	if c.Exists != nil && *c.Exists != found {
+		return false
+	}
+	if !found {
+		// Every other comparison is about a value, so an absent one fails them all.
+		return !c.hasEquals && !c.hasNotEquals && c.In == nil && c.Regex == "" && c.Range == nil
+	}
+
+	if c.hasEquals && !equal(value, c.Equals) {
+		return false
+	}
+	if c.hasNotEquals && equal(value, c.NotEquals) {
+		return false
+	}
+	if c.In != nil && !containsEqual(c.In, value) {
+		return false
+	}
+	if c.re != nil {
+		s, ok := value.(string)
+		if !ok || !c.re.MatchString(s) {
+			return false
+		}
+	}
+	if c.Range != nil {
+		n, ok := value.(float64)
+		if !ok || (c.Range.Min != nil && n < *c.Range.Min) || (c.Range.Max != nil && n > *c.Range.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsEqual(candidates []any, value any) bool {
+	for _, candidate := range candidates {
+		if equal(value, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares a declaration value with one from the policy. YAML decodes 443 as an int and
+// JSON as a float64, so the policy's value takes a round trip through JSON first.
+func equal(value, expected any) bool {
+	encoded, err := json.Marshal(expected)
+	if err != nil {
+		return false
+	}
+	var normalised any
+	if err := json.Unmarshal(encoded, &normalised); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(value, normalised)
+}
+
+// pathStep is one step of a JSON path: an object key, or a list index when index is true.
+type pathStep struct {
+	key   string
+	pos   int
+	index bool
+}
+
+// parsePath parses the subset of JSON path conditions need: "$", dotted keys and [n] indexes,
+// as in "$.interfaces[0].vlan". The leading "$." may be left out.
+func parsePath(path string) ([]pathStep, error) {
+	if path == "" {
+		return nil, errors.New("path is required")
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+
+	steps := []pathStep{}
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unclosed [", path)
+			}
+			pos, err := strconv.Atoi(rest[1:end])
+			if err != nil || pos < 0 {
+				return nil, fmt.Errorf("path %q: %q is not a list index", path, rest[1:end])
+			}
+			steps = append(steps, pathStep{pos: pos, index: true})
+			rest = strings.TrimPrefix(rest[end+1:], ".")
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q: empty key", path)
+			}
+			steps = append(steps, pathStep{key: rest[:end]})
+			rest = rest[end:]
+			if strings.HasPrefix(rest, ".") {
+				rest = rest[1:]
+				if rest == "" {
+					return nil, fmt.Errorf("path %q: empty key", path)
+				}
+			}
+		}
+	}
+	return steps, nil
+}
+
+// lookup follows path through a decoded declaration.
+func lookup(tree any, path []pathStep) (any, bool) {
+	if tree == nil {
+		return nil, false
+	}
+	node := tree
+	for _, step := range path {
+		if step.index {
+			list, ok := node.([]any)
+			if !ok || step.pos >= len(list) {
+				return nil, false
+			}
+			node = list[step.pos]
+			continue
+		}
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if node, ok = object[step.key]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// Apply evaluates a change instance and acts on the result through ApproveChangeInstanceWithContext
+// or RejectChangeInstanceWithContext, with the result's Log as the log. A manual result sends
+// nothing and returns a nil change instance.
+func (p *Policy) Apply(
+	ctx context.Context,
+	nc *client.Client,
+	pov client.POV,
+	ci *client.ChangeInstance,
+	opts ...client.TransitionOption,
+) (Result, *client.ChangeInstance, error) {
+	result := p.Evaluate(ci)
+
+	var updated *client.ChangeInstance
+	var err error
+	switch result.Decision {
+	case DecisionApprove:
+		updated, err = nc.ApproveChangeInstanceWithContext(ctx, pov, ci.ID, result.Log(), nil, opts...)
+	case DecisionReject:
+		updated, err = nc.RejectChangeInstanceWithContext(ctx, pov, ci.ID, result.Log(), nil, opts...)
+	default:
+		return result, nil, nil
+	}
+	if err != nil {
+		return result, nil, fmt.Errorf("failed to apply %s: %w", result.Log(), err)
+	}
+	return result, updated, nil
+}
+
+// Handler adapts the policy to a worker handler for PENDING changes: it approves or rejects
+// what the policy decides and skips the rest, leaving them for a person. Changes in any other
+// state are skipped, so register it for a worker that polls PENDING alone, or wrap it in a
+// handler that deals with APPROVED changes itself.
+func (p *Policy) Handler() worker.Handler {
+	return func(_ context.Context, ci *client.ChangeInstance) (worker.Result, error) {
+		if ci.State != client.ChangeInstancePENDING {
+			return worker.Skip(), nil
+		}
+		result := p.Evaluate(ci)
+		switch result.Decision {
+		case DecisionApprove:
+			return worker.Approve(result.Log()), nil
+		case DecisionReject:
+			return worker.Reject(result.Log()), nil
+		}
+		return worker.Skip(), nil
+	}
+}
@@ -0,0 +1,318 @@
+package policy_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/netautomate/netorca-go/pkg/policy"
+	"github.com/netautomate/netorca-go/pkg/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBaseURL         = "http://api-aws.demo.netorca.io"
+	changeInstancesRoot = testBaseURL + "/v1/orcabase/serviceowner/change_instances/"
+)
+
+const testPolicy = `
+default: manual
+rules:
+  - name: no-public-names
+    decision: reject
+    reason: names must not start with "public-"
+    match:
+      declaration:
+        - path: $.name
+          regex: "^public-"
+  - name: small-dev-vms
+    decision: approve
+    reason: development machines up to 8 CPUs are pre-approved
+    match:
+      services: [virtual_machine]
+      change_types: [CREATE, MODIFY]
+      consumer_teams: [web]
+      declaration:
+        - path: $.environment
+          in: [dev, test]
+        - path: $.cpu
+          range: {min: 1, max: 8}
+        - path: $.disks[0].size
+          range: {max: 100}
+  - name: decommission-dev
+    decision: approve
+    match:
+      change_types: [DELETE]
+      declaration:
+        - path: environment
+          old: true
+          equals: dev
+        - path: $.protected
+          old: true
+          exists: false
+`
+
+func newTestClient(t *testing.T) *client.Client {
+	t.Helper()
+	nc, err := client.NewClient(testBaseURL, "test-api-key", "v1", 5*time.Second)
+	require.NoError(t, err)
+	return nc
+}
+
+func mustParse(t *testing.T) *policy.Policy {
+	t.Helper()
+	p, err := policy.Parse([]byte(testPolicy))
+	require.NoError(t, err)
+	return p
+}
+
+// vmChange builds a PENDING virtual machine change from the web team.
+func vmChange(changeType client.ChangeType, declaration string) *client.ChangeInstance {
+	return &client.ChangeInstance{
+		ID:             7,
+		State:          client.ChangeInstancePENDING,
+		ChangeType:     changeType,
+		Service:        client.ChangeInstanceService{Name: "virtual_machine"},
+		ConsumerTeam:   client.Team{Name: "web"},
+		NewDeclaration: client.Declaration{Declaration: json.RawMessage(declaration)},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	p := mustParse(t)
+
+	deletion := vmChange(client.ChangeTypeDelete, ``)
+	deletion.OldDeclaration = &client.Declaration{Declaration: json.RawMessage(`{"environment":"dev"}`)}
+	protected := vmChange(client.ChangeTypeDelete, ``)
+	protected.OldDeclaration = &client.Declaration{
+		Declaration: json.RawMessage(`{"environment":"dev","protected":true}`),
+	}
+	otherTeam := vmChange(client.ChangeTypeCreate, `{"environment":"dev","cpu":2,"disks":[{"size":50}]}`)
+	otherTeam.ConsumerTeam.Name = "payments"
+
+	tests := []struct {
+		name     string
+		ci       *client.ChangeInstance
+		decision policy.Decision
+		rule     string
+	}{
+		{
+			name:     "small dev machine",
+			ci:       vmChange(client.ChangeTypeCreate, `{"environment":"dev","cpu":8,"disks":[{"size":50}]}`),
+			decision: policy.DecisionApprove,
+			rule:     "small-dev-vms",
+		},
+		{
+			name:     "first matching rule wins",
+			ci:       vmChange(client.ChangeTypeCreate, `{"name":"public-web","environment":"dev","cpu":2}`),
+			decision: policy.DecisionReject,
+			rule:     "no-public-names",
+		},
+		{
+			name:     "too many CPUs",
+			ci:       vmChange(client.ChangeTypeCreate, `{"environment":"dev","cpu":16,"disks":[{"size":50}]}`),
+			decision: policy.DecisionManual,
+		},
+		{
+			name:     "missing disk",
+			ci:       vmChange(client.ChangeTypeCreate, `{"environment":"test","cpu":2,"disks":[]}`),
+			decision: policy.DecisionManual,
+		},
+		{
+			name:     "wrong environment",
+			ci:       vmChange(client.ChangeTypeModify, `{"environment":"prod","cpu":2,"disks":[{"size":50}]}`),
+			decision: policy.DecisionManual,
+		},
+		{
+			name:     "other consumer team",
+			ci:       otherTeam,
+			decision: policy.DecisionManual,
+		},
+		{
+			name:     "old declaration",
+			ci:       deletion,
+			decision: policy.DecisionApprove,
+			rule:     "decommission-dev",
+		},
+		{
+			name:     "exists false",
+			ci:       protected,
+			decision: policy.DecisionManual,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.Evaluate(tt.ci)
+			assert.Equal(t, tt.decision, result.Decision)
+			assert.Equal(t, tt.rule, result.Rule)
+		})
+	}
+}
+
+// TestEvaluateNullComparisons checks a null in a policy file is a value to compare with, not a
+// comparison left out.
+func TestEvaluateNullComparisons(t *testing.T) {
+	p, err := policy.Parse([]byte(`
+rules:
+  - name: unowned
+    decision: reject
+    match:
+      declaration:
+        - {path: $.owner, equals: null}
+  - name: owned
+    decision: approve
+    match:
+      declaration:
+        - {path: $.owner, not_equals: null}
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "unowned", p.Evaluate(vmChange(client.ChangeTypeCreate, `{"owner":null}`)).Rule)
+	assert.Equal(t, "owned", p.Evaluate(vmChange(client.ChangeTypeCreate, `{"owner":"web"}`)).Rule)
+	assert.Empty(t, p.Evaluate(vmChange(client.ChangeTypeCreate, `{}`)).Rule, "an absent value is not null")
+}
+
+func TestResultLog(t *testing.T) {
+	assert.Equal(t, "policy rule small-dev-vms: pre-approved",
+		policy.Result{Decision: policy.DecisionApprove, Rule: "small-dev-vms", Reason: "pre-approved"}.Log())
+	assert.Equal(t, "policy rule decommission-dev",
+		policy.Result{Decision: policy.DecisionApprove, Rule: "decommission-dev"}.Log())
+	assert.Equal(t, "policy default: reject", policy.Result{Decision: policy.DecisionReject}.Log())
+}
+
+func TestParseErrors(t *testing.T) {
+	// withCondition wraps one declaration condition in a rule.
+	withCondition := func(condition string) string {
+		return "rules:\n  - name: a\n    decision: reject\n    match:\n      declaration:\n        - " + condition + "\n"
+	}
+	tests := []struct {
+		name   string
+		yaml   string
+		errMsg string
+	}{
+		{
+			name:   "unknown field",
+			yaml:   "rules:\n  - name: a\n    decision: approve\n    match:\n      service: [vm]\n",
+			errMsg: "field service not found",
+		},
+		{
+			name:   "unknown decision",
+			yaml:   "rules:\n  - name: a\n    decision: maybe\n",
+			errMsg: `rule 1 (a): unknown decision "maybe"`,
+		},
+		{
+			name:   "duplicate name",
+			yaml:   "rules:\n  - name: a\n    decision: approve\n  - name: a\n    decision: reject\n",
+			errMsg: "rule 2 (a): name is used by an earlier rule",
+		},
+		{
+			name:   "invalid regex",
+			yaml:   withCondition(`{path: $.name, regex: '('}`),
+			errMsg: "rule 1 (a): declaration condition 1: invalid regex",
+		},
+		{
+			name:   "nothing to check",
+			yaml:   withCondition(`{path: $.name}`),
+			errMsg: "declaration condition 1: nothing to check",
+		},
+		{
+			name:   "empty in",
+			yaml:   withCondition(`{path: $.environment, in: []}`),
+			errMsg: "declaration condition 1: in needs at least one value",
+		},
+		{
+			name:   "inverted range",
+			yaml:   withCondition(`{path: $.cpu, range: {min: 8, max: 1}}`),
+			errMsg: "range min 8 is above max 1",
+		},
+		{
+			name:   "bad path",
+			yaml:   withCondition(`{path: '$.disks[x]', exists: true}`),
+			errMsg: `"x" is not a list index`,
+		},
+		{
+			name:   "unknown change type",
+			yaml:   "rules:\n  - name: a\n    decision: reject\n    match:\n      change_types: [create]\n",
+			errMsg: `unknown change type "create"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := policy.Parse([]byte(tt.yaml))
+			require.ErrorIs(t, err, policy.ErrInvalidPolicy)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testPolicy), 0o600))
+
+	p, err := policy.Load(path)
+	require.NoError(t, err)
+	assert.Len(t, p.Rules, 3)
+
+	empty, err := policy.Parse(nil)
+	require.NoError(t, err)
+	assert.Equal(t, policy.DecisionManual, empty.Evaluate(vmChange(client.ChangeTypeCreate, `{}`)).Decision)
+}
+
+func TestApply(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var body map[string]any
+	httpmock.RegisterResponder("PATCH", changeInstancesRoot+"7/", func(req *http.Request) (*http.Response, error) {
+		raw, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(raw, &body))
+		return httpmock.NewStringResponse(200, `{"id":7,"state":"APPROVED"}`), nil
+	})
+
+	p := mustParse(t)
+	nc := newTestClient(t)
+
+	result, updated, err := p.Apply(context.Background(), nc, client.POVServiceOwner,
+		vmChange(client.ChangeTypeCreate, `{"environment":"dev","cpu":2,"disks":[{"size":50}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "small-dev-vms", result.Rule)
+	assert.Equal(t, client.ChangeInstanceAPPROVED, updated.State)
+	assert.Equal(t, map[string]any{
+		"state": "APPROVED",
+		"log":   "policy rule small-dev-vms: development machines up to 8 CPUs are pre-approved",
+	}, body)
+
+	result, updated, err = p.Apply(context.Background(), nc, client.POVServiceOwner,
+		vmChange(client.ChangeTypeCreate, `{"environment":"prod"}`))
+	require.NoError(t, err)
+	assert.Equal(t, policy.DecisionManual, result.Decision)
+	assert.Nil(t, updated)
+	assert.Equal(t, 1, httpmock.GetTotalCallCount(), "a manual result sends nothing")
+}
+
+func TestHandler(t *testing.T) {
+	handler := mustParse(t).Handler()
+
+	result, err := handler(context.Background(), vmChange(client.ChangeTypeCreate, `{"name":"public-web"}`))
+	require.NoError(t, err)
+	assert.Equal(t, worker.Reject(`policy rule no-public-names: names must not start with "public-"`), result)
+
+	result, err = handler(context.Background(), vmChange(client.ChangeTypeCreate, `{"environment":"prod"}`))
+	require.NoError(t, err)
+	assert.Equal(t, worker.Skip(), result)
+
+	approved := vmChange(client.ChangeTypeCreate, `{"name":"public-web"}`)
+	approved.State = client.ChangeInstanceAPPROVED
+	result, err = handler(context.Background(), approved)
+	require.NoError(t, err)
+	assert.Equal(t, worker.Skip(), result, "only PENDING changes are decided")
+}
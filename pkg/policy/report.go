@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+)
+
+// ReportEntry is a policy's verdict on one historical change instance, beside what actually
+// happened to it.
+type ReportEntry struct {
+	// ID is the change instance.
+	ID int
+	// Service is the change's service name.
+	Service string
+	// ChangeType is the change's type.
+	ChangeType client.ChangeType
+	// State is the state the change is in now.
+	State client.ChangeInstanceState
+	// Result is what the policy would have done.
+	Result Result
+	// Actual is the decision a person took, read from the change's state: approve for APPROVED
+	// and COMPLETED, reject for REJECTED. Empty for a change whose state says neither, such as
+	// one still PENDING.
+	Actual Decision
+}
+
+// Disagrees reports whether the policy would have decided the change the other way from the
+// person who did. A manual result never disagrees: it defers to them.
+func (e ReportEntry) Disagrees() bool {
+	return e.Actual != "" && e.Result.Decision != DecisionManual && e.Result.Decision != e.Actual
+}
+
+// Report is a dry run of a policy over changes that were already decided.
+type Report struct {
+	// Entries holds one entry per change, in the order they were given.
+	Entries []ReportEntry
+	// Decisions counts the policy's verdicts.
+	Decisions map[Decision]int
+	// Rules counts the changes each rule decided, by name. Changes left to the default are
+	// counted under "".
+	Rules map[string]int
+	// Disagreements are the entries the policy would have decided the other way.
+	Disagreements []ReportEntry
+}
+
+// Summary describes the report in one line, e.g. "120 change instances: 80 approve, 10 reject,
+// 30 manual; 2 disagree with the decision taken".
+func (r *Report) Summary() string {
+	parts := make([]string, 0, 3)
+	for _, decision := range []Decision{DecisionApprove, DecisionReject, DecisionManual} {
+		parts = append(parts, fmt.Sprintf("%d %s", r.Decisions[decision], decision))
+	}
+	return fmt.Sprintf("%d change instances: %s; %d disagree with the decision taken",
+		len(r.Entries), strings.Join(parts, ", "), len(r.Disagreements))
+}
+
+// DryRun evaluates the policy over changes without acting on any of them.
+func (p *Policy) DryRun(changes []client.ChangeInstance) *Report {
+	report := &Report{Decisions: map[Decision]int{}, Rules: map[string]int{}}
+	for i := range changes {
+		ci := &changes[i]
+		entry := ReportEntry{
+			ID:         ci.ID,
+			Service:    ci.Service.Name,
+			ChangeType: ci.ChangeType,
+			State:      ci.State,
+			Result:     p.Evaluate(ci),
+			Actual:     decisionTaken(ci.State),
+		}
+		report.Entries = append(report.Entries, entry)
+		report.Decisions[entry.Result.Decision]++
+		report.Rules[entry.Result.Rule]++
+		if entry.Disagrees() {
+			report.Disagreements = append(report.Disagreements, entry)
+		}
+	}
+	return report
+}
+
+// decisionTaken reads the decision a person took from a change's state. CLOSED and ERROR are
+// reached from either side, so they say nothing.
+func decisionTaken(state client.ChangeInstanceState) Decision {
+	switch state {
+	case client.ChangeInstanceAPPROVED, client.ChangeInstanceCOMPLETED:
+		return DecisionApprove
+	case client.ChangeInstanceREJECTED:
+		return DecisionReject
+	}
+	return ""
+}
+
+// DryRunHistory lists every change instance matching the filters, across all pages, and dry runs
+// the policy over them. Filter on a date window and the decided states to see how the policy
+// would have fared against the decisions people took.
+func (p *Policy) DryRunHistory(
+	ctx context.Context,
+	nc *client.Client,
+	filters *client.GetChangeInstancesRequest,
+) (*Report, error) {
+	changes, err := nc.ListAllChangeInstances(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list change instances: %w", err)
+	}
+	return p.DryRun(changes), nil
+}
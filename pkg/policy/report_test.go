@@ -0,0 +1,71 @@
+package policy_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/netautomate/netorca-go/pkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	p := mustParse(t)
+
+	approvedByHand := vmChange(client.ChangeTypeCreate, `{"name":"public-api","environment":"dev"}`)
+	approvedByHand.ID = 1
+	approvedByHand.State = client.ChangeInstanceCOMPLETED
+	agreed := vmChange(client.ChangeTypeCreate, `{"environment":"dev","cpu":2,"disks":[{"size":10}]}`)
+	agreed.ID = 2
+	agreed.State = client.ChangeInstanceAPPROVED
+	deferred := vmChange(client.ChangeTypeCreate, `{"environment":"prod"}`)
+	deferred.ID = 3
+	deferred.State = client.ChangeInstanceREJECTED
+
+	report := p.DryRun([]client.ChangeInstance{*approvedByHand, *agreed, *deferred})
+
+	require.Len(t, report.Entries, 3)
+	assert.Equal(t, map[policy.Decision]int{
+		policy.DecisionReject: 1, policy.DecisionApprove: 1, policy.DecisionManual: 1,
+	}, report.Decisions)
+	assert.Equal(t, map[string]int{"no-public-names": 1, "small-dev-vms": 1, "": 1}, report.Rules)
+
+	require.Len(t, report.Disagreements, 1)
+	assert.Equal(t, 1, report.Disagreements[0].ID)
+	assert.Equal(t, policy.DecisionApprove, report.Disagreements[0].Actual)
+	assert.Equal(t, "3 change instances: 1 approve, 1 reject, 1 manual; 1 disagree with the decision taken",
+		report.Summary())
+}
+
+func TestDryRunHistory(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var queries []string
+	httpmock.RegisterResponder("GET", changeInstancesRoot, func(req *http.Request) (*http.Response, error) {
+		queries = append(queries, req.URL.RawQuery)
+		if req.URL.Query().Get("offset") == "" {
+			return httpmock.NewStringResponse(200, fmt.Sprintf(
+				`{"count":2,"next":"%s?offset=1","previous":null,"results":[{"id":1,"state":"APPROVED"}]}`,
+				changeInstancesRoot)), nil
+		}
+		return httpmock.NewStringResponse(200,
+			`{"count":2,"next":null,"previous":null,"results":[{"id":2,"state":"REJECTED"}]}`), nil
+	})
+
+	p := mustParse(t)
+	report, err := p.DryRunHistory(context.Background(), newTestClient(t), &client.GetChangeInstancesRequest{
+		Limit: 1,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"limit=1", "limit=1&offset=1"}, queries)
+	require.Len(t, report.Entries, 2)
+	assert.Equal(t, policy.DecisionReject, report.Entries[1].Actual)
+	assert.Equal(t, 2, report.Decisions[policy.DecisionManual])
+	assert.Empty(t, report.Disagreements)
+}
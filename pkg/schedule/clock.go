@@ -0,0 +1,101 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// clockSpec is a parsed Start/End window: the weekdays it opens on, as a bit set, and the times
+// of day it opens and closes at, in minutes after midnight.
+type clockSpec struct {
+	days       uint64
+	start, end int
+}
+
+// parseClock parses a window's days - weekday names, numbers and ranges, as in cron's fifth
+// field - and its start and end times of day.
+func parseClock(days []string, start, end string) (*clockSpec, error) {
+	spec := &clockSpec{}
+	var err error
+	if spec.start, err = parseTimeOfDay(start); err != nil {
+		return nil, fmt.Errorf("start: want a time of day such as 22:00")
+	}
+	if spec.end, err = parseTimeOfDay(end); err != nil {
+		return nil, fmt.Errorf("end: want a time of day such as 02:00")
+	}
+
+	field := "*"
+	if len(days) > 0 {
+		field = strings.Join(days, ",")
+	}
+	if spec.days, err = parseCronField(field, cronFields[4]); err != nil {
+		return nil, fmt.Errorf("days: %w", err)
+	}
+	if spec.days&(1<<7) != 0 {
+		spec.days |= 1
+	}
+	return spec, nil
+}
+
+// parseTimeOfDay reads "15:04" as minutes after midnight.
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// overnight reports whether the window closes on the day after it opens.
+func (s *clockSpec) overnight() bool {
+	return s.end <= s.start
+}
+
+// openAt reports whether the window is open at t, comparing t's wall clock, in t's location,
+// with the window's times of day. Reading the clock rather than adding a duration to the
+// opening keeps the window to its times of day across a DST change: a 22:00-02:00 window is
+// open an hour longer on the night the clocks go back, and a window starting inside the hour
+// the clocks skip opens as they jump.
+func (s *clockSpec) openAt(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	today := s.days&(1<<uint(t.Weekday())) != 0
+	if !s.overnight() {
+		return today && minute >= s.start && minute < s.end
+	}
+	yesterday := s.days&(1<<uint((t.Weekday()+6)%7)) != 0
+	return (today && minute >= s.start) || (yesterday && minute < s.end)
+}
+
+// next returns the first moment at or after t, in t's location, at which the window opens.
+func (s *clockSpec) next(t time.Time) (time.Time, bool) {
+	year, month, day := t.Date()
+	// Every weekday comes up within a week, so a day on from that covers an opening earlier
+	// today that t has passed.
+	for i := range 8 {
+		date := time.Date(year, month, day+i, 0, 0, 0, 0, t.Location())
+		if s.days&(1<<uint(date.Weekday())) == 0 {
+			continue
+		}
+		if opens := wallClock(date, s.start); !opens.Before(t) {
+			return opens, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// wallClock returns the first moment on date's day at which the clock reads minute or later. A
+// time of day the clocks skip has no moment of its own; the moment they jump past it stands in.
+func wallClock(date time.Time, minute int) time.Time {
+	year, month, day := date.Date()
+	t := time.Date(year, month, day, minute/60, minute%60, 0, 0, date.Location())
+	if t.Hour()*60+t.Minute() == minute {
+		return t
+	}
+	// time.Date has resolved the skipped time into one of the zones either side of the jump.
+	start, end := t.ZoneBounds()
+	if t.Hour()*60+t.Minute() < minute {
+		return end
+	}
+	return start
+}
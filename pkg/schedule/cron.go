@@ -0,0 +1,169 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a parsed five-field cron expression: minute, hour, day of month, month and day of
+// week, each held as a bit set of the values it allows.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	// Cron's day rule: when both day fields are restricted, a day matching either is allowed.
+	domStar, dowStar bool
+}
+
+// cronField describes the range and value names of one field.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	// 7 is Sunday too, as in most crons.
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// parseCron parses a standard five-field expression such as "0 22 * * mon-thu". Each field takes
+// "*", values, ranges ("1-5"), steps ("*/15", "0-30/10") and comma-separated lists of those;
+// months and weekdays also take three-letter names.
+func parseCron(expr string) (*cronSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron %q: want 5 fields (minute hour day month weekday), got %d", expr, len(fields))
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		sets[i] = set
+	}
+
+	spec := &cronSpec{
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+	if spec.dow&(1<<7) != 0 {
+		spec.dow |= 1
+	}
+	return spec, nil
+}
+
+// parseCronField parses one field into the bit set of the values it allows.
+func parseCronField(field string, def cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, step := part, 1
+		if slash := strings.IndexByte(part, '/'); slash >= 0 {
+			n, err := strconv.Atoi(part[slash+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s: bad step in %q", def.name, part)
+			}
+			expr, step = part[:slash], n
+		}
+
+		low, high := def.min, def.max
+		if expr != "*" {
+			bounds := strings.SplitN(expr, "-", 2)
+			var err error
+			if low, err = cronValue(bounds[0], def); err != nil {
+				return 0, err
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = cronValue(bounds[1], def); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the end, every 15.
+				high = def.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("%s: range %q runs backwards", def.name, expr)
+			}
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// cronValue parses a single value or name of a field.
+func cronValue(s string, def cronField) (int, error) {
+	if v, ok := def.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < def.min || v > def.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", def.name, s, def.min, def.max)
+	}
+	return v, nil
+}
+
+// dayMatches applies cron's day rule.
+func (s *cronSpec) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dow
+	case s.dowStar:
+		return dom
+	}
+	return dom || dow
+}
+
+// cronHorizon bounds the search for the next match. An expression that allows nothing in five
+// years - the 31st of February - never matches.
+const cronHorizon = 5 * 366 * 24 * time.Hour
+
+// next returns the first minute at or after t, in t's location, that the expression matches.
+func (s *cronSpec) next(t time.Time) (time.Time, bool) {
+	if truncated := t.Truncate(time.Minute); !truncated.Equal(t) {
+		t = truncated.Add(time.Minute)
+	}
+	limit := t.Add(cronHorizon)
+	loc := t.Location()
+
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case s.month&(1<<uint(month)) == 0:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			// Stepping to the next hour rather than rebuilding the time keeps DST transitions
+			// honest: the hour a spring-forward skips simply never comes up.
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,239 @@
+package schedule
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadICalendar adds every event in the iCalendar file at path to the schedule's freezes.
+func (s *Schedule) LoadICalendar(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read calendar: %w", err)
+	}
+	defer f.Close()
+
+	freezes, err := ParseICalendar(f, s.location())
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	s.Freezes = append(s.Freezes, freezes...)
+	return nil
+}
+
+// ParseICalendar reads the events of an iCalendar (RFC 5545) stream as freezes, named after
+// their SUMMARY. An event ends at its DTEND or after its DURATION; all-day events cover their
+// days, with DTEND exclusive as the format has it, and an all-day event with neither covers its
+// one day. Times carry their own zone - UTC, or the TZID parameter - and floating times are read
+// in loc. Cancelled events are skipped, and the properties of an event's own components, such
+// as a VALARM's trigger, are not read as the event's.
+//
+// Recurrence is not expanded, so an event with an RRULE or RDATE is an error rather than a
+// freeze of its first occurrence only. Calendar exports of holiday lists normally list each
+// year's dates individually, which suits.
+func ParseICalendar(r io.Reader, loc *time.Location) ([]Freeze, error) {
+	lines, err := unfoldICalendar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var freezes []Freeze
+	var event map[string]icalProperty
+	// nested counts the components open inside the current event.
+	nested := 0
+	for number, line := range lines {
+		name, prop, ok := parseICalendarLine(line)
+		if !ok {
+			continue
+		}
+		value := strings.ToUpper(prop.value)
+		switch {
+		case name == "BEGIN" && value == "VEVENT" && event == nil:
+			event, nested = map[string]icalProperty{}, 0
+		case event == nil:
+			// The calendar's own properties, and components other than events, are not needed.
+		case name == "BEGIN":
+			nested++
+		case name == "END" && nested > 0:
+			nested--
+		case name == "END" && value == "VEVENT":
+			if strings.ToUpper(event["STATUS"].value) != "CANCELLED" {
+				freeze, err := eventFreeze(event, loc)
+				if err != nil {
+					return nil, fmt.Errorf("event ending on line %d: %w", number+1, err)
+				}
+				freezes = append(freezes, freeze)
+			}
+			event = nil
+		case nested == 0:
+			event[name] = prop
+		}
+	}
+	return freezes, nil
+}
+
+// icalProperty is one content line's parameters and value.
+type icalProperty struct {
+	params map[string]string
+	value  string
+}
+
+// unfoldICalendar splits the stream into content lines, joining the continuation lines that
+// start with a space or tab onto the line before.
+func unfoldICalendar(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseICalendarLine splits "DTSTART;TZID=Europe/London:20261220T080000" into its name,
+// parameters and value.
+func parseICalendarLine(line string) (string, icalProperty, bool) {
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return "", icalProperty{}, false
+	}
+	head := strings.Split(line[:colon], ";")
+	prop := icalProperty{params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range head[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return strings.ToUpper(head[0]), prop, true
+}
+
+// eventFreeze turns an event's properties into a freeze.
+func eventFreeze(event map[string]icalProperty, loc *time.Location) (Freeze, error) {
+	for _, name := range []string{"RRULE", "RDATE"} {
+		if _, ok := event[name]; ok {
+			return Freeze{}, fmt.Errorf("%s: recurring events are not expanded; list each occurrence as an event", name)
+		}
+	}
+
+	startProp, ok := event["DTSTART"]
+	if !ok {
+		return Freeze{}, errors.New("no DTSTART")
+	}
+	start, allDay, err := icalTime(startProp, loc)
+	if err != nil {
+		return Freeze{}, fmt.Errorf("DTSTART: %w", err)
+	}
+
+	endProp, hasEnd := event["DTEND"]
+	durationProp, hasDuration := event["DURATION"]
+	var end time.Time
+	switch {
+	case hasEnd && hasDuration:
+		return Freeze{}, errors.New("both DTEND and DURATION")
+	case hasEnd:
+		if end, _, err = icalTime(endProp, loc); err != nil {
+			return Freeze{}, fmt.Errorf("DTEND: %w", err)
+		}
+	case hasDuration:
+		if end, err = icalDuration(start, durationProp.value); err != nil {
+			return Freeze{}, fmt.Errorf("DURATION: %w", err)
+		}
+	case allDay:
+		end = start.AddDate(0, 0, 1)
+	default:
+		return Freeze{}, errors.New("a timed event needs a DTEND or DURATION")
+	}
+	if !end.After(start) {
+		return Freeze{}, errors.New("ends before it starts")
+	}
+
+	summary := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).
+		Replace(event["SUMMARY"].value)
+	return Freeze{Name: summary, Start: start, End: end}, nil
+}
+
+// icalTime reads a DATE or DATE-TIME value. allDay reports a DATE.
+func icalTime(prop icalProperty, loc *time.Location) (t time.Time, allDay bool, err error) {
+	value := prop.value
+	if prop.params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	if tzid := prop.params["TZID"]; tzid != "" {
+		zone, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		loc = zone
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// icalDuration adds a DURATION value such as "P1W", "P2D" or "PT1H30M" to start. Weeks and days
+// are calendar days, so a day-long event keeps its time of day across a DST change; hours,
+// minutes and seconds are exact.
+func icalDuration(start time.Time, value string) (time.Time, error) {
+	bad := fmt.Errorf("%q is not a duration such as P1D or PT1H30M", value)
+	sign := 1
+	rest := strings.TrimPrefix(value, "+")
+	if strings.HasPrefix(rest, "-") {
+		sign, rest = -1, rest[1:]
+	}
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok || rest == "" {
+		return time.Time{}, bad
+	}
+
+	var days int
+	var exact time.Duration
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' && !inTime {
+			inTime, rest = true, rest[1:]
+			continue
+		}
+		digits := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+		if digits == 0 || digits == len(rest) {
+			return time.Time{}, bad
+		}
+		n, err := strconv.Atoi(rest[:digits])
+		if err != nil {
+			return time.Time{}, bad
+		}
+		switch unit := rest[digits]; {
+		case unit == 'W' && !inTime:
+			days += 7 * n
+		case unit == 'D' && !inTime:
+			days += n
+		case unit == 'H' && inTime:
+			exact += time.Duration(n) * time.Hour
+		case unit == 'M' && inTime:
+			exact += time.Duration(n) * time.Minute
+		case unit == 'S' && inTime:
+			exact += time.Duration(n) * time.Second
+		default:
+			return time.Time{}, bad
+		}
+		rest = rest[digits+1:]
+	}
+	return start.AddDate(0, 0, sign*days).Add(time.Duration(sign) * exact), nil
+}
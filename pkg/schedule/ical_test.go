@@ -0,0 +1,122 @@
+package schedule_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netautomate/netorca-go/pkg/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas\\, Boxing Day\r\n" +
+	"DTSTART;VALUE=DATE:20261225\r\n" +
+	"DTEND;VALUE=DATE:20261227\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:New Year\r\n" +
+	"DTSTART:20270101\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Data centre\r\n" +
+	"  migration\r\n" +
+	"DTSTART:20261102T180000Z\r\n" +
+	"DTEND:20261103T060000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Trading day\r\n" +
+	"DTSTART;TZID=America/New_York:20261127T093000\r\n" +
+	"DTEND;TZID=America/New_York:20261127T160000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Floating\r\n" +
+	"DTSTART:20261201T080000\r\n" +
+	"DTEND:20261201T090000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Patch weekend\r\n" +
+	"DTSTART;TZID=Europe/London:20261024T180000\r\n" +
+	"DURATION:P1DT12H\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DTSTART:20261001T000000Z\r\n" +
+	"SUMMARY:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Called off\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20261105\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Audit week\r\n" +
+	"DTSTART;VALUE=DATE:20261109\r\n" +
+	"DURATION:P1W\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICalendar(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	freezes, err := schedule.ParseICalendar(strings.NewReader(testCalendar), london)
+	require.NoError(t, err)
+	require.Len(t, freezes, 7)
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"Christmas, Boxing Day",
+			time.Date(2026, 12, 25, 0, 0, 0, 0, london), time.Date(2026, 12, 27, 0, 0, 0, 0, london)},
+		{"New Year", time.Date(2027, 1, 1, 0, 0, 0, 0, london), time.Date(2027, 1, 2, 0, 0, 0, 0, london)},
+		{"Data centre migration",
+			time.Date(2026, 11, 2, 18, 0, 0, 0, time.UTC), time.Date(2026, 11, 3, 6, 0, 0, 0, time.UTC)},
+		{"Trading day",
+			time.Date(2026, 11, 27, 9, 30, 0, 0, newYork), time.Date(2026, 11, 27, 16, 0, 0, 0, newYork)},
+		{"Floating", time.Date(2026, 12, 1, 8, 0, 0, 0, london), time.Date(2026, 12, 1, 9, 0, 0, 0, london)},
+		// A day across the clocks going back, then twelve hours; the alarm's DTSTART is its own.
+		{"Patch weekend",
+			time.Date(2026, 10, 24, 18, 0, 0, 0, london), time.Date(2026, 10, 26, 6, 0, 0, 0, london)},
+		{"Audit week", time.Date(2026, 11, 9, 0, 0, 0, 0, london), time.Date(2026, 11, 16, 0, 0, 0, 0, london)},
+	}
+	for i, tt := range tests {
+		assert.Equal(t, tt.name, freezes[i].Name)
+		assert.True(t, tt.start.Equal(freezes[i].Start), "%s starts %s", tt.name, freezes[i].Start)
+		assert.True(t, tt.end.Equal(freezes[i].End), "%s ends %s", tt.name, freezes[i].End)
+	}
+}
+
+func TestParseICalendarErrors(t *testing.T) {
+	event := func(lines string) string {
+		return "BEGIN:VCALENDAR\nBEGIN:VEVENT\n" + lines + "END:VEVENT\nEND:VCALENDAR\n"
+	}
+	tests := []struct {
+		name     string
+		calendar string
+		errMsg   string
+	}{
+		{"no start", event("SUMMARY:x\n"), "event ending on line 4: no DTSTART"},
+		{"timed without end", event("DTSTART:20261102T180000Z\n"), "a timed event needs a DTEND or DURATION"},
+		{"end and duration", event("DTSTART:20261102\nDTEND:20261103\nDURATION:P1D\n"), "both DTEND and DURATION"},
+		{"bad duration", event("DTSTART:20261102\nDURATION:1D\n"), `DURATION: "1D" is not a duration`},
+		{"hours outside time", event("DTSTART:20261102\nDURATION:P1H\n"), `DURATION: "P1H" is not a duration`},
+		{"negative duration", event("DTSTART:20261102\nDURATION:-P1D\n"), "ends before it starts"},
+		{"rrule", event("DTSTART:20261225\nRRULE:FREQ=YEARLY\n"), "RRULE: recurring events are not expanded"},
+		{"rdate", event("DTSTART:20261225\nRDATE;VALUE=DATE:20271225\n"), "RDATE: recurring events are not expanded"},
+		{"backwards", event("DTSTART:20261103\nDTEND:20261102\n"), "ends before it starts"},
+		{"unknown zone", event("DTSTART;TZID=Mars/Olympus:20261102T180000\nDTEND:20261103\n"), "DTSTART:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := schedule.ParseICalendar(strings.NewReader(tt.calendar), time.UTC)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
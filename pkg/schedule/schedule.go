@@ -0,0 +1,479 @@
+// Package schedule decides when changes may be made: inside agreed maintenance windows, and
+// never during a freeze. A schedule is written in YAML, with freezes optionally taken from
+// iCalendar files:
+//
+//	timezone: Europe/London
+//	windows:
+//	  - name: weeknights
+//	    days: [mon, tue, wed, thu]
+//	    start: "22:00"
+//	    end: "02:00"            # the next morning
+//	  - name: sunday-morning
+//	    cron: "0 6 * * sun"     # opens at 06:00 every Sunday...
+//	    duration: 3h            # ...for three hours
+//	freezes:
+//	  - name: year-end
+//	    start: 2026-12-19
+//	    end: 2027-01-03         # dates are whole days, both included
+//	holidays: [2026-08-31]
+//	icalendar: [freezes.ics]    # every event is a freeze
+//
+// Check says whether the schedule is open at a moment and, if not, why and until when. Gate
+// turns a schedule into a client.TransitionGate, so approvals and completions made outside a
+// window are deferred with the reason in the change's log:
+//
+//	sched, err := schedule.Load("schedule.yaml")
+//	_, err = nc.CompleteChangeInstanceWithContext(ctx, pov, id, "done", nil, client.WithGate(sched.Gate()))
+//	if errors.Is(err, client.ErrTransitionDeferred) { /* try again once the window opens */ }
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidSchedule is the sentinel for a schedule that cannot be used: malformed YAML, an
+// unknown time zone, a cron expression or time that does not parse, or a freeze that ends
+// before it starts.
+var ErrInvalidSchedule = errors.New("schedule: invalid schedule")
+
+// Schedule is a set of change windows and freezes in one time zone. The zero value is always
+// open; a schedule with no windows is open whenever no freeze is in force.
+type Schedule struct {
+	// Location is the time zone windows and whole-day dates are read in. Defaults to UTC.
+	Location *time.Location
+	// Windows are the periods changes may be made in. Empty means any time.
+	Windows []Window
+	// Freezes are periods no change may be made in, whatever the windows say.
+	Freezes []Freeze
+	// Now is the clock Gate reads. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Window is a recurring period in which changes may be made. It either opens at every minute
+// its cron expression matches and stays open for Duration, or - with Start and End in place of
+// Cron - runs between two wall-clock times of day, so it keeps to them across a DST change.
+type Window struct {
+	// Name identifies the window in explanations.
+	Name string
+	// Cron is a five-field cron expression for the moments the window opens, read in the
+	// schedule's time zone.
+	Cron string
+	// Duration is how long the window stays open each time.
+	Duration time.Duration
+	// Days are the weekdays a Start/End window opens on, as names, numbers or ranges such as
+	// "mon-thu". Empty means every day.
+	Days []string
+	// Start and End are the times of day, such as "22:00", a window without Cron opens and
+	// closes at in the schedule's time zone. An End at or before Start is on the next day.
+	Start, End string
+
+	spec  *cronSpec
+	clock *clockSpec
+}
+
+// NewWindow builds a window, checking its cron expression.
+func NewWindow(name, cron string, duration time.Duration) (Window, error) {
+	spec, err := parseCron(cron)
+	if err != nil {
+		return Window{}, err
+	}
+	if duration <= 0 {
+		return Window{}, errors.New("duration must be positive")
+	}
+	return Window{Name: name, Cron: cron, Duration: duration, spec: spec}, nil
+}
+
+// NewClockWindow builds a window open from start to end, times of day such as "22:00" and
+// "02:00", on the given weekdays - every day when there are none.
+func NewClockWindow(name string, days []string, start, end string) (Window, error) {
+	clock, err := parseClock(days, start, end)
+	if err != nil {
+		return Window{}, err
+	}
+	return Window{Name: name, Days: days, Start: start, End: end, clock: clock}, nil
+}
+
+// parsed returns the window's cron expression or times of day, parsing them if the window was
+// built by hand rather than by NewWindow or NewClockWindow. A window that does not parse is
+// never open.
+func (w *Window) parsed() (*cronSpec, *clockSpec, bool) {
+	if w.spec != nil || w.clock != nil {
+		return w.spec, w.clock, true
+	}
+	if w.Cron == "" {
+		clock, err := parseClock(w.Days, w.Start, w.End)
+		return nil, clock, err == nil
+	}
+	spec, err := parseCron(w.Cron)
+	return spec, nil, err == nil
+}
+
+// Freeze is a period in which no change may be made, from Start up to but not including End.
+type Freeze struct {
+	// Name identifies the freeze in explanations.
+	Name string
+	// Start is the first moment of the freeze.
+	Start time.Time
+	// End is the first moment after it.
+	End time.Time
+}
+
+// Status is a schedule's verdict on one moment.
+type Status struct {
+	// Open reports whether changes may be made.
+	Open bool
+	// Window names the window that is open, when the schedule has windows and one is.
+	Window string
+	// Freeze is the freeze in force, if any.
+	Freeze *Freeze
+	// NextOpen is when the schedule next opens, when it is closed. Zero if it does not open
+	// again within the search horizon of five years.
+	NextOpen time.Time
+	// Reason explains a closed schedule in words fit for a change's log.
+	Reason string
+}
+
+// file is the YAML form of a schedule.
+type file struct {
+	Timezone  string       `yaml:"timezone"`
+	Windows   []fileWindow `yaml:"windows"`
+	Freezes   []fileFreeze `yaml:"freezes"`
+	Holidays  []string     `yaml:"holidays"`
+	ICalendar []string     `yaml:"icalendar"`
+}
+
+type fileWindow struct {
+	Name     string   `yaml:"name"`
+	Cron     string   `yaml:"cron"`
+	Duration string   `yaml:"duration"`
+	Days     []string `yaml:"days"`
+	Start    string   `yaml:"start"`
+	End      string   `yaml:"end"`
+}
+
+type fileFreeze struct {
+	Name  string `yaml:"name"`
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// Parse reads a schedule from YAML. iCalendar paths are read relative to the working directory;
+// use Load to have them read relative to the schedule file.
+func Parse(data []byte) (*Schedule, error) {
+	return parse(data, ".")
+}
+
+// Load reads and parses the schedule file at path.
+func Load(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule: %w", err)
+	}
+	return parse(data, filepath.Dir(path))
+}
+
+func parse(data []byte, dir string) (*Schedule, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var f file
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+
+	s, err := f.schedule(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+	return s, nil
+}
+
+// schedule converts the YAML form, reporting every problem at once.
+func (f *file) schedule(dir string) (*Schedule, error) {
+	loc := time.UTC
+	if f.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(f.Timezone); err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+	}
+	s := &Schedule{Location: loc}
+
+	var errs []error
+	for i, w := range f.Windows {
+		window, err := w.window(i)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.Windows = append(s.Windows, window)
+	}
+
+	for i, fr := range f.Freezes {
+		freeze, err := fr.freeze(loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("freeze %d (%s): %w", i+1, fr.Name, err))
+			continue
+		}
+		s.Freezes = append(s.Freezes, freeze)
+	}
+
+	for _, holiday := range f.Holidays {
+		day, err := time.ParseInLocation(time.DateOnly, holiday, loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("holiday %q: want a date such as 2026-12-25", holiday))
+			continue
+		}
+		s.Freezes = append(s.Freezes, Freeze{Name: "holiday " + holiday, Start: day, End: day.AddDate(0, 0, 1)})
+	}
+
+	for _, path := range f.ICalendar {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if err := s.LoadICalendar(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return s, nil
+}
+
+// window converts one YAML window: either a cron expression and duration, or days with start
+// and end times of day.
+func (w *fileWindow) window(i int) (Window, error) {
+	where := fmt.Sprintf("window %d (%s)", i+1, w.Name)
+	if w.Cron != "" {
+		if len(w.Days) > 0 || w.Start != "" || w.End != "" {
+			return Window{}, fmt.Errorf("%s: use cron and duration, or days, start and end - not both", where)
+		}
+		duration, err := time.ParseDuration(w.Duration)
+		if err != nil {
+			return Window{}, fmt.Errorf("%s: duration: %w", where, err)
+		}
+		window, err := NewWindow(w.Name, w.Cron, duration)
+		if err != nil {
+			return Window{}, fmt.Errorf("%s: %w", where, err)
+		}
+		return window, nil
+	}
+
+	window, err := NewClockWindow(w.Name, w.Days, w.Start, w.End)
+	if err != nil {
+		return Window{}, fmt.Errorf("%s: %w", where, err)
+	}
+	return window, nil
+}
+
+// freeze converts one YAML freeze.
+func (f *fileFreeze) freeze(loc *time.Location) (Freeze, error) {
+	start, _, err := parseMoment(f.Start, loc)
+	if err != nil {
+		return Freeze{}, fmt.Errorf("start: %w", err)
+	}
+	end, endDay, err := parseMoment(f.End, loc)
+	if err != nil {
+		return Freeze{}, fmt.Errorf("end: %w", err)
+	}
+	// A whole-day end includes the day itself.
+	if endDay {
+		end = end.AddDate(0, 0, 1)
+	}
+	if !end.After(start) {
+		return Freeze{}, errors.New("ends before it starts")
+	}
+	return Freeze{Name: f.Name, Start: start, End: end}, nil
+}
+
+// parseMoment reads a freeze bound: a date, which is the start of that day in loc, or a date and
+// time, with or without an offset. day reports a bare date.
+func parseMoment(s string, loc *time.Location) (t time.Time, day bool, err error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%q is not a date or a date and time", s)
+}
+
+// location returns the schedule's time zone.
+func (s *Schedule) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+// freezeAt returns the freeze in force at t, preferring the one that ends last so a caller
+// skipping past it skips past every overlapping freeze in one go.
+func (s *Schedule) freezeAt(t time.Time) *Freeze {
+	var found *Freeze
+	for i := range s.Freezes {
+		freeze := &s.Freezes[i]
+		if !t.Before(freeze.Start) && t.Before(freeze.End) && (found == nil || freeze.End.After(found.End)) {
+			found = freeze
+		}
+	}
+	return found
+}
+
+// windowAt returns the window open at t. With no windows, the schedule is always inside one.
+func (s *Schedule) windowAt(t time.Time) (string, bool) {
+	if len(s.Windows) == 0 {
+		return "", true
+	}
+	t = t.In(s.location())
+	for i := range s.Windows {
+		w := &s.Windows[i]
+		spec, clock, ok := w.parsed()
+		switch {
+		case !ok:
+			continue
+		case clock != nil:
+			if clock.openAt(t) {
+				return w.Name, true
+			}
+			continue
+		}
+		// The window is open if it opened within the last Duration.
+		opened, ok := spec.next(t.Add(-w.Duration).Add(time.Minute).Truncate(time.Minute))
+		if ok && !opened.After(t) {
+			return w.Name, true
+		}
+	}
+	return "", false
+}
+
+// nextWindowOpening returns the first moment after t at which a window opens.
+func (s *Schedule) nextWindowOpening(t time.Time) (time.Time, string, bool) {
+	t = t.In(s.location())
+	var best time.Time
+	var name string
+	for i := range s.Windows {
+		w := &s.Windows[i]
+		spec, clock, ok := w.parsed()
+		if !ok {
+			continue
+		}
+		var opens time.Time
+		if clock != nil {
+			opens, ok = clock.next(t)
+		} else {
+			opens, ok = spec.next(t)
+		}
+		if ok && (best.IsZero() || opens.Before(best)) {
+			best, name = opens, w.Name
+		}
+	}
+	return best, name, !best.IsZero()
+}
+
+// maxOpenSearch bounds the hops NextOpen takes between freezes and windows.
+const maxOpenSearch = 10000
+
+// Check reports whether the schedule is open at t and, if not, why and until when.
+func (s *Schedule) Check(t time.Time) Status {
+	freeze := s.freezeAt(t)
+	window, inWindow := s.windowAt(t)
+	if freeze == nil && inWindow {
+		return Status{Open: true, Window: window}
+	}
+
+	status := Status{Freeze: freeze}
+	if freeze != nil {
+		status.Reason = fmt.Sprintf("inside the %s freeze until %s", freezeName(freeze), s.format(freeze.End))
+	} else {
+		status.Reason = "outside the change windows"
+	}
+
+	if next, nextWindow, ok := s.NextOpen(t); ok {
+		status.NextOpen = next
+		if nextWindow != "" {
+			status.Reason += fmt.Sprintf("; the next window (%s) opens %s", nextWindow, s.format(next))
+		} else if freeze == nil || !next.Equal(freeze.End) {
+			status.Reason += "; changes may go ahead from " + s.format(next)
+		}
+	} else {
+		status.Reason += "; no change window opens in the next five years"
+	}
+	return status
+}
+
+// NextOpen returns the first moment at or after t at which the schedule is open, and the window
+// open then (empty for a schedule with no windows).
+func (s *Schedule) NextOpen(t time.Time) (time.Time, string, bool) {
+	candidate := t
+	for range maxOpenSearch {
+		if freeze := s.freezeAt(candidate); freeze != nil {
+			candidate = freeze.End
+			continue
+		}
+		if window, ok := s.windowAt(candidate); ok {
+			return candidate.In(s.location()), window, true
+		}
+		next, _, ok := s.nextWindowOpening(candidate)
+		if !ok {
+			return time.Time{}, "", false
+		}
+		candidate = next
+	}
+	return time.Time{}, "", false
+}
+
+func freezeName(freeze *Freeze) string {
+	if freeze.Name == "" {
+		return "change"
+	}
+	return freeze.Name
+}
+
+// format writes a time for explanations, in the schedule's time zone.
+func (s *Schedule) format(t time.Time) string {
+	return t.In(s.location()).Format("2006-01-02 15:04 MST")
+}
+
+// Gate returns a client.TransitionGate that defers moves to the given states while the schedule
+// is closed, with the schedule's explanation as the reason and its next opening as the time to
+// retry. It gates APPROVED and COMPLETED when no state is given; other moves - a rejection, an
+// ERROR report - are never held back.
+func (s *Schedule) Gate(states ...client.ChangeInstanceState) client.TransitionGate {
+	if len(states) == 0 {
+		states = []client.ChangeInstanceState{client.ChangeInstanceAPPROVED, client.ChangeInstanceCOMPLETED}
+	}
+	gated := map[client.ChangeInstanceState]bool{}
+	for _, state := range states {
+		gated[state] = true
+	}
+
+	return func(_ context.Context, id int, to client.ChangeInstanceState) error {
+		if !gated[to] {
+			return nil
+		}
+		now := time.Now
+		if s.Now != nil {
+			now = s.Now
+		}
+		status := s.Check(now())
+		if status.Open {
+			return nil
+		}
+		return &client.DeferredTransitionError{ID: id, To: to, Reason: status.Reason, Until: status.NextOpen}
+	}
+}
@@ -0,0 +1,275 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/netautomate/netorca-go/pkg/schedule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchedule = `
+timezone: Europe/London
+windows:
+  - name: weeknights
+    days: [mon, tue, wed, thu]
+    start: "22:00"
+    end: "02:00"
+  - name: sunday-morning
+    cron: "0 6 * * sun"
+    duration: 3h
+freezes:
+  - name: year-end
+    start: 2026-12-19
+    end: 2027-01-03
+holidays: [2026-08-31]
+`
+
+func mustParse(t *testing.T) *schedule.Schedule {
+	t.Helper()
+	s, err := schedule.Parse([]byte(testSchedule))
+	require.NoError(t, err)
+	return s
+}
+
+// london reads a wall-clock time in the schedule's time zone.
+func london(t *testing.T, value string) time.Time {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	at, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	require.NoError(t, err)
+	return at
+}
+
+func TestCheck(t *testing.T) {
+	s := mustParse(t)
+
+	tests := []struct {
+		name     string
+		at       string
+		window   string
+		nextOpen string
+	}{
+		{name: "monday night", at: "2026-10-19 23:00", window: "weeknights"},
+		{name: "after midnight", at: "2026-10-20 01:30", window: "weeknights"},
+		{name: "tuesday lunchtime", at: "2026-10-20 12:00", nextOpen: "2026-10-20 22:00"},
+		{name: "window closes", at: "2026-10-20 02:00", nextOpen: "2026-10-20 22:00"},
+		{name: "friday night waits for sunday", at: "2026-10-23 22:00", nextOpen: "2026-10-25 06:00"},
+		{name: "sunday morning", at: "2026-10-25 08:59", window: "sunday-morning"},
+		{name: "year-end freeze", at: "2026-12-21 23:00", nextOpen: "2027-01-04 22:00"},
+		{name: "holiday", at: "2026-08-31 23:00", nextOpen: "2026-09-01 00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := s.Check(london(t, tt.at))
+			if tt.nextOpen == "" {
+				assert.True(t, status.Open, status.Reason)
+				assert.Equal(t, tt.window, status.Window)
+				return
+			}
+			assert.False(t, status.Open)
+			assert.True(t, london(t, tt.nextOpen).Equal(status.NextOpen), "next open %s", status.NextOpen)
+		})
+	}
+}
+
+func TestCheckReasons(t *testing.T) {
+	s := mustParse(t)
+
+	status := s.Check(london(t, "2026-10-20 12:00"))
+	assert.Equal(t, "outside the change windows; the next window (weeknights) opens 2026-10-20 22:00 BST",
+		status.Reason)
+	assert.Nil(t, status.Freeze)
+
+	status = s.Check(london(t, "2026-12-21 23:00"))
+	require.NotNil(t, status.Freeze)
+	assert.Equal(t, "year-end", status.Freeze.Name)
+	assert.Equal(t, "inside the year-end freeze until 2027-01-04 00:00 GMT; "+
+		"the next window (weeknights) opens 2027-01-04 22:00 GMT", status.Reason)
+}
+
+func TestFreezesWithoutWindows(t *testing.T) {
+	assert.True(t, (&schedule.Schedule{}).Check(time.Now()).Open, "the zero schedule is always open")
+
+	start := time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC)
+	s := schedule.Schedule{Freezes: []schedule.Freeze{
+		{Name: "christmas", Start: start, End: start.AddDate(0, 0, 2)},
+		{Start: start.AddDate(0, 0, 1), End: start.AddDate(0, 0, 3)},
+	}}
+
+	status := s.Check(start.Add(time.Hour))
+	assert.False(t, status.Open)
+	assert.Equal(t, "inside the christmas freeze until 2026-12-26 00:00 UTC; "+
+		"changes may go ahead from 2026-12-27 00:00 UTC", status.Reason)
+
+	status = s.Check(start.AddDate(0, 0, 1))
+	assert.Equal(t, "inside the change freeze until 2026-12-27 00:00 UTC", status.Reason,
+		"the overlapping freeze that ends last is reported")
+	assert.True(t, start.AddDate(0, 0, 3).Equal(status.NextOpen))
+	assert.True(t, s.Check(start.AddDate(0, 0, 3)).Open, "a freeze's end is not part of it")
+}
+
+func TestCronWindows(t *testing.T) {
+	// Day of month and day of week both restricted: a day matching either will do.
+	w, err := schedule.NewWindow("13th-or-friday", "30 12 13 * fri", time.Hour)
+	require.NoError(t, err)
+	s := schedule.Schedule{Windows: []schedule.Window{w}}
+
+	assert.True(t, s.Check(time.Date(2026, 10, 13, 12, 45, 0, 0, time.UTC)).Open, "the 13th, a Tuesday")
+	assert.True(t, s.Check(time.Date(2026, 10, 23, 13, 29, 0, 0, time.UTC)).Open, "a Friday")
+	assert.False(t, s.Check(time.Date(2026, 10, 23, 13, 30, 0, 0, time.UTC)).Open)
+	assert.False(t, s.Check(time.Date(2026, 10, 22, 12, 45, 0, 0, time.UTC)).Open)
+
+	// Steps and month names.
+	w, err = schedule.NewWindow("quarter-hours", "*/15 9-17 * jan-mar,dec mon-fri", time.Minute)
+	require.NoError(t, err)
+	s = schedule.Schedule{Windows: []schedule.Window{w}}
+	next, window, ok := s.NextOpen(time.Date(2026, 10, 23, 9, 1, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, "quarter-hours", window)
+	assert.Equal(t, time.Date(2026, 12, 1, 9, 0, 0, 0, time.UTC), next)
+
+	// A window that never opens.
+	w, err = schedule.NewWindow("never", "0 0 31 feb *", time.Hour)
+	require.NoError(t, err)
+	s = schedule.Schedule{Windows: []schedule.Window{w}}
+	status := s.Check(time.Date(2026, 10, 23, 9, 0, 0, 0, time.UTC))
+	assert.False(t, status.Open)
+	assert.True(t, status.NextOpen.IsZero())
+	assert.Equal(t, "outside the change windows; no change window opens in the next five years", status.Reason)
+}
+
+// TestClockWindowsAcrossDST checks that days/start/end windows keep to their times of day on the
+// nights the clocks change, rather than opening for a fixed length of time.
+func TestClockWindowsAcrossDST(t *testing.T) {
+	// The clocks go back from 02:00 BST to 01:00 GMT early on Sunday 2026-10-25.
+	s, err := schedule.Parse([]byte("timezone: Europe/London\n" +
+		"windows: [{name: saturday-night, days: [sat], start: '22:00', end: '02:00'}]\n"))
+	require.NoError(t, err)
+	fallBack := time.Date(2026, 10, 25, 1, 0, 0, 0, time.UTC)
+	assert.True(t, s.Check(fallBack.Add(-30*time.Minute)).Open, "01:30 BST")
+	assert.True(t, s.Check(fallBack).Open, "01:00 GMT, the hour repeated")
+	assert.True(t, s.Check(fallBack.Add(59*time.Minute)).Open, "01:59 GMT")
+	assert.False(t, s.Check(fallBack.Add(time.Hour)).Open, "02:00 GMT")
+
+	// The clocks go forward from 01:00 GMT to 02:00 BST early on Sunday 2026-03-29.
+	s, err = schedule.Parse([]byte("timezone: Europe/London\n" +
+		"windows: [{name: sunday-small-hours, days: [sun], start: '01:30', end: '03:00'}]\n"))
+	require.NoError(t, err)
+	springForward := time.Date(2026, 3, 29, 1, 0, 0, 0, time.UTC)
+	assert.False(t, s.Check(springForward.Add(-time.Minute)).Open, "00:59 GMT")
+	assert.True(t, s.Check(springForward).Open, "02:00 BST, as 01:30 is skipped")
+	assert.True(t, s.Check(springForward.Add(30*time.Minute)).Open, "02:30 BST")
+	assert.False(t, s.Check(springForward.Add(time.Hour)).Open, "03:00 BST")
+
+	status := s.Check(time.Date(2026, 3, 28, 12, 0, 0, 0, time.UTC))
+	assert.True(t, springForward.Equal(status.NextOpen), "it opens as the clocks jump, got %s", status.NextOpen)
+	next, _, ok := s.NextOpen(springForward.Add(time.Hour))
+	require.True(t, ok)
+	assert.Equal(t, london(t, "2026-04-05 01:30"), next)
+
+	// Built by hand, the same window reads its days and times when it is first checked.
+	s = &schedule.Schedule{Location: s.Location, Windows: []schedule.Window{
+		{Name: "sunday-small-hours", Days: []string{"sun"}, Start: "01:30", End: "03:00"},
+	}}
+	assert.True(t, s.Check(springForward).Open)
+}
+
+func TestNewWindowErrors(t *testing.T) {
+	tests := []struct {
+		cron   string
+		errMsg string
+	}{
+		{"0 22 * *", "want 5 fields"},
+		{"0 24 * * *", `hour: "24" is not between 0 and 23`},
+		{"0 22 * * fri-mon", `day of week: range "fri-mon" runs backwards`},
+		{"*/0 22 * * *", `minute: bad step in "*/0"`},
+		{"0 22 * smarch *", `month: "smarch" is not between 1 and 12`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cron, func(t *testing.T) {
+			_, err := schedule.NewWindow("w", tt.cron, time.Hour)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+
+	_, err := schedule.NewWindow("w", "0 22 * * *", 0)
+	assert.EqualError(t, err, "duration must be positive")
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		errMsg string
+	}{
+		{"unknown field", "window: []\n", "field window not found"},
+		{"unknown time zone", "timezone: Mars/Olympus\n", "timezone: unknown time zone Mars/Olympus"},
+		{"both forms", "windows:\n  - {name: w, cron: '0 6 * * *', duration: 1h, days: [mon]}\n",
+			"window 1 (w): use cron and duration, or days, start and end - not both"},
+		{"bad duration", "windows:\n  - {name: w, cron: '0 6 * * *', duration: soon}\n", "window 1 (w): duration:"},
+		{"bad start", "windows:\n  - {name: w, start: '10pm', end: '02:00'}\n", "window 1 (w): start:"},
+		{"bad days", "windows:\n  - {name: w, days: [someday], start: '22:00', end: '02:00'}\n",
+			`window 1 (w): days: day of week: "someday"`},
+		{"backwards freeze", "freezes:\n  - {name: f, start: 2026-12-19, end: 2026-12-01}\n",
+			"freeze 1 (f): ends before it starts"},
+		{"bad holiday", "holidays: [christmas]\n", `holiday "christmas": want a date`},
+		{"missing calendar", "icalendar: [/nonexistent/freezes.ics]\n", "failed to read calendar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := schedule.Parse([]byte(tt.yaml))
+			require.ErrorIs(t, err, schedule.ErrInvalidSchedule)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schedule.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testSchedule+"icalendar: [freezes.ics]\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "freezes.ics"), []byte(
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Launch\r\nDTSTART;VALUE=DATE:20261020\r\n"+
+			"END:VEVENT\r\nEND:VCALENDAR\r\n"), 0o600))
+
+	s, err := schedule.Load(path)
+	require.NoError(t, err, "the calendar is read relative to the schedule file")
+
+	status := s.Check(london(t, "2026-10-20 23:00"))
+	assert.False(t, status.Open)
+	assert.Equal(t, "Launch", status.Freeze.Name)
+
+	_, err = schedule.Load(filepath.Join(dir, "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read schedule")
+}
+
+func TestGate(t *testing.T) {
+	s := mustParse(t)
+	now := london(t, "2026-10-20 12:00")
+	s.Now = func() time.Time { return now }
+	gate := s.Gate()
+
+	err := gate(context.Background(), 7, client.ChangeInstanceCOMPLETED)
+	require.ErrorIs(t, err, client.ErrTransitionDeferred)
+	var deferred *client.DeferredTransitionError
+	require.True(t, errors.As(err, &deferred))
+	assert.Equal(t, 7, deferred.ID)
+	assert.True(t, london(t, "2026-10-20 22:00").Equal(deferred.Until))
+
+	require.NoError(t, gate(context.Background(), 7, client.ChangeInstanceREJECTED), "rejections are not held back")
+
+	now = london(t, "2026-10-20 22:30")
+	require.NoError(t, gate(context.Background(), 7, client.ChangeInstanceCOMPLETED))
+
+	now = london(t, "2026-10-20 12:00")
+	require.NoError(t, s.Gate(client.ChangeInstanceCOMPLETED)(context.Background(), 7, client.ChangeInstanceAPPROVED),
+		"only the given states are gated")
+}
@@ -0,0 +1,440 @@
+// Package webhook receives NetOrca change notifications over HTTP, as an alternative to polling
+// for new change instances.
+//
+// A Receiver is an http.Handler. It verifies each delivery - by HMAC signature or by shared
+// secret - rejects deliveries outside the replay window or seen before, decodes the payload into
+// the client package's own types and dispatches a typed event to the callbacks registered for it:
+//
+//	receiver, err := webhook.NewReceiver(webhook.Options{Secret: os.Getenv("WEBHOOK_SECRET")})
+//	receiver.OnChangeInstanceCreated(func(ctx context.Context, event *webhook.ChangeInstanceEvent) error {
+//		return queue(event.ChangeInstance.ID)
+//	})
+//	http.Handle("/netorca/events", receiver)
+//
+// The webhooktest package builds signed sample deliveries for testing a receiver end to end.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+)
+
+// The headers a delivery carries.
+const (
+	// HeaderSignature carries "sha256=" and the hex HMAC-SHA256 of the timestamp, a dot and the
+	// body, keyed with the secret. Used in VerifyHMAC mode.
+	HeaderSignature = "X-NetOrca-Signature"
+	// HeaderToken carries the shared secret itself. Used in VerifySharedSecret mode.
+	HeaderToken = "X-NetOrca-Token"
+	// HeaderTimestamp carries the delivery time as Unix seconds. Required in both modes.
+	HeaderTimestamp = "X-NetOrca-Timestamp"
+)
+
+// Sentinel errors for deliveries the receiver refuses. ServeHTTP maps each onto a status code;
+// Verify returns them wrapped, for callers running their own handler.
+var (
+	// ErrInvalidSignature is returned for a missing or wrong signature or token.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	// ErrStaleDelivery is returned for a delivery whose timestamp is missing, malformed or
+	// outside the replay window.
+	ErrStaleDelivery = errors.New("webhook: delivery outside the replay window")
+)
+
+// Verification selects how deliveries are authenticated.
+type Verification int
+
+const (
+	// VerifyHMAC checks HeaderSignature, which binds the secret to both the body and the
+	// timestamp. It is the default, and the mode to prefer: the secret never crosses the wire.
+	VerifyHMAC Verification = iota
+	// VerifySharedSecret compares HeaderToken with the secret, for senders that cannot sign.
+	VerifySharedSecret
+)
+
+// EventType names a kind of delivery.
+type EventType string
+
+const (
+	// EventChangeInstanceCreated is sent when a submission raises a change instance.
+	EventChangeInstanceCreated EventType = "change_instance.created"
+	// EventChangeInstanceStateChanged is sent when a change instance moves between states.
+	EventChangeInstanceStateChanged EventType = "change_instance.state_changed"
+	// EventServiceItemCreated is sent when a service item is first declared.
+	EventServiceItemCreated EventType = "service_item.created"
+	// EventServiceItemStateChanged is sent when a service item's runtime or change state moves.
+	EventServiceItemStateChanged EventType = "service_item.state_changed"
+)
+
+// Payload is the body of a delivery, as sent on the wire.
+type Payload struct {
+	// ID identifies the event. Redeliveries of one event share it, which is what lets the
+	// receiver drop duplicates.
+	ID string `json:"id"`
+	// Type names the event.
+	Type EventType `json:"type"`
+	// Created is when the event happened, which may be well before a retried delivery.
+	Created time.Time `json:"created"`
+	// Data is the object the event is about: a change instance or a service item.
+	Data json.RawMessage `json:"data"`
+	// Previous holds the fields a state change moved away from, and is absent otherwise.
+	Previous json.RawMessage `json:"previous,omitempty"`
+}
+
+// Event is the metadata shared by every typed event.
+type Event struct {
+	ID      string
+	Type    EventType
+	Created time.Time
+}
+
+// ChangeInstanceEvent is delivered for the change instance event types.
+type ChangeInstanceEvent struct {
+	Event
+	// ChangeInstance is the change as it stands after the event.
+	ChangeInstance client.ChangeInstance
+	// PreviousState is the state a change moved away from. Empty for a created event.
+	PreviousState client.ChangeInstanceState
+}
+
+// ServiceItemEvent is delivered for the service item event types.
+type ServiceItemEvent struct {
+	Event
+	// ServiceItem is the item as it stands after the event.
+	ServiceItem client.ServiceItem
+	// PreviousRuntimeState is the runtime state the item moved away from, if that changed.
+	PreviousRuntimeState client.RuntimeState
+	// PreviousChangeState is the change state the item moved away from, if that changed.
+	PreviousChangeState client.ChangeState
+}
+
+// ChangeInstanceCallback handles a change instance event. Returning an error answers the delivery
+// with a 500, so NetOrca retries it.
+type ChangeInstanceCallback func(ctx context.Context, event *ChangeInstanceEvent) error
+
+// ServiceItemCallback handles a service item event. Returning an error answers the delivery with
+// a 500, so NetOrca retries it.
+type ServiceItemCallback func(ctx context.Context, event *ServiceItemEvent) error
+
+// Options configures a Receiver.
+type Options struct {
+	// Secret is the webhook secret configured in NetOrca. Required.
+	Secret string
+	// Verification selects how deliveries are authenticated. Defaults to VerifyHMAC.
+	Verification Verification
+	// ReplayWindow is how far a delivery's timestamp may be from now, in either direction. Event
+	// ids are remembered for duplicate detection until their timestamp is that far in the past.
+	// Defaults to 5 minutes.
+	ReplayWindow time.Duration
+	// MaxBodyBytes caps the size of a delivery. Defaults to 1 MiB.
+	MaxBodyBytes int64
+	// Now returns the current time. Defaults to time.Now; tests replace it to pin the clock.
+	Now func() time.Time
+	// Logf receives refused deliveries and callback failures. Defaults to log.Printf.
+	Logf func(format string, args ...any)
+}
+
+// Validate reports settings that cannot be defaulted into something sensible.
+func (o *Options) Validate() error {
+	if o.Secret == "" {
+		return fmt.Errorf("webhook: secret cannot be empty")
+	}
+	if o.Verification != VerifyHMAC && o.Verification != VerifySharedSecret {
+		return fmt.Errorf("webhook: unknown verification mode %d", o.Verification)
+	}
+	if o.ReplayWindow < 0 || o.MaxBodyBytes < 0 {
+		return fmt.Errorf("webhook: replay window and body limit cannot be negative")
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the options with every unset field defaulted.
+func (o Options) withDefaults() Options {
+	if o.ReplayWindow == 0 {
+		o.ReplayWindow = 5 * time.Minute
+	}
+	if o.MaxBodyBytes == 0 {
+		o.MaxBodyBytes = 1 << 20
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	if o.Logf == nil {
+		o.Logf = log.Printf
+	}
+	return o
+}
+
+// Receiver verifies and dispatches webhook deliveries. Create one with NewReceiver; it is safe
+// to register callbacks while it serves.
+type Receiver struct {
+	opts Options
+
+	mu                   sync.Mutex
+	changeInstanceEvents map[EventType][]ChangeInstanceCallback
+	serviceItemEvents    map[EventType][]ServiceItemCallback
+	// seen maps recently accepted event ids to when they may be forgotten: the moment their
+	// delivery's timestamp falls out of the replay window.
+	seen map[string]time.Time
+}
+
+// NewReceiver builds a receiver with the given options.
+func NewReceiver(opts Options) (*Receiver, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Receiver{
+		opts:                 opts.withDefaults(),
+		changeInstanceEvents: map[EventType][]ChangeInstanceCallback{},
+		serviceItemEvents:    map[EventType][]ServiceItemCallback{},
+		seen:                 map[string]time.Time{},
+	}, nil
+}
+
+// OnChangeInstanceCreated registers a callback for EventChangeInstanceCreated.
+func (r *Receiver) OnChangeInstanceCreated(callback ChangeInstanceCallback) {
+	r.onChangeInstance(EventChangeInstanceCreated, callback)
+}
+
+// OnChangeInstanceStateChanged registers a callback for EventChangeInstanceStateChanged.
+func (r *Receiver) OnChangeInstanceStateChanged(callback ChangeInstanceCallback) {
+	r.onChangeInstance(EventChangeInstanceStateChanged, callback)
+}
+
+// OnServiceItemCreated registers a callback for EventServiceItemCreated.
+func (r *Receiver) OnServiceItemCreated(callback ServiceItemCallback) {
+	r.onServiceItem(EventServiceItemCreated, callback)
+}
+
+// OnServiceItemStateChanged registers a callback for EventServiceItemStateChanged.
+func (r *Receiver) OnServiceItemStateChanged(callback ServiceItemCallback) {
+	r.onServiceItem(EventServiceItemStateChanged, callback)
+}
+
+func (r *Receiver) onChangeInstance(eventType EventType, callback ChangeInstanceCallback) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changeInstanceEvents[eventType] = append(r.changeInstanceEvents[eventType], callback)
+}
+
+func (r *Receiver) onServiceItem(eventType EventType, callback ServiceItemCallback) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.serviceItemEvents[eventType] = append(r.serviceItemEvents[eventType], callback)
+}
+
+// Sign returns the HeaderSignature value for a body delivered at the given time.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify authenticates a delivery's headers against its body and checks its timestamp falls
+// inside the replay window. It does not check for duplicates, which needs the payload's id.
+func (r *Receiver) Verify(header http.Header, body []byte) error {
+	timestamp, err := deliveryTimestamp(header)
+	if err != nil {
+		return err
+	}
+	if skew := r.opts.Now().Sub(timestamp).Abs(); skew > r.opts.ReplayWindow {
+		return fmt.Errorf("%w: timestamp is %s away", ErrStaleDelivery, skew.Round(time.Second))
+	}
+
+	switch r.opts.Verification {
+	case VerifySharedSecret:
+		token := header.Get(HeaderToken)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(r.opts.Secret)) != 1 {
+			return fmt.Errorf("%w: token does not match", ErrInvalidSignature)
+		}
+	default:
+		expected := Sign(r.opts.Secret, timestamp, body)
+		if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(expected)) {
+			return fmt.Errorf("%w: signature does not match", ErrInvalidSignature)
+		}
+	}
+	return nil
+}
+
+// deliveryTimestamp reads the time a delivery was stamped with.
+func deliveryTimestamp(header http.Header) (time.Time, error) {
+	raw := header.Get(HeaderTimestamp)
+	seconds, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: bad timestamp %q", ErrStaleDelivery, raw)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// ServeHTTP implements http.Handler. It answers:
+//   - 204 once every callback for the event has succeeded, for a duplicate delivery, and for an
+//     event type this package does not know, so NetOrca stops retrying it;
+//   - 400 for a malformed payload, 401 for a failed signature or a stale timestamp, 405 for
+//     anything but POST and 413 for an oversized body;
+//   - 500 when a callback fails, so NetOrca retries the delivery.
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.opts.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "delivery too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read delivery", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.Verify(req.Header, body); err != nil {
+		r.opts.Logf("webhook: refused delivery: %v", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "malformed payload", http.StatusBadRequest)
+		return
+	}
+	if payload.ID == "" {
+		http.Error(w, "payload has no id", http.StatusBadRequest)
+		return
+	}
+
+	timestamp, _ := deliveryTimestamp(req.Header) // checked by Verify
+	if !r.claim(payload.ID, timestamp) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := r.dispatch(req.Context(), &payload); err != nil {
+		// Forget the id, so the retry this 500 triggers is not taken for a duplicate.
+		r.release(payload.ID)
+		if errors.Is(err, errMalformed) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.opts.Logf("webhook: event %s (%s) failed: %v", payload.ID, payload.Type, err)
+		http.Error(w, "callback failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errMalformed marks a payload whose data does not decode into the type its event names.
+var errMalformed = errors.New("malformed event data")
+
+// dispatch decodes the payload into its typed event and runs the callbacks registered for it.
+func (r *Receiver) dispatch(ctx context.Context, payload *Payload) error {
+	meta := Event{ID: payload.ID, Type: payload.Type, Created: payload.Created}
+
+	switch payload.Type {
+	case EventChangeInstanceCreated, EventChangeInstanceStateChanged:
+		event := &ChangeInstanceEvent{Event: meta}
+		var previous struct {
+			State client.ChangeInstanceState `json:"state"`
+		}
+		if err := decodeEventData(payload, &event.ChangeInstance, &previous); err != nil {
+			return err
+		}
+		event.PreviousState = previous.State
+
+		r.mu.Lock()
+		callbacks := r.changeInstanceEvents[payload.Type]
+		r.mu.Unlock()
+		for _, callback := range callbacks {
+			if err := callback(ctx, event); err != nil {
+				return err
+			}
+		}
+
+	case EventServiceItemCreated, EventServiceItemStateChanged:
+		event := &ServiceItemEvent{Event: meta}
+		var previous struct {
+			RuntimeState client.RuntimeState `json:"runtime_state"`
+			ChangeState  client.ChangeState  `json:"change_state"`
+		}
+		if err := decodeEventData(payload, &event.ServiceItem, &previous); err != nil {
+			return err
+		}
+		event.PreviousRuntimeState = previous.RuntimeState
+		event.PreviousChangeState = previous.ChangeState
+
+		r.mu.Lock()
+		callbacks := r.serviceItemEvents[payload.Type]
+		r.mu.Unlock()
+		for _, callback := range callbacks {
+			if err := callback(ctx, event); err != nil {
+				return err
+			}
+		}
+
+	default:
+		// A newer NetOrca may send events this package does not model yet. Acknowledging
+		// them keeps the sender from retrying something no callback could ever handle.
+		r.opts.Logf("webhook: ignoring event %s of unknown type %q", payload.ID, payload.Type)
+	}
+	return nil
+}
+
+// decodeEventData decodes a payload's data and, when present, its previous fields.
+func decodeEventData(payload *Payload, data, previous any) error {
+	if err := json.Unmarshal(payload.Data, data); err != nil {
+		return fmt.Errorf("%w for %s: %w", errMalformed, payload.Type, err)
+	}
+	if len(payload.Previous) > 0 && !strings.EqualFold(string(payload.Previous), "null") {
+		if err := json.Unmarshal(payload.Previous, previous); err != nil {
+			return fmt.Errorf("%w for %s: previous: %w", errMalformed, payload.Type, err)
+		}
+	}
+	return nil
+}
+
+// claim records an event id as accepted, returning false if it was accepted already. Each id is
+// kept until its delivery's timestamp plus the replay window - not for a window after it was
+// accepted, since a delivery stamped in the future stays valid for longer than that - and pruned
+// as it goes: a replay after that is refused by its timestamp anyway.
+func (r *Receiver) claim(id string, timestamp time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.opts.Now()
+	for seenID, until := range r.seen {
+		if now.After(until) {
+			delete(r.seen, seenID)
+		}
+	}
+	if _, duplicate := r.seen[id]; duplicate {
+		return false
+	}
+	r.seen[id] = timestamp.Add(r.opts.ReplayWindow)
+	return true
+}
+
+// release forgets an event id, so a redelivery is processed afresh.
+func (r *Receiver) release(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.seen, id)
+}
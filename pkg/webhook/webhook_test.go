@@ -0,0 +1,218 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/netautomate/netorca-go/pkg/webhook"
+	"github.com/netautomate/netorca-go/pkg/webhook/webhooktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "s3cret"
+
+func newReceiver(t *testing.T, opts webhook.Options) *webhook.Receiver {
+	t.Helper()
+	if opts.Secret == "" {
+		opts.Secret = testSecret
+	}
+	opts.Logf = t.Logf
+	receiver, err := webhook.NewReceiver(opts)
+	require.NoError(t, err)
+	return receiver
+}
+
+// serve runs one delivery through the receiver and returns the status code.
+func serve(receiver *webhook.Receiver, req *http.Request) int {
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestReceiverDispatchesTypedEvents(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{})
+	sender := webhooktest.NewSender(testSecret)
+
+	var created, changed []*webhook.ChangeInstanceEvent
+	var items []*webhook.ServiceItemEvent
+	receiver.OnChangeInstanceCreated(func(_ context.Context, event *webhook.ChangeInstanceEvent) error {
+		created = append(created, event)
+		return nil
+	})
+	receiver.OnChangeInstanceStateChanged(func(_ context.Context, event *webhook.ChangeInstanceEvent) error {
+		changed = append(changed, event)
+		return nil
+	})
+	receiver.OnServiceItemStateChanged(func(_ context.Context, event *webhook.ServiceItemEvent) error {
+		items = append(items, event)
+		return nil
+	})
+
+	ci := webhooktest.SampleChangeInstance()
+	assert.Equal(t, http.StatusNoContent, serve(receiver, sender.ChangeInstanceCreated(ci)))
+
+	ci.State = client.ChangeInstanceAPPROVED
+	assert.Equal(t, http.StatusNoContent,
+		serve(receiver, sender.ChangeInstanceStateChanged(ci, client.ChangeInstancePENDING)))
+
+	item := webhooktest.SampleServiceItem()
+	assert.Equal(t, http.StatusNoContent,
+		serve(receiver, sender.ServiceItemStateChanged(item, client.RuntimeStateOutOfService)))
+
+	// no callback is registered for this one, which is still a successful delivery
+	assert.Equal(t, http.StatusNoContent, serve(receiver, sender.ServiceItemCreated(item)))
+
+	require.Len(t, created, 1)
+	assert.Equal(t, webhook.EventChangeInstanceCreated, created[0].Type)
+	assert.Equal(t, 53, created[0].ChangeInstance.ID)
+	assert.Equal(t, client.ChangeTypeCreate, created[0].ChangeInstance.ChangeType)
+	assert.Empty(t, created[0].PreviousState)
+
+	require.Len(t, changed, 1)
+	assert.Equal(t, client.ChangeInstanceAPPROVED, changed[0].ChangeInstance.State)
+	assert.Equal(t, client.ChangeInstancePENDING, changed[0].PreviousState)
+
+	require.Len(t, items, 1)
+	assert.Equal(t, client.RuntimeStateInService, items[0].ServiceItem.RuntimeState)
+	assert.Equal(t, client.RuntimeStateOutOfService, items[0].PreviousRuntimeState)
+}
+
+func TestReceiverVerification(t *testing.T) {
+	ci := webhooktest.SampleChangeInstance()
+
+	t.Run("wrong HMAC secret", func(t *testing.T) {
+		receiver := newReceiver(t, webhook.Options{})
+		assert.Equal(t, http.StatusUnauthorized,
+			serve(receiver, webhooktest.NewSender("other").ChangeInstanceCreated(ci)))
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		receiver := newReceiver(t, webhook.Options{})
+		req := webhooktest.NewSender(testSecret).Request([]byte(`{"id":"evt-1","type":"x"}`))
+		tampered := webhooktest.NewSender("").Request([]byte(`{"id":"evt-2","type":"x"}`))
+		tampered.Header = req.Header
+		assert.Equal(t, http.StatusUnauthorized, serve(receiver, tampered))
+	})
+
+	t.Run("shared secret", func(t *testing.T) {
+		receiver := newReceiver(t, webhook.Options{Verification: webhook.VerifySharedSecret})
+		sender := &webhooktest.Sender{Secret: testSecret, Verification: webhook.VerifySharedSecret}
+		assert.Equal(t, http.StatusNoContent, serve(receiver, sender.ChangeInstanceCreated(ci)))
+
+		sender.Secret = "guess"
+		assert.Equal(t, http.StatusUnauthorized, serve(receiver, sender.ChangeInstanceCreated(ci)))
+	})
+
+	t.Run("HMAC signature is not accepted as a token", func(t *testing.T) {
+		receiver := newReceiver(t, webhook.Options{Verification: webhook.VerifySharedSecret})
+		assert.Equal(t, http.StatusUnauthorized,
+			serve(receiver, webhooktest.NewSender(testSecret).ChangeInstanceCreated(ci)))
+	})
+
+	t.Run("Verify wraps the sentinels", func(t *testing.T) {
+		receiver := newReceiver(t, webhook.Options{})
+		req := webhooktest.NewSender("other").ChangeInstanceCreated(ci)
+		req.Header.Del(webhook.HeaderTimestamp)
+		require.ErrorIs(t, receiver.Verify(req.Header, nil), webhook.ErrStaleDelivery)
+
+		req = webhooktest.NewSender("other").ChangeInstanceCreated(ci)
+		require.ErrorIs(t, receiver.Verify(req.Header, nil), webhook.ErrInvalidSignature)
+	})
+}
+
+func TestReceiverReplayProtection(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	receiver := newReceiver(t, webhook.Options{Now: func() time.Time { return now }})
+	sender := &webhooktest.Sender{Secret: testSecret, Now: func() time.Time { return now }}
+
+	calls := 0
+	receiver.OnChangeInstanceCreated(func(context.Context, *webhook.ChangeInstanceEvent) error {
+		calls++
+		return nil
+	})
+
+	first := sender.ChangeInstanceCreated(webhooktest.SampleChangeInstance())
+	assert.Equal(t, http.StatusNoContent, serve(receiver, first))
+	assert.Equal(t, http.StatusNoContent, serve(receiver, sender.Redeliver(first)), "a duplicate is acknowledged")
+	assert.Equal(t, 1, calls, "but not dispatched twice")
+
+	stale := &webhooktest.Sender{Secret: testSecret, Now: func() time.Time { return now.Add(-10 * time.Minute) }}
+	assert.Equal(t, http.StatusUnauthorized,
+		serve(receiver, stale.ChangeInstanceCreated(webhooktest.SampleChangeInstance())))
+	assert.Equal(t, 1, calls)
+}
+
+func TestReceiverReplayProtectionForFutureStampedDeliveries(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	receiver := newReceiver(t, webhook.Options{Now: func() time.Time { return now }})
+	// The sender's clock runs four minutes fast: its deliveries stay valid until 12:09.
+	sender := &webhooktest.Sender{Secret: testSecret, Now: func() time.Time { return now.Add(4 * time.Minute) }}
+
+	calls := 0
+	receiver.OnChangeInstanceCreated(func(context.Context, *webhook.ChangeInstanceEvent) error {
+		calls++
+		return nil
+	})
+
+	first := sender.ChangeInstanceCreated(webhooktest.SampleChangeInstance())
+	replay := sender.Redeliver(first)
+	assert.Equal(t, http.StatusNoContent, serve(receiver, first))
+
+	now = now.Add(6 * time.Minute)
+	assert.Equal(t, http.StatusNoContent, serve(receiver, replay), "a replay its timestamp still admits")
+	assert.Equal(t, 1, calls, "is still recognised as a duplicate")
+}
+
+func TestReceiverCallbackFailureAllowsRetry(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{})
+	sender := webhooktest.NewSender(testSecret)
+
+	fail := true
+	receiver.OnChangeInstanceCreated(func(context.Context, *webhook.ChangeInstanceEvent) error {
+		if fail {
+			return errors.New("queue unavailable")
+		}
+		return nil
+	})
+
+	first := sender.ChangeInstanceCreated(webhooktest.SampleChangeInstance())
+	assert.Equal(t, http.StatusInternalServerError, serve(receiver, first))
+
+	fail = false
+	assert.Equal(t, http.StatusNoContent, serve(receiver, sender.Redeliver(first)),
+		"the retry of a failed delivery is not taken for a duplicate")
+}
+
+func TestReceiverRejectsBadRequests(t *testing.T) {
+	receiver := newReceiver(t, webhook.Options{MaxBodyBytes: 64})
+	sender := webhooktest.NewSender(testSecret)
+
+	get := httptest.NewRequest(http.MethodGet, webhooktest.Target, nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(receiver, get))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge,
+		serve(receiver, sender.Request([]byte(`{"id":"`+strings.Repeat("x", 100)+`"}`))))
+
+	assert.Equal(t, http.StatusBadRequest, serve(receiver, sender.Request([]byte(`{not json`))))
+	assert.Equal(t, http.StatusBadRequest, serve(receiver, sender.Request([]byte(`{"type":"change_instance.created"}`))))
+	assert.Equal(t, http.StatusBadRequest,
+		serve(receiver, sender.Request([]byte(`{"id":"e","type":"change_instance.created","data":[1]}`))))
+
+	// an event type from a newer platform is acknowledged rather than retried forever
+	assert.Equal(t, http.StatusNoContent, serve(receiver, sender.Request([]byte(`{"id":"e2","type":"team.renamed"}`))))
+}
+
+func TestNewReceiverValidation(t *testing.T) {
+	_, err := webhook.NewReceiver(webhook.Options{})
+	require.Error(t, err)
+
+	_, err = webhook.NewReceiver(webhook.Options{Secret: "x", Verification: webhook.Verification(9)})
+	require.Error(t, err)
+}
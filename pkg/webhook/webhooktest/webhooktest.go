@@ -0,0 +1,160 @@
+// Package webhooktest builds signed NetOrca webhook deliveries, for testing a webhook.Receiver -
+// and the callbacks behind it - without a NetOrca instance.
+//
+//	sender := webhooktest.NewSender("secret")
+//	rec := httptest.NewRecorder()
+//	receiver.ServeHTTP(rec, sender.ChangeInstanceCreated(webhooktest.SampleChangeInstance()))
+package webhooktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/netautomate/netorca-go/pkg/webhook"
+)
+
+// Target is the URL sample deliveries are addressed to. Receivers do not look at it.
+const Target = "http://receiver.example/netorca/events"
+
+// Sender signs sample deliveries the way NetOrca does.
+type Sender struct {
+	// Secret is the secret deliveries are signed or authenticated with.
+	Secret string
+	// Verification selects the header the secret is carried in. Defaults to webhook.VerifyHMAC.
+	Verification webhook.Verification
+	// Now returns the delivery time stamped on each request. Defaults to time.Now; set it to
+	// produce stale deliveries.
+	Now func() time.Time
+
+	sequence atomic.Int64
+}
+
+// NewSender returns a sender signing HMAC deliveries with the given secret.
+func NewSender(secret string) *Sender {
+	return &Sender{Secret: secret}
+}
+
+// ChangeInstanceCreated builds a change_instance.created delivery.
+func (s *Sender) ChangeInstanceCreated(ci client.ChangeInstance) *http.Request {
+	return s.Deliver(webhook.EventChangeInstanceCreated, ci, nil)
+}
+
+// ChangeInstanceStateChanged builds a change_instance.state_changed delivery for a change that
+// moved from the previous state to the one it carries.
+func (s *Sender) ChangeInstanceStateChanged(
+	ci client.ChangeInstance,
+	previous client.ChangeInstanceState,
+) *http.Request {
+	return s.Deliver(webhook.EventChangeInstanceStateChanged, ci, map[string]any{"state": previous})
+}
+
+// ServiceItemCreated builds a service_item.created delivery.
+func (s *Sender) ServiceItemCreated(item client.ServiceItem) *http.Request {
+	return s.Deliver(webhook.EventServiceItemCreated, item, nil)
+}
+
+// ServiceItemStateChanged builds a service_item.state_changed delivery for an item that moved
+// from the previous runtime state to the one it carries.
+func (s *Sender) ServiceItemStateChanged(item client.ServiceItem, previous client.RuntimeState) *http.Request {
+	return s.Deliver(webhook.EventServiceItemStateChanged, item, map[string]any{"runtime_state": previous})
+}
+
+// Deliver builds a signed delivery of any event type, with a fresh event id. Use Redeliver to
+// send the same event again.
+func (s *Sender) Deliver(eventType webhook.EventType, data, previous any) *http.Request {
+	payload := webhook.Payload{
+		ID:      fmt.Sprintf("evt-%d", s.sequence.Add(1)),
+		Type:    eventType,
+		Created: s.now().UTC().Truncate(time.Second),
+		Data:    mustMarshal(data),
+	}
+	if previous != nil {
+		payload.Previous = mustMarshal(previous)
+	}
+	return s.Request(mustMarshal(payload))
+}
+
+// Redeliver builds a fresh, correctly signed request carrying the same body as an earlier one
+// built by a sender, as NetOrca does when it retries.
+func (s *Sender) Redeliver(req *http.Request) *http.Request {
+	body, err := req.GetBody()
+	if err != nil {
+		panic(fmt.Sprintf("webhooktest: request body cannot be replayed: %v", err))
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(body); err != nil {
+		panic(fmt.Sprintf("webhooktest: reading request body: %v", err))
+	}
+	return s.Request(buf.Bytes())
+}
+
+// Request signs an arbitrary body, for deliveries the typed helpers cannot express such as a
+// malformed payload.
+func (s *Sender) Request(body []byte) *http.Request {
+	now := s.now()
+	req := httptest.NewRequest(http.MethodPost, Target, bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	if s.Verification == webhook.VerifySharedSecret {
+		req.Header.Set(webhook.HeaderToken, s.Secret)
+	} else {
+		req.Header.Set(webhook.HeaderSignature, webhook.Sign(s.Secret, now, body))
+	}
+	return req
+}
+
+func (s *Sender) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// SampleChangeInstance returns a PENDING CREATE change on a virtual machine service, enough for
+// a callback to route on.
+func SampleChangeInstance() client.ChangeInstance {
+	return client.ChangeInstance{
+		ID:         53,
+		State:      client.ChangeInstancePENDING,
+		ChangeType: client.ChangeTypeCreate,
+		Service:    client.ChangeInstanceService{ID: 7, Name: "virtual_machine", AllowManualApproval: true},
+		ServiceItem: client.ServiceItem{
+			ID:   389,
+			Name: "web-01",
+		},
+		NewDeclaration: client.Declaration{
+			Version:     1,
+			Declaration: json.RawMessage(`{"name":"web-01","cpu":2}`),
+		},
+	}
+}
+
+// SampleServiceItem returns an in-service virtual machine item.
+func SampleServiceItem() client.ServiceItem {
+	return client.ServiceItem{
+		ID:           389,
+		Name:         "web-01",
+		RuntimeState: client.RuntimeStateInService,
+		ChangeState:  client.ChangeStateOK,
+	}
+}
+
+// mustMarshal encodes test data, which is always encodable.
+func mustMarshal(v any) json.RawMessage {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("webhooktest: %v", err))
+	}
+	return raw
+}
@@ -0,0 +1,532 @@
+// Package worker runs the poll-dispatch-report loop every service owning team otherwise writes
+// by hand: list the change instances waiting on your services, hand each one to the code for its
+// service, and report the outcome back through the change instance state helpers.
+//
+// A minimal worker registers a handler per service and runs until its context is cancelled:
+//
+//	w, err := worker.New(nc, worker.Options{Concurrency: 4})
+//	w.Handle("virtual_machine", client.ChangeTypeCreate, createVM)
+//	w.Handle("virtual_machine", "", otherVMChanges) // any other change type
+//	err = w.Run(ctx)
+//
+// A handler sees each change once per state it is polled in: one that approves a PENDING change
+// is called again when the change comes back as APPROVED, which is where it does the work and
+// completes it.
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/netautomate/netorca-go/pkg/client"
+)
+
+// ErrNoHandlers is returned by Run and RunOnce when no handler has been registered, since such a
+// worker could only ever poll and discard.
+var ErrNoHandlers = errors.New("worker: no handlers registered")
+
+// Handler processes one change instance and reports what should happen to it.
+//
+// The context carries the per-instance timeout. A returned error is reported as the ERROR state
+// with the error text as the log, exactly as if the handler had returned Fail; a panic is
+// reported the same way, with the stack appended.
+type Handler func(ctx context.Context, ci *client.ChangeInstance) (Result, error)
+
+// Outcome is what the worker does with a change instance once its handler returns.
+type Outcome int
+
+const (
+	// OutcomeSkip leaves the change instance untouched; it is offered again on the next poll.
+	OutcomeSkip Outcome = iota
+	// OutcomeApprove moves the change instance to APPROVED.
+	OutcomeApprove
+	// OutcomeComplete moves the change instance to COMPLETED.
+	OutcomeComplete
+	// OutcomeReject moves the change instance to REJECTED.
+	OutcomeReject
+	// OutcomeClose moves the change instance to CLOSED.
+	OutcomeClose
+	// OutcomeError moves the change instance to ERROR.
+	OutcomeError
+)
+
+// String returns the outcome's name, for logs.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSkip:
+		return "skip"
+	case OutcomeApprove:
+		return "approve"
+	case OutcomeComplete:
+		return "complete"
+	case OutcomeReject:
+		return "reject"
+	case OutcomeClose:
+		return "close"
+	case OutcomeError:
+		return "error"
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// stateHelper is the shape shared by the client's context-first state helpers.
+type stateHelper func(
+	*client.Client, context.Context, client.POV, int, string, json.RawMessage, ...client.TransitionOption,
+) (*client.ChangeInstance, error)
+
+// outcomeHelpers maps each outcome onto the state helper that reports it.
+var outcomeHelpers = map[Outcome]stateHelper{
+	OutcomeApprove:  (*client.Client).ApproveChangeInstanceWithContext,
+	OutcomeComplete: (*client.Client).CompleteChangeInstanceWithContext,
+	OutcomeReject:   (*client.Client).RejectChangeInstanceWithContext,
+	OutcomeClose:    (*client.Client).CloseChangeInstanceWithContext,
+	OutcomeError:    (*client.Client).SetErrorChangeInstanceWithContext,
+}
+
+// Result is a handler's verdict on one change instance. Build it with Skip, Approve, Complete,
+// Reject, Close or Fail rather than by hand.
+type Result struct {
+	// Outcome is the transition to report.
+	Outcome Outcome
+	// Log is recorded against the change as the reason for the transition. For a rejection it is
+	// what the consumer reads, so write it for them.
+	Log string
+	// DeployedItem records what was built. Nil leaves the linked deployed item untouched.
+	DeployedItem json.RawMessage
+}
+
+// Skip leaves the change instance as it is, to be offered again on the next poll.
+func Skip() Result {
+	return Result{Outcome: OutcomeSkip}
+}
+
+// Approve reports the change instance as APPROVED.
+func Approve(logStr string) Result {
+	return Result{Outcome: OutcomeApprove, Log: logStr}
+}
+
+// Complete reports the change instance as COMPLETED, with the deployed item that was built.
+func Complete(logStr string, deployedItem json.RawMessage) Result {
+	return Result{Outcome: OutcomeComplete, Log: logStr, DeployedItem: deployedItem}
+}
+
+// Reject reports the change instance as REJECTED.
+func Reject(logStr string) Result {
+	return Result{Outcome: OutcomeReject, Log: logStr}
+}
+
+// Close reports the change instance as CLOSED.
+func Close(logStr string) Result {
+	return Result{Outcome: OutcomeClose, Log: logStr}
+}
+
+// Fail reports the change instance as ERROR. Pass the deployed item when a partial build left
+// something behind worth recording.
+func Fail(logStr string, deployedItem json.RawMessage) Result {
+	return Result{Outcome: OutcomeError, Log: logStr, DeployedItem: deployedItem}
+}
+
+// Options tunes a worker. The zero value is usable: every field has a default.
+type Options struct {
+	// POV is the point of view the worker lists and reports from. Defaults to serviceowner.
+	POV client.POV
+	// States are the change instance states polled for. Defaults to PENDING and APPROVED. ERROR
+	// is left out on purpose, so a failed change waits for a person rather than being retried in
+	// a loop.
+	States []client.ChangeInstanceState
+	// PollInterval is the pause between polls in Run. Defaults to 30 seconds.
+	PollInterval time.Duration
+	// Concurrency is how many handlers may run at once. Defaults to 4.
+	Concurrency int
+	// HandlerTimeout bounds each handler call. A handler still running at the deadline has its
+	// change reported as ERROR and its context cancelled. One that ignores the cancellation keeps
+	// running and its result is discarded, but it holds its Concurrency slot, and its change is
+	// not dispatched again, until it does return; Run and RunOnce do not wait for it. Defaults
+	// to 5 minutes.
+	HandlerTimeout time.Duration
+	// PageSize is the listing page size. Defaults to 100.
+	PageSize int
+	// TransitionOptions are passed to every state helper call - client.Guarded(), for instance,
+	// to have each outcome checked against the current state before it is sent.
+	TransitionOptions []client.TransitionOption
+	// Gate holds changes back while it is closed - a change window from pkg/schedule, typically.
+	// Before a PENDING or APPROVED change reaches its handler, the gate is asked about the move
+	// the handler would make next, to APPROVED or to COMPLETED. If it defers, the handler is not
+	// called, the reason is appended to the change's log - once, not on every poll - and the
+	// change is offered again on the next poll. The gate is also applied to every report, so a
+	// window that closes while a handler runs defers its result rather than letting it through.
+	Gate client.TransitionGate
+	// Logf receives the worker's diagnostics: listing failures, handler failures and reports the
+	// API refused. Defaults to log.Printf.
+	Logf func(format string, args ...any)
+}
+
+// Validate reports settings that cannot be defaulted into something sensible.
+func (o *Options) Validate() error {
+	if o.POV != "" {
+		if err := o.POV.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.PollInterval < 0 || o.HandlerTimeout < 0 {
+		return fmt.Errorf("worker: poll interval and handler timeout cannot be negative")
+	}
+	if o.Concurrency < 0 || o.PageSize < 0 {
+		return fmt.Errorf("worker: concurrency and page size cannot be negative")
+	}
+	for _, state := range o.States {
+		if !state.IsKnown() {
+			return fmt.Errorf("worker: unknown change instance state %q", state)
+		}
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the options with every unset field defaulted.
+func (o Options) withDefaults() Options {
+	if o.POV == "" {
+		o.POV = client.POVServiceOwner
+	}
+	if len(o.States) == 0 {
+		o.States = []client.ChangeInstanceState{client.ChangeInstancePENDING, client.ChangeInstanceAPPROVED}
+	}
+	if o.PollInterval == 0 {
+		o.PollInterval = 30 * time.Second
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = 4
+	}
+	if o.HandlerTimeout == 0 {
+		o.HandlerTimeout = 5 * time.Minute
+	}
+	if o.PageSize == 0 {
+		o.PageSize = 100
+	}
+	if o.Logf == nil {
+		o.Logf = log.Printf
+	}
+	if o.Gate != nil {
+		// A fresh slice, so the caller's backing array is never appended to.
+		o.TransitionOptions = append(append([]client.TransitionOption{}, o.TransitionOptions...), client.WithGate(o.Gate))
+	}
+	return o
+}
+
+// gatedMoves maps the states a gate is consulted in onto the move a handler makes from them.
+var gatedMoves = map[client.ChangeInstanceState]client.ChangeInstanceState{
+	client.ChangeInstancePENDING:  client.ChangeInstanceAPPROVED,
+	client.ChangeInstanceAPPROVED: client.ChangeInstanceCOMPLETED,
+}
+
+// handlerKey identifies a registered handler. An empty change type matches any.
+type handlerKey struct {
+	service    string
+	changeType client.ChangeType
+}
+
+// Worker polls for change instances and dispatches them to registered handlers. Create one with
+// New; it is safe to register handlers while it runs.
+type Worker struct {
+	client *client.Client
+	opts   Options
+	slots  chan struct{}
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	handlers map[handlerKey]Handler
+	inFlight map[int]struct{}
+}
+
+// New builds a worker around the given client.
+func New(nc *client.Client, opts Options) (*Worker, error) {
+	if nc == nil {
+		return nil, fmt.Errorf("worker: client cannot be nil")
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+
+	return &Worker{
+		client:   nc,
+		opts:     opts,
+		slots:    make(chan struct{}, opts.Concurrency),
+		handlers: map[handlerKey]Handler{},
+		inFlight: map[int]struct{}{},
+	}, nil
+}
+
+// Handle registers the handler for a service's changes of one type. Pass an empty change type to
+// handle every type the service has no more specific handler for. Registering the same pair
+// twice replaces the earlier handler.
+func (w *Worker) Handle(service string, changeType client.ChangeType, handler Handler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers[handlerKey{service: service, changeType: changeType}] = handler
+}
+
+// Run polls and dispatches until the context is cancelled, then shuts down gracefully: it stops
+// polling, lets handlers already running finish (each still bounded by HandlerTimeout) and
+// reports their outcomes before returning nil.
+//
+// A failed poll is logged and retried on the next interval, so a flaky API does not stop the
+// worker.
+func (w *Worker) Run(ctx context.Context) error {
+	if !w.hasHandlers() {
+		return ErrNoHandlers
+	}
+	defer w.wg.Wait()
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+			w.opts.Logf("worker: poll failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs a single poll, waits for every handler it dispatched and returns. It suits
+// workers driven by an external scheduler such as a cron job. Unlike Run it returns a failed
+// listing as an error.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	if !w.hasHandlers() {
+		return ErrNoHandlers
+	}
+	err := w.poll(ctx)
+	w.wg.Wait()
+	return err
+}
+
+// hasHandlers reports whether any handler is registered.
+func (w *Worker) hasHandlers() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.handlers) > 0
+}
+
+// services returns the registered service names, sorted so polls run in a stable order.
+func (w *Worker) services() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	seen := map[string]bool{}
+	var services []string
+	for key := range w.handlers {
+		if !seen[key.service] {
+			seen[key.service] = true
+			services = append(services, key.service)
+		}
+	}
+	sort.Strings(services)
+	return services
+}
+
+// handlerFor returns the handler for a change instance: the one for its exact change type, else
+// the service's catch-all, else nil.
+func (w *Worker) handlerFor(ci *client.ChangeInstance) Handler {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if handler, ok := w.handlers[handlerKey{service: ci.Service.Name, changeType: ci.ChangeType}]; ok {
+		return handler
+	}
+	return w.handlers[handlerKey{service: ci.Service.Name}]
+}
+
+// poll lists each registered service's waiting changes and dispatches them. Listing per service
+// keeps the platform doing the filtering, rather than paging through every change the team owns.
+func (w *Worker) poll(ctx context.Context) error {
+	var errs []error
+	for _, service := range w.services() {
+		if err := w.pollService(ctx, service); err != nil {
+			errs = append(errs, fmt.Errorf("listing changes for service %s: %w", service, err))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pollService pages through one service's waiting changes, dispatching each as it goes.
+func (w *Worker) pollService(ctx context.Context, service string) error {
+	filters := &client.GetChangeInstancesRequest{
+		POV:         w.opts.POV,
+		ServiceName: service,
+		States:      w.opts.States,
+		Limit:       w.opts.PageSize,
+	}
+
+	for {
+		page, err := w.client.GetChangeInstancesWithContext(ctx, filters)
+		if err != nil {
+			return err
+		}
+		for i := range page.Results {
+			if !w.dispatch(ctx, &page.Results[i]) {
+				return nil
+			}
+		}
+		if page.Next == nil || len(page.Results) == 0 {
+			return nil
+		}
+		filters.Offset += len(page.Results)
+	}
+}
+
+// dispatch starts a handler for the change instance once a concurrency slot is free. Changes with
+// no handler, or whose handler is still running from an earlier poll, are passed over. It
+// returns false when the context ended while waiting for a slot.
+func (w *Worker) dispatch(ctx context.Context, ci *client.ChangeInstance) bool {
+	handler := w.handlerFor(ci)
+	if handler == nil {
+		return true
+	}
+
+	w.mu.Lock()
+	if _, busy := w.inFlight[ci.ID]; busy {
+		w.mu.Unlock()
+		return true
+	}
+	w.mu.Unlock()
+
+	select {
+	case w.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+
+	w.mu.Lock()
+	w.inFlight[ci.ID] = struct{}{}
+	w.mu.Unlock()
+
+	// Handlers outlive a cancelled Run on purpose: shutdown waits for them rather than abandoning
+	// changes half built. Only the per-instance timeout bounds them.
+	detached := context.WithoutCancel(ctx)
+
+	w.wg.Add(1)
+	go func() {
+		overrun := w.process(detached, handler, ci)
+		if overrun == nil {
+			w.release(ci.ID)
+			w.wg.Done()
+			return
+		}
+		// A handler that ignored its timeout is no longer waited on, but it still counts against
+		// Concurrency and still owns its change until it returns.
+		w.wg.Done()
+		<-overrun
+		w.release(ci.ID)
+	}()
+	return true
+}
+
+// release frees the change instance's concurrency slot and lets it be dispatched again.
+func (w *Worker) release(id int) {
+	w.mu.Lock()
+	delete(w.inFlight, id)
+	w.mu.Unlock()
+	<-w.slots
+}
+
+// process runs the handler and reports its result, unless the gate holds the change back. It
+// returns the overrun channel from invoke.
+func (w *Worker) process(ctx context.Context, handler Handler, ci *client.ChangeInstance) <-chan struct{} {
+	if w.deferred(ctx, ci) {
+		return nil
+	}
+	result, overrun := w.invoke(ctx, handler, ci)
+	if result.Outcome == OutcomeSkip {
+		return overrun
+	}
+
+	helper, ok := outcomeHelpers[result.Outcome]
+	if !ok {
+		w.opts.Logf("worker: change instance %d: handler returned unknown outcome %s", ci.ID, result.Outcome)
+		return overrun
+	}
+	if result.Outcome == OutcomeError {
+		w.opts.Logf("worker: change instance %d failed: %s", ci.ID, result.Log)
+	}
+
+	_, err := helper(w.client, ctx, w.opts.POV, ci.ID, result.Log, result.DeployedItem, w.opts.TransitionOptions...)
+	if err != nil && !errors.Is(err, client.ErrTransitionDeferred) {
+		w.opts.Logf("worker: change instance %d: failed to report %s: %v", ci.ID, result.Outcome, err)
+	}
+	return overrun
+}
+
+// deferred consults the gate about the move the handler would make next, and reports whether
+// the change is being held back. A deferral is appended to the change's log unless the log
+// already ends with it; a gate that fails outright is logged here and the change offered again
+// next poll.
+func (w *Worker) deferred(ctx context.Context, ci *client.ChangeInstance) bool {
+	next, ok := gatedMoves[ci.State]
+	if w.opts.Gate == nil || !ok {
+		return false
+	}
+
+	err := w.opts.Gate(ctx, ci.ID, next)
+	if err == nil {
+		return false
+	}
+	var deferral *client.DeferredTransitionError
+	if !errors.As(err, &deferral) {
+		w.opts.Logf("worker: change instance %d: gate refused %s: %v", ci.ID, next, err)
+		return true
+	}
+
+	recorded := *deferral
+	recorded.ID, recorded.To = ci.ID, next
+	if err := w.client.RecordDeferral(ctx, w.opts.POV, &recorded); err != nil {
+		w.opts.Logf("worker: change instance %d: failed to record deferral: %v", ci.ID, err)
+	}
+	return true
+}
+
+// invoke calls the handler under the per-instance timeout, turning an error, a panic or an
+// overrun into a Fail result. When the handler overran, it also returns a channel closed once
+// the handler finally returns; otherwise the channel is nil.
+func (w *Worker) invoke(ctx context.Context, handler Handler, ci *client.ChangeInstance) (Result, <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(ctx, w.opts.HandlerTimeout)
+	defer cancel()
+
+	// Buffered, so a handler that finishes after the timeout can still deliver and exit.
+	done := make(chan Result, 1)
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		defer func() {
+			if r := recover(); r != nil {
+				done <- Fail(fmt.Sprintf("handler panicked: %v\n\n%s", r, debug.Stack()), nil)
+			}
+		}()
+
+		result, err := handler(ctx, ci)
+		if err != nil {
+			result = Fail(err.Error(), result.DeployedItem)
+		}
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		return result, nil
+	case <-ctx.Done():
+		return Fail(fmt.Sprintf("handler timed out after %s", w.opts.HandlerTimeout), nil), exited
+	}
+}
@@ -0,0 +1,412 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/netautomate/netorca-go/pkg/client"
+	"github.com/netautomate/netorca-go/pkg/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBaseURL         = "http://api-aws.demo.netorca.io"
+	changeInstancesRoot = testBaseURL + "/v1/orcabase/serviceowner/change_instances/"
+)
+
+// newTestClient builds a client pointed at the mocked base URL.
+func newTestClient(t *testing.T) *client.Client {
+	t.Helper()
+	nc, err := client.NewClient(testBaseURL, "test-api-key", "v1", 5*time.Second)
+	require.NoError(t, err)
+	return nc
+}
+
+// changeJSON renders a change instance as the listing returns it.
+func changeJSON(id int, service string, changeType client.ChangeType) string {
+	return fmt.Sprintf(`{"id":%d,"state":"PENDING","change_type":"%s","service":{"id":1,"name":"%s"}}`,
+		id, changeType, service)
+}
+
+// patches records every listing query and state report the worker sends, the reports keyed by
+// change instance id.
+type patches struct {
+	mu      sync.Mutex
+	queries []string
+	bodies  map[int]map[string]any
+}
+
+// register mocks the listing with the given changes and records the PATCHes that follow.
+func (p *patches) register(t *testing.T, changes ...string) {
+	t.Helper()
+	p.bodies = map[int]map[string]any{}
+
+	httpmock.RegisterResponder("GET", changeInstancesRoot, func(req *http.Request) (*http.Response, error) {
+		p.mu.Lock()
+		p.queries = append(p.queries, req.URL.RawQuery)
+		p.mu.Unlock()
+
+		var matching []string
+		for _, change := range changes {
+			if strings.Contains(change, `"name":"`+req.URL.Query().Get("service_name")+`"`) {
+				matching = append(matching, change)
+			}
+		}
+		return httpmock.NewStringResponse(200, fmt.Sprintf(`{"count":%d,"next":null,"previous":null,"results":[%s]}`,
+			len(matching), strings.Join(matching, ","))), nil
+	})
+	httpmock.RegisterRegexpResponder("PATCH", regexpChangeInstance,
+		func(req *http.Request) (*http.Response, error) {
+			var id int
+			_, err := fmt.Sscanf(strings.TrimPrefix(req.URL.Path, "/v1/orcabase/serviceowner/change_instances/"),
+				"%d/", &id)
+			require.NoError(t, err)
+			raw, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(raw, &body))
+
+			p.mu.Lock()
+			p.bodies[id] = body
+			p.mu.Unlock()
+			return httpmock.NewStringResponse(200, fmt.Sprintf(`{"id":%d,"state":%q}`, id, body["state"])), nil
+		})
+}
+
+// get returns the body reported for a change instance, or nil.
+func (p *patches) get(id int) map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.bodies[id]
+}
+
+var regexpChangeInstance = regexp.MustCompile(`/change_instances/\d+/$`)
+
+func newWorker(t *testing.T, opts worker.Options) *worker.Worker {
+	t.Helper()
+	if opts.Logf == nil {
+		opts.Logf = t.Logf
+	}
+	w, err := worker.New(newTestClient(t), opts)
+	require.NoError(t, err)
+	return w
+}
+
+func TestWorkerDispatch(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var reported patches
+	reported.register(t,
+		changeJSON(1, "vm", client.ChangeTypeCreate),
+		changeJSON(2, "vm", client.ChangeTypeDelete),
+		changeJSON(3, "lb", client.ChangeTypeCreate),
+		changeJSON(4, "dns", client.ChangeTypeCreate),
+	)
+
+	w := newWorker(t, worker.Options{})
+	w.Handle("vm", client.ChangeTypeCreate, func(_ context.Context, ci *client.ChangeInstance) (worker.Result, error) {
+		return worker.Complete("built", json.RawMessage(`{"ip":"10.0.0.1"}`)), nil
+	})
+	w.Handle("vm", "", func(_ context.Context, ci *client.ChangeInstance) (worker.Result, error) {
+		return worker.Reject("deletes go through the change board"), nil
+	})
+	w.Handle("lb", "", func(_ context.Context, ci *client.ChangeInstance) (worker.Result, error) {
+		return worker.Skip(), nil
+	})
+
+	require.NoError(t, w.RunOnce(context.Background()))
+
+	assert.Equal(t, map[string]any{
+		"state": "COMPLETED", "log": "built", "deployed_item": map[string]any{"ip": "10.0.0.1"},
+	}, reported.get(1))
+	assert.Equal(t, map[string]any{"state": "REJECTED", "log": "deletes go through the change board"},
+		reported.get(2))
+	assert.Nil(t, reported.get(3), "a skipped change is left untouched")
+	assert.Nil(t, reported.get(4), "a service without a handler is never polled")
+
+	assert.Equal(t, []string{
+		"limit=100&service_name=lb&state=PENDING%2CAPPROVED",
+		"limit=100&service_name=vm&state=PENDING%2CAPPROVED",
+	}, reported.queries)
+}
+
+func TestWorkerFailures(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var reported patches
+	reported.register(t,
+		changeJSON(1, "panics", client.ChangeTypeCreate),
+		changeJSON(2, "errors", client.ChangeTypeCreate),
+		changeJSON(3, "hangs", client.ChangeTypeCreate),
+	)
+
+	w := newWorker(t, worker.Options{HandlerTimeout: 50 * time.Millisecond})
+	w.Handle("panics", "", func(context.Context, *client.ChangeInstance) (worker.Result, error) {
+		panic("nil map")
+	})
+	w.Handle("errors", "", func(context.Context, *client.ChangeInstance) (worker.Result, error) {
+		return worker.Result{}, errors.New("quota exceeded")
+	})
+	w.Handle("hangs", "", func(ctx context.Context, _ *client.ChangeInstance) (worker.Result, error) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return worker.Complete("too late", nil), nil
+	})
+
+	require.NoError(t, w.RunOnce(context.Background()))
+
+	panicked := reported.get(1)
+	assert.Equal(t, "ERROR", panicked["state"])
+	assert.Contains(t, panicked["log"], "handler panicked: nil map")
+	assert.Contains(t, panicked["log"], "worker_test.go", "the stack is recorded in the log")
+
+	assert.Equal(t, map[string]any{"state": "ERROR", "log": "quota exceeded"}, reported.get(2))
+	assert.Equal(t, map[string]any{"state": "ERROR", "log": "handler timed out after 50ms"}, reported.get(3))
+}
+
+func TestWorkerConcurrencyLimit(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	changes := make([]string, 0, 6)
+	for id := 1; id <= 6; id++ {
+		changes = append(changes, changeJSON(id, "vm", client.ChangeTypeCreate))
+	}
+	var reported patches
+	reported.register(t, changes...)
+
+	var running, peak atomic.Int32
+	w := newWorker(t, worker.Options{Concurrency: 2})
+	w.Handle("vm", "", func(context.Context, *client.ChangeInstance) (worker.Result, error) {
+		now := running.Add(1)
+		for {
+			seen := peak.Load()
+			if now <= seen || peak.CompareAndSwap(seen, now) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return worker.Approve("ok"), nil
+	})
+
+	require.NoError(t, w.RunOnce(context.Background()))
+
+	assert.Equal(t, int32(2), peak.Load())
+	for id := 1; id <= 6; id++ {
+		assert.Equal(t, "APPROVED", reported.get(id)["state"])
+	}
+}
+
+// TestWorkerOverrunKeepsItsSlot has a handler ignore its timeout: its change is reported as
+// ERROR, but the handler keeps its slot and its change until it really returns.
+func TestWorkerOverrunKeepsItsSlot(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var reported patches
+	reported.register(t, changeJSON(1, "vm", client.ChangeTypeCreate), changeJSON(2, "vm", client.ChangeTypeCreate))
+
+	stuck := make(chan struct{})
+	var secondStarted atomic.Bool
+	w := newWorker(t, worker.Options{Concurrency: 1, HandlerTimeout: 20 * time.Millisecond})
+	w.Handle("vm", "", func(_ context.Context, ci *client.ChangeInstance) (worker.Result, error) {
+		if ci.ID == 1 {
+			<-stuck // ignores its context
+			return worker.Approve("too late"), nil
+		}
+		secondStarted.Store(true)
+		return worker.Approve("ok"), nil
+	})
+
+	ran := make(chan error, 1)
+	go func() { ran <- w.RunOnce(context.Background()) }()
+
+	assert.Eventually(t, func() bool { return reported.get(1) != nil }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, map[string]any{"state": "ERROR", "log": "handler timed out after 20ms"}, reported.get(1))
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, secondStarted.Load(), "the overrunning handler still holds the only slot")
+
+	close(stuck)
+	require.NoError(t, <-ran)
+	assert.True(t, secondStarted.Load())
+	assert.Equal(t, "ERROR", reported.get(1)["state"], "the late result is discarded")
+	assert.Equal(t, "APPROVED", reported.get(2)["state"])
+}
+
+// TestWorkerGracefulShutdown checks that cancelling Run lets a running handler finish and report
+// before Run returns.
+func TestWorkerGracefulShutdown(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var reported patches
+	reported.register(t, changeJSON(1, "vm", client.ChangeTypeCreate))
+
+	started := make(chan struct{})
+	w := newWorker(t, worker.Options{PollInterval: time.Hour})
+	w.Handle("vm", "", func(ctx context.Context, _ *client.ChangeInstance) (worker.Result, error) {
+		close(started)
+		time.Sleep(30 * time.Millisecond)
+		return worker.Complete("finished after shutdown began", nil), ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+	assert.Equal(t, map[string]any{"state": "COMPLETED", "log": "finished after shutdown began"}, reported.get(1))
+}
+
+func TestWorkerGate(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	var reported patches
+	reported.register(t, changeJSON(1, "vm", client.ChangeTypeCreate))
+	// Reading change 1 back returns whatever log was last written to it.
+	httpmock.RegisterRegexpResponder("GET", regexpChangeInstance, func(*http.Request) (*http.Response, error) {
+		logStr := "2026-10-18T09:00:00Z INFO submitted by the consumer"
+		if body := reported.get(1); body != nil {
+			logStr = body["log"].(string)
+		}
+		return httpmock.NewJsonResponse(200, map[string]any{"id": 1, "state": "PENDING", "log": logStr})
+	})
+	patchCount := func() int {
+		return httpmock.GetCallCountInfo()["PATCH =~"+regexpChangeInstance.String()]
+	}
+
+	// Like a change window: approvals and completions wait, anything else goes through.
+	var open atomic.Bool
+	gate := func(_ context.Context, id int, to client.ChangeInstanceState) error {
+		if open.Load() || (to != client.ChangeInstanceAPPROVED && to != client.ChangeInstanceCOMPLETED) {
+			return nil
+		}
+		return &client.DeferredTransitionError{ID: id, To: to, Reason: "outside the change windows"}
+	}
+
+	var calls atomic.Int32
+	closeMidRun := false
+	w := newWorker(t, worker.Options{Gate: gate})
+	w.Handle("vm", "", func(_ context.Context, _ *client.ChangeInstance) (worker.Result, error) {
+		calls.Add(1)
+		if closeMidRun {
+			open.Store(false)
+		}
+		return worker.Approve("looks fine"), nil
+	})
+
+	require.NoError(t, w.RunOnce(context.Background()))
+	assert.Zero(t, calls.Load(), "the handler is not called while the window is closed")
+	deferral := reported.get(1)
+	assert.Equal(t, "PENDING", deferral["state"], "the deferral leaves the state alone")
+	assert.Regexp(t, `^2026-10-18T09:00:00Z INFO submitted by the consumer\n`+
+		`\S+ INFO APPROVED deferred: outside the change windows$`, deferral["log"],
+		"the deferral is appended, and the existing log survives it")
+	assert.Equal(t, 1, patchCount())
+
+	require.NoError(t, w.RunOnce(context.Background()))
+	assert.Zero(t, calls.Load(), "the handler is still not called on the next poll")
+	assert.Equal(t, 1, patchCount(), "the same deferral is not logged twice")
+
+	// A window that closes while the handler runs defers its result too.
+	open.Store(true)
+	closeMidRun = true
+	require.NoError(t, w.RunOnce(context.Background()))
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 1, patchCount(), "the held-back approval is not reported, and its deferral is already logged")
+
+	open.Store(true)
+	closeMidRun = false
+	require.NoError(t, w.RunOnce(context.Background()))
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, map[string]any{"state": "APPROVED", "log": "looks fine"}, reported.get(1))
+}
+
+// TestWorkerConflictGate checks that a change refused by the conflict gate is not handled, and
+// is handled on a later pass once the older change on its service item is resolved.
+func TestWorkerConflictGate(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	const change12 = `{"id":12,"state":"APPROVED","change_type":"MODIFY","service":{"id":1,"name":"vm"},` +
+		`"submission":{"id":3},"service_item":{"id":5}}`
+	var olderResolved atomic.Bool
+	httpmock.RegisterResponder("GET", changeInstancesRoot, func(req *http.Request) (*http.Response, error) {
+		results := change12
+		if req.URL.Query().Get("service_item_id") == "5" && !olderResolved.Load() {
+			results += `,{"id":10,"state":"PENDING","submission":{"id":2},"service_item":{"id":5}}`
+		}
+		return httpmock.NewStringResponse(200, `{"count":1,"next":null,"results":[`+results+`]}`), nil
+	})
+	httpmock.RegisterResponder("GET", changeInstancesRoot+"12/", httpmock.NewStringResponder(200, change12))
+	httpmock.RegisterResponder("PATCH", changeInstancesRoot+"12/",
+		httpmock.NewStringResponder(200, `{"id":12,"state":"COMPLETED"}`))
+
+	var mu sync.Mutex
+	var logged []string
+	nc := newTestClient(t)
+	w, err := worker.New(nc, worker.Options{
+		Gate: nc.ConflictGate(nil),
+		Logf: func(format string, args ...any) {
+			mu.Lock()
+			defer mu.Unlock()
+			logged = append(logged, fmt.Sprintf(format, args...))
+		},
+	})
+	require.NoError(t, err)
+	var calls atomic.Int32
+	w.Handle("vm", "", func(context.Context, *client.ChangeInstance) (worker.Result, error) {
+		calls.Add(1)
+		return worker.Complete("applied", nil), nil
+	})
+
+	require.NoError(t, w.RunOnce(context.Background()))
+	assert.Zero(t, calls.Load(), "the handler is not called while an older change is open")
+	assert.Zero(t, httpmock.GetCallCountInfo()["PATCH "+changeInstancesRoot+"12/"])
+	require.Len(t, logged, 1)
+	assert.Contains(t, logged[0], "gate refused COMPLETED")
+	assert.Contains(t, logged[0], "older change instances on service item 5 are resolved: 10")
+
+	olderResolved.Store(true)
+	require.NoError(t, w.RunOnce(context.Background()))
+	assert.Equal(t, int32(1), calls.Load(), "the change is handled on a later pass")
+	assert.Equal(t, 1, httpmock.GetCallCountInfo()["PATCH "+changeInstancesRoot+"12/"])
+}
+
+func TestWorkerValidation(t *testing.T) {
+	_, err := worker.New(nil, worker.Options{})
+	require.Error(t, err)
+
+	_, err = worker.New(newTestClient(t), worker.Options{Concurrency: -1})
+	require.Error(t, err)
+
+	_, err = worker.New(newTestClient(t), worker.Options{States: []client.ChangeInstanceState{"DONE"}})
+	require.Error(t, err)
+
+	w := newWorker(t, worker.Options{})
+	require.ErrorIs(t, w.RunOnce(context.Background()), worker.ErrNoHandlers)
+}